@@ -19,8 +19,18 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	MetadataService_GetMetadata_FullMethodName = "/MetadataService/GetMetadata"
-	MetadataService_PutMetadata_FullMethodName = "/MetadataService/PutMetadata"
+	MetadataService_GetMetadata_FullMethodName             = "/MetadataService/GetMetadata"
+	MetadataService_PutMetadata_FullMethodName             = "/MetadataService/PutMetadata"
+	MetadataService_UpdateMetadata_FullMethodName          = "/MetadataService/UpdateMetadata"
+	MetadataService_DeleteMetadata_FullMethodName          = "/MetadataService/DeleteMetadata"
+	MetadataService_UndeleteMetadata_FullMethodName        = "/MetadataService/UndeleteMetadata"
+	MetadataService_ListMetadataRevisions_FullMethodName   = "/MetadataService/ListMetadataRevisions"
+	MetadataService_RestoreMetadataRevision_FullMethodName = "/MetadataService/RestoreMetadataRevision"
+	MetadataService_ListMetadata_FullMethodName            = "/MetadataService/ListMetadata"
+	MetadataService_SearchMetadata_FullMethodName          = "/MetadataService/SearchMetadata"
+	MetadataService_ResolveMovieID_FullMethodName          = "/MetadataService/ResolveMovieID"
+	MetadataService_BulkPutMetadata_FullMethodName         = "/MetadataService/BulkPutMetadata"
+	MetadataService_UploadAsset_FullMethodName             = "/MetadataService/UploadAsset"
 )
 
 // MetadataServiceClient is the client API for MetadataService service.
@@ -29,6 +39,21 @@ const (
 type MetadataServiceClient interface {
 	GetMetadata(ctx context.Context, in *GetMetadataRequest, opts ...grpc.CallOption) (*GetMetadataResponse, error)
 	PutMetadata(ctx context.Context, in *PutMetadataRequest, opts ...grpc.CallOption) (*PutMetadataResponse, error)
+	UpdateMetadata(ctx context.Context, in *UpdateMetadataRequest, opts ...grpc.CallOption) (*UpdateMetadataResponse, error)
+	DeleteMetadata(ctx context.Context, in *DeleteMetadataRequest, opts ...grpc.CallOption) (*DeleteMetadataResponse, error)
+	UndeleteMetadata(ctx context.Context, in *UndeleteMetadataRequest, opts ...grpc.CallOption) (*UndeleteMetadataResponse, error)
+	ListMetadataRevisions(ctx context.Context, in *ListMetadataRevisionsRequest, opts ...grpc.CallOption) (*ListMetadataRevisionsResponse, error)
+	RestoreMetadataRevision(ctx context.Context, in *RestoreMetadataRevisionRequest, opts ...grpc.CallOption) (*RestoreMetadataRevisionResponse, error)
+	ListMetadata(ctx context.Context, in *ListMetadataRequest, opts ...grpc.CallOption) (*ListMetadataResponse, error)
+	SearchMetadata(ctx context.Context, in *SearchMetadataRequest, opts ...grpc.CallOption) (*SearchMetadataResponse, error)
+	// Returns the id of the movie with an external id.
+	ResolveMovieID(ctx context.Context, in *ResolveMovieIDRequest, opts ...grpc.CallOption) (*ResolveMovieIDResponse, error)
+	// Writes a stream of metadata in transactional batches. Items that fail are left out of their
+	// batch and reported in the response.
+	BulkPutMetadata(ctx context.Context, opts ...grpc.CallOption) (MetadataService_BulkPutMetadataClient, error)
+	// Uploads an image in chunks, optionally attaching it to a movie. The first message carries the
+	// upload info, the next ones the content.
+	UploadAsset(ctx context.Context, opts ...grpc.CallOption) (MetadataService_UploadAssetClient, error)
 }
 
 type metadataServiceClient struct {
@@ -57,12 +82,167 @@ func (c *metadataServiceClient) PutMetadata(ctx context.Context, in *PutMetadata
 	return out, nil
 }
 
+func (c *metadataServiceClient) UpdateMetadata(ctx context.Context, in *UpdateMetadataRequest, opts ...grpc.CallOption) (*UpdateMetadataResponse, error) {
+	out := new(UpdateMetadataResponse)
+	err := c.cc.Invoke(ctx, MetadataService_UpdateMetadata_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) DeleteMetadata(ctx context.Context, in *DeleteMetadataRequest, opts ...grpc.CallOption) (*DeleteMetadataResponse, error) {
+	out := new(DeleteMetadataResponse)
+	err := c.cc.Invoke(ctx, MetadataService_DeleteMetadata_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) UndeleteMetadata(ctx context.Context, in *UndeleteMetadataRequest, opts ...grpc.CallOption) (*UndeleteMetadataResponse, error) {
+	out := new(UndeleteMetadataResponse)
+	err := c.cc.Invoke(ctx, MetadataService_UndeleteMetadata_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) ListMetadataRevisions(ctx context.Context, in *ListMetadataRevisionsRequest, opts ...grpc.CallOption) (*ListMetadataRevisionsResponse, error) {
+	out := new(ListMetadataRevisionsResponse)
+	err := c.cc.Invoke(ctx, MetadataService_ListMetadataRevisions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) RestoreMetadataRevision(ctx context.Context, in *RestoreMetadataRevisionRequest, opts ...grpc.CallOption) (*RestoreMetadataRevisionResponse, error) {
+	out := new(RestoreMetadataRevisionResponse)
+	err := c.cc.Invoke(ctx, MetadataService_RestoreMetadataRevision_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) ListMetadata(ctx context.Context, in *ListMetadataRequest, opts ...grpc.CallOption) (*ListMetadataResponse, error) {
+	out := new(ListMetadataResponse)
+	err := c.cc.Invoke(ctx, MetadataService_ListMetadata_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) SearchMetadata(ctx context.Context, in *SearchMetadataRequest, opts ...grpc.CallOption) (*SearchMetadataResponse, error) {
+	out := new(SearchMetadataResponse)
+	err := c.cc.Invoke(ctx, MetadataService_SearchMetadata_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) ResolveMovieID(ctx context.Context, in *ResolveMovieIDRequest, opts ...grpc.CallOption) (*ResolveMovieIDResponse, error) {
+	out := new(ResolveMovieIDResponse)
+	err := c.cc.Invoke(ctx, MetadataService_ResolveMovieID_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metadataServiceClient) BulkPutMetadata(ctx context.Context, opts ...grpc.CallOption) (MetadataService_BulkPutMetadataClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MetadataService_ServiceDesc.Streams[0], MetadataService_BulkPutMetadata_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &metadataServiceBulkPutMetadataClient{stream}
+	return x, nil
+}
+
+type MetadataService_BulkPutMetadataClient interface {
+	Send(*PutMetadataRequest) error
+	CloseAndRecv() (*BulkPutMetadataResponse, error)
+	grpc.ClientStream
+}
+
+type metadataServiceBulkPutMetadataClient struct {
+	grpc.ClientStream
+}
+
+func (x *metadataServiceBulkPutMetadataClient) Send(m *PutMetadataRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *metadataServiceBulkPutMetadataClient) CloseAndRecv() (*BulkPutMetadataResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(BulkPutMetadataResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *metadataServiceClient) UploadAsset(ctx context.Context, opts ...grpc.CallOption) (MetadataService_UploadAssetClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MetadataService_ServiceDesc.Streams[1], MetadataService_UploadAsset_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &metadataServiceUploadAssetClient{stream}
+	return x, nil
+}
+
+type MetadataService_UploadAssetClient interface {
+	Send(*UploadAssetRequest) error
+	CloseAndRecv() (*UploadAssetResponse, error)
+	grpc.ClientStream
+}
+
+type metadataServiceUploadAssetClient struct {
+	grpc.ClientStream
+}
+
+func (x *metadataServiceUploadAssetClient) Send(m *UploadAssetRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *metadataServiceUploadAssetClient) CloseAndRecv() (*UploadAssetResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(UploadAssetResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // MetadataServiceServer is the server API for MetadataService service.
 // All implementations must embed UnimplementedMetadataServiceServer
 // for forward compatibility
 type MetadataServiceServer interface {
 	GetMetadata(context.Context, *GetMetadataRequest) (*GetMetadataResponse, error)
 	PutMetadata(context.Context, *PutMetadataRequest) (*PutMetadataResponse, error)
+	UpdateMetadata(context.Context, *UpdateMetadataRequest) (*UpdateMetadataResponse, error)
+	DeleteMetadata(context.Context, *DeleteMetadataRequest) (*DeleteMetadataResponse, error)
+	UndeleteMetadata(context.Context, *UndeleteMetadataRequest) (*UndeleteMetadataResponse, error)
+	ListMetadataRevisions(context.Context, *ListMetadataRevisionsRequest) (*ListMetadataRevisionsResponse, error)
+	RestoreMetadataRevision(context.Context, *RestoreMetadataRevisionRequest) (*RestoreMetadataRevisionResponse, error)
+	ListMetadata(context.Context, *ListMetadataRequest) (*ListMetadataResponse, error)
+	SearchMetadata(context.Context, *SearchMetadataRequest) (*SearchMetadataResponse, error)
+	// Returns the id of the movie with an external id.
+	ResolveMovieID(context.Context, *ResolveMovieIDRequest) (*ResolveMovieIDResponse, error)
+	// Writes a stream of metadata in transactional batches. Items that fail are left out of their
+	// batch and reported in the response.
+	BulkPutMetadata(MetadataService_BulkPutMetadataServer) error
+	// Uploads an image in chunks, optionally attaching it to a movie. The first message carries the
+	// upload info, the next ones the content.
+	UploadAsset(MetadataService_UploadAssetServer) error
 	mustEmbedUnimplementedMetadataServiceServer()
 }
 
@@ -76,6 +256,36 @@ func (UnimplementedMetadataServiceServer) GetMetadata(context.Context, *GetMetad
 func (UnimplementedMetadataServiceServer) PutMetadata(context.Context, *PutMetadataRequest) (*PutMetadataResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PutMetadata not implemented")
 }
+func (UnimplementedMetadataServiceServer) UpdateMetadata(context.Context, *UpdateMetadataRequest) (*UpdateMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) DeleteMetadata(context.Context, *DeleteMetadataRequest) (*DeleteMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) UndeleteMetadata(context.Context, *UndeleteMetadataRequest) (*UndeleteMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UndeleteMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) ListMetadataRevisions(context.Context, *ListMetadataRevisionsRequest) (*ListMetadataRevisionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMetadataRevisions not implemented")
+}
+func (UnimplementedMetadataServiceServer) RestoreMetadataRevision(context.Context, *RestoreMetadataRevisionRequest) (*RestoreMetadataRevisionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreMetadataRevision not implemented")
+}
+func (UnimplementedMetadataServiceServer) ListMetadata(context.Context, *ListMetadataRequest) (*ListMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) SearchMetadata(context.Context, *SearchMetadataRequest) (*SearchMetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) ResolveMovieID(context.Context, *ResolveMovieIDRequest) (*ResolveMovieIDResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResolveMovieID not implemented")
+}
+func (UnimplementedMetadataServiceServer) BulkPutMetadata(MetadataService_BulkPutMetadataServer) error {
+	return status.Errorf(codes.Unimplemented, "method BulkPutMetadata not implemented")
+}
+func (UnimplementedMetadataServiceServer) UploadAsset(MetadataService_UploadAssetServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadAsset not implemented")
+}
 func (UnimplementedMetadataServiceServer) mustEmbedUnimplementedMetadataServiceServer() {}
 
 // UnsafeMetadataServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -125,6 +335,202 @@ func _MetadataService_PutMetadata_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MetadataService_UpdateMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).UpdateMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_UpdateMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).UpdateMetadata(ctx, req.(*UpdateMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_DeleteMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).DeleteMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_DeleteMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).DeleteMetadata(ctx, req.(*DeleteMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_UndeleteMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UndeleteMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).UndeleteMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_UndeleteMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).UndeleteMetadata(ctx, req.(*UndeleteMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_ListMetadataRevisions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMetadataRevisionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).ListMetadataRevisions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_ListMetadataRevisions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).ListMetadataRevisions(ctx, req.(*ListMetadataRevisionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_RestoreMetadataRevision_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreMetadataRevisionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).RestoreMetadataRevision(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_RestoreMetadataRevision_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).RestoreMetadataRevision(ctx, req.(*RestoreMetadataRevisionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_ListMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).ListMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_ListMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).ListMetadata(ctx, req.(*ListMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_SearchMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).SearchMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_SearchMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).SearchMetadata(ctx, req.(*SearchMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_ResolveMovieID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveMovieIDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetadataServiceServer).ResolveMovieID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetadataService_ResolveMovieID_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetadataServiceServer).ResolveMovieID(ctx, req.(*ResolveMovieIDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetadataService_BulkPutMetadata_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MetadataServiceServer).BulkPutMetadata(&metadataServiceBulkPutMetadataServer{stream})
+}
+
+type MetadataService_BulkPutMetadataServer interface {
+	SendAndClose(*BulkPutMetadataResponse) error
+	Recv() (*PutMetadataRequest, error)
+	grpc.ServerStream
+}
+
+type metadataServiceBulkPutMetadataServer struct {
+	grpc.ServerStream
+}
+
+func (x *metadataServiceBulkPutMetadataServer) SendAndClose(m *BulkPutMetadataResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *metadataServiceBulkPutMetadataServer) Recv() (*PutMetadataRequest, error) {
+	m := new(PutMetadataRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _MetadataService_UploadAsset_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MetadataServiceServer).UploadAsset(&metadataServiceUploadAssetServer{stream})
+}
+
+type MetadataService_UploadAssetServer interface {
+	SendAndClose(*UploadAssetResponse) error
+	Recv() (*UploadAssetRequest, error)
+	grpc.ServerStream
+}
+
+type metadataServiceUploadAssetServer struct {
+	grpc.ServerStream
+}
+
+func (x *metadataServiceUploadAssetServer) SendAndClose(m *UploadAssetResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *metadataServiceUploadAssetServer) Recv() (*UploadAssetRequest, error) {
+	m := new(UploadAssetRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // MetadataService_ServiceDesc is the grpc.ServiceDesc for MetadataService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -140,14 +546,59 @@ var MetadataService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PutMetadata",
 			Handler:    _MetadataService_PutMetadata_Handler,
 		},
+		{
+			MethodName: "UpdateMetadata",
+			Handler:    _MetadataService_UpdateMetadata_Handler,
+		},
+		{
+			MethodName: "DeleteMetadata",
+			Handler:    _MetadataService_DeleteMetadata_Handler,
+		},
+		{
+			MethodName: "UndeleteMetadata",
+			Handler:    _MetadataService_UndeleteMetadata_Handler,
+		},
+		{
+			MethodName: "ListMetadataRevisions",
+			Handler:    _MetadataService_ListMetadataRevisions_Handler,
+		},
+		{
+			MethodName: "RestoreMetadataRevision",
+			Handler:    _MetadataService_RestoreMetadataRevision_Handler,
+		},
+		{
+			MethodName: "ListMetadata",
+			Handler:    _MetadataService_ListMetadata_Handler,
+		},
+		{
+			MethodName: "SearchMetadata",
+			Handler:    _MetadataService_SearchMetadata_Handler,
+		},
+		{
+			MethodName: "ResolveMovieID",
+			Handler:    _MetadataService_ResolveMovieID_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BulkPutMetadata",
+			Handler:       _MetadataService_BulkPutMetadata_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "UploadAsset",
+			Handler:       _MetadataService_UploadAsset_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "movie.proto",
 }
 
 const (
 	RatingService_GetAggregatedRating_FullMethodName = "/RatingService/GetAggregatedRating"
 	RatingService_PutRating_FullMethodName           = "/RatingService/PutRating"
+	RatingService_BulkPutRatings_FullMethodName      = "/RatingService/BulkPutRatings"
+	RatingService_ExportRatings_FullMethodName       = "/RatingService/ExportRatings"
 )
 
 // RatingServiceClient is the client API for RatingService service.
@@ -156,6 +607,11 @@ const (
 type RatingServiceClient interface {
 	GetAggregatedRating(ctx context.Context, in *GetAggregatedRatingRequest, opts ...grpc.CallOption) (*GetAggregatedRatingResponse, error)
 	PutRating(ctx context.Context, in *PutRatingRequest, opts ...grpc.CallOption) (*PutRatingResponse, error)
+	// Writes a stream of ratings in transactional batches. Items that fail are left out of their
+	// batch and reported in the response. Streams are only safely retried with event ids.
+	BulkPutRatings(ctx context.Context, opts ...grpc.CallOption) (RatingService_BulkPutRatingsClient, error)
+	// Streams all ratings of a record type.
+	ExportRatings(ctx context.Context, in *ExportRatingsRequest, opts ...grpc.CallOption) (RatingService_ExportRatingsClient, error)
 }
 
 type ratingServiceClient struct {
@@ -184,12 +640,83 @@ func (c *ratingServiceClient) PutRating(ctx context.Context, in *PutRatingReques
 	return out, nil
 }
 
+func (c *ratingServiceClient) BulkPutRatings(ctx context.Context, opts ...grpc.CallOption) (RatingService_BulkPutRatingsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &RatingService_ServiceDesc.Streams[0], RatingService_BulkPutRatings_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &ratingServiceBulkPutRatingsClient{stream}
+	return x, nil
+}
+
+type RatingService_BulkPutRatingsClient interface {
+	Send(*PutRatingRequest) error
+	CloseAndRecv() (*BulkPutRatingsResponse, error)
+	grpc.ClientStream
+}
+
+type ratingServiceBulkPutRatingsClient struct {
+	grpc.ClientStream
+}
+
+func (x *ratingServiceBulkPutRatingsClient) Send(m *PutRatingRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *ratingServiceBulkPutRatingsClient) CloseAndRecv() (*BulkPutRatingsResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(BulkPutRatingsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *ratingServiceClient) ExportRatings(ctx context.Context, in *ExportRatingsRequest, opts ...grpc.CallOption) (RatingService_ExportRatingsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &RatingService_ServiceDesc.Streams[1], RatingService_ExportRatings_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &ratingServiceExportRatingsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RatingService_ExportRatingsClient interface {
+	Recv() (*Rating, error)
+	grpc.ClientStream
+}
+
+type ratingServiceExportRatingsClient struct {
+	grpc.ClientStream
+}
+
+func (x *ratingServiceExportRatingsClient) Recv() (*Rating, error) {
+	m := new(Rating)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // RatingServiceServer is the server API for RatingService service.
 // All implementations must embed UnimplementedRatingServiceServer
 // for forward compatibility
 type RatingServiceServer interface {
 	GetAggregatedRating(context.Context, *GetAggregatedRatingRequest) (*GetAggregatedRatingResponse, error)
 	PutRating(context.Context, *PutRatingRequest) (*PutRatingResponse, error)
+	// Writes a stream of ratings in transactional batches. Items that fail are left out of their
+	// batch and reported in the response. Streams are only safely retried with event ids.
+	BulkPutRatings(RatingService_BulkPutRatingsServer) error
+	// Streams all ratings of a record type.
+	ExportRatings(*ExportRatingsRequest, RatingService_ExportRatingsServer) error
 	mustEmbedUnimplementedRatingServiceServer()
 }
 
@@ -203,6 +730,12 @@ func (UnimplementedRatingServiceServer) GetAggregatedRating(context.Context, *Ge
 func (UnimplementedRatingServiceServer) PutRating(context.Context, *PutRatingRequest) (*PutRatingResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PutRating not implemented")
 }
+func (UnimplementedRatingServiceServer) BulkPutRatings(RatingService_BulkPutRatingsServer) error {
+	return status.Errorf(codes.Unimplemented, "method BulkPutRatings not implemented")
+}
+func (UnimplementedRatingServiceServer) ExportRatings(*ExportRatingsRequest, RatingService_ExportRatingsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExportRatings not implemented")
+}
 func (UnimplementedRatingServiceServer) mustEmbedUnimplementedRatingServiceServer() {}
 
 // UnsafeRatingServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -252,6 +785,53 @@ func _RatingService_PutRating_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RatingService_BulkPutRatings_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RatingServiceServer).BulkPutRatings(&ratingServiceBulkPutRatingsServer{stream})
+}
+
+type RatingService_BulkPutRatingsServer interface {
+	SendAndClose(*BulkPutRatingsResponse) error
+	Recv() (*PutRatingRequest, error)
+	grpc.ServerStream
+}
+
+type ratingServiceBulkPutRatingsServer struct {
+	grpc.ServerStream
+}
+
+func (x *ratingServiceBulkPutRatingsServer) SendAndClose(m *BulkPutRatingsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *ratingServiceBulkPutRatingsServer) Recv() (*PutRatingRequest, error) {
+	m := new(PutRatingRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _RatingService_ExportRatings_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportRatingsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RatingServiceServer).ExportRatings(m, &ratingServiceExportRatingsServer{stream})
+}
+
+type RatingService_ExportRatingsServer interface {
+	Send(*Rating) error
+	grpc.ServerStream
+}
+
+type ratingServiceExportRatingsServer struct {
+	grpc.ServerStream
+}
+
+func (x *ratingServiceExportRatingsServer) Send(m *Rating) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // RatingService_ServiceDesc is the grpc.ServiceDesc for RatingService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -268,12 +848,24 @@ var RatingService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _RatingService_PutRating_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BulkPutRatings",
+			Handler:       _RatingService_BulkPutRatings_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ExportRatings",
+			Handler:       _RatingService_ExportRatings_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "movie.proto",
 }
 
 const (
 	MovieService_GetMovieDetails_FullMethodName = "/MovieService/GetMovieDetails"
+	MovieService_SearchMovies_FullMethodName    = "/MovieService/SearchMovies"
 )
 
 // MovieServiceClient is the client API for MovieService service.
@@ -281,6 +873,7 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type MovieServiceClient interface {
 	GetMovieDetails(ctx context.Context, in *GetMovieDetailsRequest, opts ...grpc.CallOption) (*GetMovieDetailsResponse, error)
+	SearchMovies(ctx context.Context, in *SearchMoviesRequest, opts ...grpc.CallOption) (*SearchMoviesResponse, error)
 }
 
 type movieServiceClient struct {
@@ -300,11 +893,21 @@ func (c *movieServiceClient) GetMovieDetails(ctx context.Context, in *GetMovieDe
 	return out, nil
 }
 
+func (c *movieServiceClient) SearchMovies(ctx context.Context, in *SearchMoviesRequest, opts ...grpc.CallOption) (*SearchMoviesResponse, error) {
+	out := new(SearchMoviesResponse)
+	err := c.cc.Invoke(ctx, MovieService_SearchMovies_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MovieServiceServer is the server API for MovieService service.
 // All implementations must embed UnimplementedMovieServiceServer
 // for forward compatibility
 type MovieServiceServer interface {
 	GetMovieDetails(context.Context, *GetMovieDetailsRequest) (*GetMovieDetailsResponse, error)
+	SearchMovies(context.Context, *SearchMoviesRequest) (*SearchMoviesResponse, error)
 	mustEmbedUnimplementedMovieServiceServer()
 }
 
@@ -315,6 +918,9 @@ type UnimplementedMovieServiceServer struct {
 func (UnimplementedMovieServiceServer) GetMovieDetails(context.Context, *GetMovieDetailsRequest) (*GetMovieDetailsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetMovieDetails not implemented")
 }
+func (UnimplementedMovieServiceServer) SearchMovies(context.Context, *SearchMoviesRequest) (*SearchMoviesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchMovies not implemented")
+}
 func (UnimplementedMovieServiceServer) mustEmbedUnimplementedMovieServiceServer() {}
 
 // UnsafeMovieServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -346,6 +952,24 @@ func _MovieService_GetMovieDetails_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MovieService_SearchMovies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchMoviesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MovieServiceServer).SearchMovies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MovieService_SearchMovies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MovieServiceServer).SearchMovies(ctx, req.(*SearchMoviesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MovieService_ServiceDesc is the grpc.ServiceDesc for MovieService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -357,6 +981,10 @@ var MovieService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetMovieDetails",
 			Handler:    _MovieService_GetMovieDetails_Handler,
 		},
+		{
+			MethodName: "SearchMovies",
+			Handler:    _MovieService_SearchMovies_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "movie.proto",
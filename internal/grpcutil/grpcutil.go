@@ -2,17 +2,72 @@ package grpcutil
 
 import (
 	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
 
+	"github.com/meirongdev/movie-microservice/gen"
 	"github.com/meirongdev/movie-microservice/pkg/discovery"
+	"github.com/meirongdev/movie-microservice/pkg/locale"
 	"golang.org/x/exp/rand"
+	"google.golang.org/genproto/googleapis/rpc/code"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 func ServiceConnection(ctx context.Context, serviceName string, registry discovery.Registry) (*grpc.ClientConn, error) {
-	addrs, err := registry.ServiceAddresses(ctx, serviceName)
+	addrs, err := registry.ServiceAddresses(ctx, serviceName, discovery.ProtocolGRPC)
 	if err != nil {
 		return nil, err
 	}
 	return grpc.Dial(addrs[rand.Intn(len(addrs))], grpc.WithTransportCredentials(insecure.NewCredentials()))
 }
+
+// BulkPutFailure returns the failure of an item of a bulk write, given the gRPC status error the
+// item failed with.
+func BulkPutFailure(index int64, id string, err error) *gen.BulkPutFailure {
+	st := status.Convert(err)
+	return &gen.BulkPutFailure{Index: index, Id: id, Code: code.Code(st.Code()).String(), Message: st.Message()}
+}
+
+// Locales returns the fallback chain of a locale requested by a call or, if none is requested, of
+// the Accept-Language header of calls through the REST gateway.
+func Locales(ctx context.Context, requested string) ([]string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return locale.Negotiate(requested, strings.Join(md.Get("grpcgateway-accept-language"), ","))
+}
+
+// Serve serves gRPC on lis and HTTP on httpLis until ctx is done or either server fails, then
+// stops both. It returns the error of the server that failed first, if any.
+func Serve(ctx context.Context, srv *grpc.Server, lis net.Listener, httpSrv *http.Server, httpLis net.Listener) error {
+	errs := make(chan error, 2)
+	go func() { errs <- srv.Serve(lis) }()
+	go func() {
+		if err := httpSrv.Serve(httpLis); !errors.Is(err, http.ErrServerClosed) {
+			errs <- err
+			return
+		}
+		errs <- nil
+	}()
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if shutdownErr := httpSrv.Shutdown(shutdownCtx); shutdownErr != nil && err == nil {
+		err = shutdownErr
+	}
+	srv.GracefulStop()
+	return err
+}
+
+// Port returns the TCP port lis listens on, which is chosen by the system if port 0 was requested.
+func Port(lis net.Listener) int {
+	return lis.Addr().(*net.TCPAddr).Port
+}
@@ -0,0 +1,48 @@
+package grpcutil
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lis
+}
+
+func TestServe(t *testing.T) {
+	t.Run("stops when the context is done", func(t *testing.T) {
+		lis, httpLis := listen(t), listen(t)
+		if Port(lis) == 0 || Port(httpLis) == 0 {
+			t.Fatalf("Port: got %d and %d, want the ports chosen by the system", Port(lis), Port(httpLis))
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if err := Serve(ctx, grpc.NewServer(), lis, &http.Server{}, httpLis); err != nil {
+			t.Fatalf("Serve: %v", err)
+		}
+	})
+	t.Run("stops when a server fails", func(t *testing.T) {
+		lis, httpLis := listen(t), listen(t)
+		httpLis.Close()
+		done := make(chan error)
+		go func() { done <- Serve(context.Background(), grpc.NewServer(), lis, &http.Server{}, httpLis) }()
+		select {
+		case err := <-done:
+			if err == nil {
+				t.Fatal("Serve: got no error, want the error of the HTTP server")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Serve did not stop after the HTTP server failed")
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package mysqlutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/meirongdev/movie-microservice/pkg/config"
+)
+
+const defaultHealthInterval = 5 * time.Second
+
+// Cluster routes queries to a MySQL primary and its read replicas. Reads go to healthy replicas
+// in turn, or to the primary if none is healthy; writes always go to the primary.
+type Cluster struct {
+	primary        *sql.DB
+	replicas       []*replica
+	next           atomic.Uint64
+	readYourWrites bool
+	stop           chan struct{}
+	wg             sync.WaitGroup
+}
+
+type replica struct {
+	db      *sql.DB
+	addr    string
+	healthy atomic.Bool
+	// checked is only accessed by the health checks, which run one at a time.
+	checked bool
+}
+
+// OpenCluster opens the primary database of cfg as Open does, and its replicas. Replicas that
+// cannot be reached are skipped until a health check succeeds.
+func OpenCluster(ctx context.Context, cfg config.MySQLConfig) (*Cluster, error) {
+	primary, err := Open(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cluster{primary: primary, readYourWrites: cfg.ReadYourWrites, stop: make(chan struct{})}
+	for _, dsn := range cfg.Replicas {
+		driverConfig, err := cfg.ReplicaDriverConfig(dsn)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		db, err := openDB(driverConfig, cfg.Pool)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		r := &replica{db: db, addr: driverConfig.Addr}
+		r.check(ctx)
+		c.replicas = append(c.replicas, r)
+	}
+	if len(c.replicas) > 0 {
+		interval := cfg.ReplicaHealthInterval
+		if interval <= 0 {
+			interval = defaultHealthInterval
+		}
+		c.wg.Add(1)
+		go c.checkHealth(interval)
+	}
+	return c, nil
+}
+
+// Primary returns the primary database.
+func (c *Cluster) Primary() *sql.DB {
+	return c.primary
+}
+
+// Reader returns the database to read from: the next healthy replica, or the primary if there is
+// none or the session of the context has written with read-your-writes enabled.
+func (c *Cluster) Reader(ctx context.Context) *sql.DB {
+	if c.readYourWrites {
+		if s, ok := ctx.Value(sessionKey{}).(*session); ok && s.wrote.Load() {
+			return c.primary
+		}
+	}
+	// Advancing the counter past unhealthy replicas keeps the reads evenly spread over the others.
+	n := uint64(len(c.replicas))
+	for range n {
+		if r := c.replicas[c.next.Add(1)%n]; r.healthy.Load() {
+			return r.db
+		}
+	}
+	return c.primary
+}
+
+// Writer returns the primary database, recording the write in the session of the context.
+func (c *Cluster) Writer(ctx context.Context) *sql.DB {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		s.wrote.Store(true)
+	}
+	return c.primary
+}
+
+// Close stops the health checks and closes all databases.
+func (c *Cluster) Close() error {
+	close(c.stop)
+	c.wg.Wait()
+	errs := []error{c.primary.Close()}
+	for _, r := range c.replicas {
+		errs = append(errs, r.db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Cluster) checkHealth(interval time.Duration) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			for _, r := range c.replicas {
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				r.check(ctx)
+				cancel()
+			}
+		}
+	}
+}
+
+// check pings the replica and updates its health, logging changes.
+func (r *replica) check(ctx context.Context) {
+	err := r.db.PingContext(ctx)
+	healthy := err == nil
+	if r.healthy.Swap(healthy) == healthy && r.checked {
+		return
+	}
+	r.checked = true
+	if healthy {
+		log.Printf("MySQL replica %s is healthy\n", r.addr)
+	} else {
+		log.Printf("MySQL replica %s is unhealthy: %v\n", r.addr, err)
+	}
+}
@@ -0,0 +1,77 @@
+package mysqlutil
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/meirongdev/movie-microservice/pkg/config"
+)
+
+// newTestCluster creates a cluster of databases that are never connected to.
+func newTestCluster(t *testing.T, replicas int, readYourWrites bool) *Cluster {
+	t.Helper()
+	open := func() *sql.DB {
+		db, err := openDB(mysql.NewConfig(), config.MySQLPoolConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	c := &Cluster{primary: open(), readYourWrites: readYourWrites}
+	for range replicas {
+		r := &replica{db: open()}
+		r.healthy.Store(true)
+		c.replicas = append(c.replicas, r)
+	}
+	return c
+}
+
+func TestReaderRoundRobin(t *testing.T) {
+	c := newTestCluster(t, 3, false)
+	c.replicas[1].healthy.Store(false)
+	counts := map[*sql.DB]int{}
+	for range 10 {
+		counts[c.Reader(context.Background())]++
+	}
+	if counts[c.replicas[1].db] != 0 || counts[c.primary] != 0 {
+		t.Fatalf("reads went to an unhealthy replica or the primary: %v", counts)
+	}
+	if counts[c.replicas[0].db] != 5 || counts[c.replicas[2].db] != 5 {
+		t.Fatalf("reads are not spread over the healthy replicas: %v", counts)
+	}
+}
+
+func TestReaderFallsBackToPrimary(t *testing.T) {
+	c := newTestCluster(t, 2, false)
+	for _, r := range c.replicas {
+		r.healthy.Store(false)
+	}
+	if got := c.Reader(context.Background()); got != c.primary {
+		t.Fatal("read without healthy replicas did not go to the primary")
+	}
+	if got := newTestCluster(t, 0, false); got.Reader(context.Background()) != got.primary {
+		t.Fatal("read without replicas did not go to the primary")
+	}
+}
+
+func TestReadYourWrites(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		c := newTestCluster(t, 1, enabled)
+		ctx := WithSession(context.Background())
+		if c.Reader(ctx) == c.primary {
+			t.Fatal("read before a write went to the primary")
+		}
+		if c.Writer(ctx) != c.primary {
+			t.Fatal("write did not go to the primary")
+		}
+		if got := c.Reader(ctx) == c.primary; got != enabled {
+			t.Errorf("read-your-writes %v: read after a write went to the primary: %v", enabled, got)
+		}
+		if c.Reader(WithSession(context.Background())) == c.primary {
+			t.Error("read of another session went to the primary")
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package mysqlutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/meirongdev/movie-microservice/pkg/config"
+)
+
+const (
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 30 * time.Second
+)
+
+// Open opens a MySQL database with the connection pool, timeouts and TLS of cfg, and pings it,
+// retrying with exponential backoff up to cfg.Retry.Attempts times.
+func Open(ctx context.Context, cfg config.MySQLConfig) (*sql.DB, error) {
+	driverConfig, err := cfg.DriverConfig()
+	if err != nil {
+		return nil, err
+	}
+	db, err := openDB(driverConfig, cfg.Pool)
+	if err != nil {
+		return nil, err
+	}
+	if err := ping(ctx, db, cfg.Retry); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// openDB opens a database with the pool settings, without connecting.
+func openDB(driverConfig *mysql.Config, pool config.MySQLPoolConfig) (*sql.DB, error) {
+	connector, err := mysql.NewConnector(driverConfig)
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(connector)
+	if pool.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(pool.MaxOpenConns)
+	}
+	if pool.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	return db, nil
+}
+
+func ping(ctx context.Context, db *sql.DB, retry config.MySQLRetryConfig) error {
+	delay := retry.InitialBackoff
+	if delay <= 0 {
+		delay = defaultInitialBackoff
+	}
+	maxDelay := retry.MaxBackoff
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxBackoff
+	}
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil || attempt >= retry.Attempts {
+			return err
+		}
+		log.Printf("Failed to connect to MySQL (attempt %d), retrying in %v: %v\n", attempt, delay, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxDelay)
+	}
+}
+
+// erDupEntry is the MySQL error number of duplicate key violations.
+const erDupEntry = 1062
+
+// IsDuplicateKey reports whether err is a duplicate key violation.
+func IsDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == erDupEntry
+}
@@ -0,0 +1,48 @@
+package mysqlutil
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+)
+
+type sessionKey struct{}
+
+type session struct {
+	wrote atomic.Bool
+}
+
+// WithSession returns a context carrying a new session. With read-your-writes enabled, the reads
+// of a session go to the primary once the session has written.
+func WithSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+// SessionHandler starts a session for every HTTP request.
+func SessionHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		h.ServeHTTP(w, req.WithContext(WithSession(req.Context())))
+	})
+}
+
+// UnarySessionInterceptor starts a session for every unary gRPC call.
+func UnarySessionInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(WithSession(ctx), req)
+}
+
+// StreamSessionInterceptor starts a session for every streaming gRPC call.
+func StreamSessionInterceptor(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, sessionStream{stream, WithSession(stream.Context())})
+}
+
+// sessionStream defines a server stream whose context carries a session.
+type sessionStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s sessionStream) Context() context.Context {
+	return s.ctx
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	commonConfig "github.com/meirongdev/movie-microservice/pkg/config"
 	"gopkg.in/yaml.v3"
@@ -13,8 +14,32 @@ type config struct {
 }
 
 type apiConfig struct {
-	Port        int                      `yaml:"port"`
-	MysqlConfig commonConfig.MySQLConfig `yaml:"mysql"`
+	Port        int                        `yaml:"port"`
+	HTTPPort    int                        `yaml:"http_port"`
+	Storage     commonConfig.StorageConfig `yaml:"storage"`
+	MysqlConfig commonConfig.MySQLConfig   `yaml:"mysql"`
+	Purge       purgeConfig                `yaml:"purge"`
+	Assets      assetsConfig               `yaml:"assets"`
+}
+
+// assetsConfig selects the blob store of uploaded images: "local" (the default).
+type assetsConfig struct {
+	Store string            `yaml:"store"`
+	Local localAssetsConfig `yaml:"local"`
+}
+
+// localAssetsConfig configures the storage of images in a local directory.
+type localAssetsConfig struct {
+	// Dir is the directory of the images, "assets" by default.
+	Dir string `yaml:"dir"`
+}
+
+// purgeConfig defines how long deleted metadata is kept before it is purged.
+type purgeConfig struct {
+	// Retention is the age of tombstones which are purged, 0 to keep deleted metadata forever.
+	Retention time.Duration `yaml:"retention"`
+	// Interval is the time between purges, an hour by default.
+	Interval time.Duration `yaml:"interval"`
 }
 
 func loadConfig(path string) (config, error) {
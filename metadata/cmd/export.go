@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/meirongdev/movie-microservice/metadata/internal/catalog"
+	"github.com/meirongdev/movie-microservice/metadata/internal/controller/metadata"
+)
+
+// exportPageSize is the number of movies read per page, so that the catalog is streamed rather
+// than loaded at once.
+const exportPageSize = 100
+
+// exportMetadata runs the export command, which writes all movies that are not deleted, sorted by
+// id, to the -file file ("-" for stdout) as CSV or JSON lines.
+func exportMetadata(ctx context.Context, cfg config, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	path := fs.String("file", "-", "file to export the movies to, - for stdout")
+	format := fs.String("format", "", "format of the file, csv or jsonl (default by the file extension)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format == "" {
+		*format = string(catalog.FormatOf(*path))
+	}
+	var out io.Writer = os.Stdout
+	var f *os.File
+	if *path != "-" {
+		var err error
+		if f, err = os.Create(*path); err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w, err := catalog.NewWriter(out, catalog.Format(*format))
+	if err != nil {
+		return err
+	}
+	repo, err := newRepository(ctx, cfg.API)
+	if err != nil {
+		return err
+	}
+	ctrl := metadata.New(repo)
+	var exported int
+	for token := ""; ; {
+		movies, next, err := ctrl.List(ctx, "id", exportPageSize, token)
+		if err != nil {
+			return err
+		}
+		for _, m := range movies {
+			if err := w.Write(m); err != nil {
+				return err
+			}
+		}
+		exported += len(movies)
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if f != nil {
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	log.Printf("Exported %d movies\n", exported)
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/meirongdev/movie-microservice/metadata/internal/catalog"
+	"github.com/meirongdev/movie-microservice/metadata/internal/controller/asset"
+	"github.com/meirongdev/movie-microservice/metadata/internal/controller/metadata"
+	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+)
+
+// Import modes, selecting how movies which already exist are imported.
+const (
+	importUpsert       = "upsert"
+	importSkipExisting = "skip-existing"
+)
+
+// importFailure defines a row which could not be imported, as written to the -report file.
+type importFailure struct {
+	Line  int    `json:"line"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+// importMetadata runs the import command, which writes the movies of the CSV or JSON-lines -file
+// file ("-" for stdin) through the controller, one row at a time. Rows that cannot be read or
+// validated are reported and skipped; other write errors stop the import.
+func importMetadata(ctx context.Context, cfg config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	path := fs.String("file", "-", "CSV or JSON-lines file of movies to import, - for stdin")
+	format := fs.String("format", "", "format of the file, csv or jsonl (default by the file extension)")
+	mapping := fs.String("map", "", "comma-separated field=column pairs naming the columns or keys of fields")
+	mode := fs.String("mode", importUpsert, "upsert to overwrite existing movies, or skip-existing to keep them")
+	dryRun := fs.Bool("dry-run", false, "validate the movies without writing them")
+	reportPath := fs.String("report", "", "JSON-lines file receiving the rows that failed (default: logged)")
+	editor := fs.String("editor", "import", "editor recorded in the revisions of imported movies")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *mode != importUpsert && *mode != importSkipExisting {
+		return fmt.Errorf("unknown mode %q", *mode)
+	}
+	m, err := catalog.ParseMapping(*mapping)
+	if err != nil {
+		return err
+	}
+	var in io.Reader = os.Stdin
+	if *path != "-" {
+		f, err := os.Open(*path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	if *format == "" {
+		*format = string(catalog.FormatOf(*path))
+	}
+	r, err := catalog.NewReader(in, catalog.Format(*format), m)
+	if err != nil {
+		return err
+	}
+	var report *json.Encoder
+	if *reportPath != "" {
+		f, err := os.Create(*reportPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		report = json.NewEncoder(f)
+	}
+	repo, err := newRepository(ctx, cfg.API)
+	if err != nil {
+		return err
+	}
+	store, err := newBlobStore(cfg.API.Assets)
+	if err != nil {
+		return err
+	}
+	ctrl := metadata.New(repo, metadata.WithAssets(asset.New(store)))
+	var imported, skipped, failed int
+	fail := func(failure importFailure) error {
+		failed++
+		if report == nil {
+			log.Printf("Line %d: %s\n", failure.Line, failure.Error)
+			return nil
+		}
+		return report.Encode(failure)
+	}
+	for {
+		movie, err := r.Read()
+		var rowErr *catalog.RowError
+		if err == io.EOF {
+			break
+		} else if errors.As(err, &rowErr) {
+			if err := fail(importFailure{Line: rowErr.Line, Error: rowErr.Err.Error()}); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+		movie.UpdatedBy = *editor
+		exists, err := importMovie(ctx, ctrl, movie, *mode == importSkipExisting, *dryRun)
+		if err != nil && (errors.Is(err, metadata.ErrInvalidArgument) || errors.Is(err, metadata.ErrDuplicateExternalID)) {
+			if err := fail(importFailure{Line: r.Line(), ID: movie.ID, Error: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return fmt.Errorf("line %d: %w", r.Line(), err)
+		}
+		if exists {
+			skipped++
+		} else {
+			imported++
+		}
+	}
+	verb := "Imported"
+	if *dryRun {
+		verb = "Validated"
+	}
+	log.Printf("%s %d movies, %d skipped, %d failed\n", verb, imported, skipped, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d movies could not be imported", failed)
+	}
+	return nil
+}
+
+// importMovie writes a movie, unless it exists and existing movies are skipped, and reports
+// whether it was skipped. Dry runs only validate the movie.
+func importMovie(ctx context.Context, ctrl *metadata.Controller, m *model.Metadata, skipExisting, dryRun bool) (bool, error) {
+	if dryRun {
+		if err := ctrl.Validate(m); err != nil || !skipExisting {
+			return false, err
+		}
+		_, err := ctrl.GetIncludingDeleted(ctx, m.ID)
+		if err != nil && errors.Is(err, metadata.ErrNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	if !skipExisting {
+		_, err := ctrl.Put(ctx, m, metadata.AnyVersion)
+		return false, err
+	}
+	// Creating the movie only if it does not exist yet fails with a version mismatch otherwise.
+	_, err := ctrl.Put(ctx, m, 0)
+	if err != nil && errors.Is(err, metadata.ErrVersionMismatch) {
+		return true, nil
+	}
+	return false, err
+}
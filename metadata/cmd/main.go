@@ -6,12 +6,20 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/meirongdev/movie-microservice/gen"
+	"github.com/meirongdev/movie-microservice/internal/grpcutil"
+	"github.com/meirongdev/movie-microservice/internal/mysqlutil"
+	"github.com/meirongdev/movie-microservice/metadata/internal/controller/asset"
 	"github.com/meirongdev/movie-microservice/metadata/internal/controller/metadata"
 	grpchandler "github.com/meirongdev/movie-microservice/metadata/internal/handler/grpc"
-	"github.com/meirongdev/movie-microservice/metadata/internal/repository/mysql"
+	httphandler "github.com/meirongdev/movie-microservice/metadata/internal/handler/http"
 
 	"github.com/meirongdev/movie-microservice/pkg/discovery"
 	"github.com/meirongdev/movie-microservice/pkg/discovery/consul"
@@ -29,44 +37,111 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	port := config.API.Port
-	log.Printf("Starting the metadata service on port %d", port)
+	switch cmd := flag.Arg(0); cmd {
+	case "":
+	case "migrate":
+		if err := runMigrations(context.Background(), config, flag.Args()[1:]); err != nil {
+			log.Fatalf("Migrate failed: %v", err)
+		}
+		return
+	case "import":
+		if err := importMetadata(context.Background(), config, flag.Args()[1:]); err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+		return
+	case "export":
+		if err := exportMetadata(context.Background(), config, flag.Args()[1:]); err != nil {
+			log.Fatalf("Export failed: %v", err)
+		}
+		return
+	case "purge":
+		if err := runPurge(context.Background(), config, flag.Args()[1:]); err != nil {
+			log.Fatalf("Purge failed: %v", err)
+		}
+		return
+	default:
+		log.Fatalf("Unknown command %q", cmd)
+	}
+	if err := run(config); err != nil {
+		log.Fatalf("The metadata service failed: %v", err)
+	}
+}
+
+func run(cfg config) error {
+	// The service stops on SIGINT or SIGTERM.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%v", cfg.API.Port))
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	httpLis, err := net.Listen("tcp", fmt.Sprintf("localhost:%v", cfg.API.HTTPPort))
+	if err != nil {
+		lis.Close()
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	log.Printf("Starting the metadata service on %s (gRPC) and %s (HTTP)", lis.Addr(), httpLis.Addr())
 	// Register the service in Consul start
 	registry, err := consul.NewRegistry("localhost:8500")
 	if err != nil {
-		panic(err)
+		return err
 	}
-	ctx := context.Background()
 	instanceID := discovery.GenerateInstanceID(serviceName)
-	if err := registry.Register(ctx, instanceID, serviceName, fmt.Sprintf("localhost:%d", port)); err != nil {
-		panic(err)
+	// The ports are those of the listeners, which the system chooses if port 0 is configured.
+	endpoints := map[string]int{discovery.ProtocolGRPC: grpcutil.Port(lis), discovery.ProtocolHTTP: grpcutil.Port(httpLis)}
+	for protocol, p := range endpoints {
+		id := discovery.ProtocolInstanceID(instanceID, protocol)
+		if err := registry.Register(ctx, id, serviceName, fmt.Sprintf("localhost:%d", p), protocol); err != nil {
+			return err
+		}
+		defer registry.Deregister(context.Background(), id, serviceName)
 	}
 	go func() {
 		for {
-			if err := registry.ReportHealthyState(instanceID, serviceName); err != nil {
-				log.Println("Failed to report healthy state: " + err.Error())
+			for protocol := range endpoints {
+				if err := registry.ReportHealthyState(discovery.ProtocolInstanceID(instanceID, protocol), serviceName); err != nil {
+					log.Println("Failed to report healthy state: " + err.Error())
+				}
 			}
 			time.Sleep(1 * time.Second)
 		}
 	}()
-	defer registry.Deregister(ctx, instanceID, serviceName)
 	// Register the service in Consul end
-	mysqlConfig := config.API.MysqlConfig
-	dsn := mysqlConfig.FormatDSN()
-	repo, err := mysql.New(dsn)
+	repo, err := newRepository(ctx, cfg.API)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	ctrl := metadata.New(repo)
-	h := grpchandler.New(ctrl)
-	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%v", port))
+	store, err := newBlobStore(cfg.API.Assets)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		return err
+	}
+	assets := asset.New(store)
+	ctrl := metadata.New(repo, metadata.WithAssets(assets))
+	if cfg.API.Purge.Retention > 0 {
+		go purgePeriodically(ctx, ctrl, cfg.API.Purge)
 	}
-	srv := grpc.NewServer()
+	h := grpchandler.New(ctrl, assets)
+
+	// The REST/JSON gateway generated from api/movie.proto calls the gRPC handler in-process.
+	gwmux := runtime.NewServeMux()
+	if err := gen.RegisterMetadataServiceHandlerServer(ctx, gwmux, h); err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	hh := httphandler.New(ctrl)
+	mux.HandleFunc("GET /metadata", hh.GetMetadata)
+	mux.HandleFunc("PUT /metadata", hh.PutMetadata)
+	mux.HandleFunc("PATCH /metadata", hh.PatchMetadata)
+	mux.HandleFunc("DELETE /metadata", hh.DeleteMetadata)
+	ah := httphandler.NewAssetHandler(assets, ctrl)
+	mux.HandleFunc("POST /assets", ah.UploadAsset)
+	mux.HandleFunc("GET /assets/{hash}", ah.GetAsset)
+	mux.HandleFunc("GET /assets/{hash}/{size}", ah.GetAsset)
+	mux.Handle("/v1/", gwmux)
+
+	// Requests run in sessions so that reads can follow their own writes with read replicas.
+	srv := grpc.NewServer(grpc.UnaryInterceptor(mysqlutil.UnarySessionInterceptor), grpc.StreamInterceptor(mysqlutil.StreamSessionInterceptor))
 	reflection.Register(srv)
 	gen.RegisterMetadataServiceServer(srv, h)
-	if err := srv.Serve(lis); err != nil {
-		panic(err)
-	}
+	return grpcutil.Serve(ctx, srv, lis, &http.Server{Handler: mysqlutil.SessionHandler(mux)}, httpLis)
 }
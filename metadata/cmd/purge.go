@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/meirongdev/movie-microservice/metadata/internal/controller/metadata"
+)
+
+const defaultPurgeInterval = time.Hour
+
+// purgePeriodically purges metadata deleted longer than the retention ago, at every interval
+// until the context is done.
+func purgePeriodically(ctx context.Context, ctrl *metadata.Controller, cfg purgeConfig) {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = defaultPurgeInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := ctrl.Purge(ctx, time.Now().Add(-cfg.Retention)); err != nil {
+			log.Printf("Failed to purge deleted metadata: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d deleted movies", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runPurge runs the purge command, which purges metadata deleted longer than the retention ago
+// once. The retention is taken from purge.retention unless given as argument.
+func runPurge(ctx context.Context, cfg config, args []string) error {
+	retention := cfg.API.Purge.Retention
+	switch len(args) {
+	case 0:
+	case 1:
+		var err error
+		if retention, err = time.ParseDuration(args[0]); err != nil {
+			return fmt.Errorf("invalid retention: %w", err)
+		}
+	default:
+		return fmt.Errorf("usage: purge [retention]")
+	}
+	if retention <= 0 {
+		return fmt.Errorf("no retention configured")
+	}
+	repo, err := newRepository(ctx, cfg.API)
+	if err != nil {
+		return err
+	}
+	n, err := metadata.New(repo).Purge(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+	log.Printf("Purged %d movies deleted before %s", n, time.Now().Add(-retention).Format(time.RFC3339))
+	return nil
+}
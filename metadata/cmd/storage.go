@@ -0,0 +1,75 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/meirongdev/movie-microservice/metadata/internal/blob"
+	"github.com/meirongdev/movie-microservice/metadata/internal/blob/local"
+	"github.com/meirongdev/movie-microservice/metadata/internal/repository"
+	"github.com/meirongdev/movie-microservice/metadata/internal/repository/memory"
+	"github.com/meirongdev/movie-microservice/metadata/internal/repository/mysql"
+	"github.com/meirongdev/movie-microservice/metadata/internal/repository/sqlite"
+	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+)
+
+// metadataRepository is the repository interface of the metadata controller.
+type metadataRepository interface {
+	Get(ctx context.Context, id string) (*model.Metadata, error)
+	GetForUpdate(ctx context.Context, id string) (*model.Metadata, error)
+	Put(ctx context.Context, id string, metadata *model.Metadata, expectedVersion int64) (*model.Metadata, error)
+	List(ctx context.Context, q repository.ListQuery) ([]*model.Metadata, string, error)
+	Search(ctx context.Context, q repository.SearchQuery) ([]*model.Metadata, string, error)
+	ListRevisions(ctx context.Context, q repository.RevisionQuery) ([]*model.Revision, string, error)
+	GetRevision(ctx context.Context, id string, version int64) (*model.Revision, error)
+	GetRevisionAt(ctx context.Context, id string, t time.Time) (*model.Revision, error)
+	PutBatch(ctx context.Context, items []repository.BatchPut) ([]*model.Metadata, error)
+	Purge(ctx context.Context, before time.Time) (int, error)
+	ResolveExternalID(ctx context.Context, namespace, externalID string) (string, error)
+}
+
+// newRepository creates the repository selected by storage.driver, applying pending
+// migrations first if enabled.
+func newRepository(ctx context.Context, cfg apiConfig) (metadataRepository, error) {
+	switch cfg.Storage.Driver {
+	case "", "mysql":
+		if cfg.MysqlConfig.AutoMigrate {
+			m, err := mysql.NewMigrator(ctx, cfg.MysqlConfig)
+			if err != nil {
+				return nil, err
+			}
+			defer m.Close()
+			if _, err := m.Up(ctx); err != nil {
+				return nil, err
+			}
+		}
+		return mysql.New(ctx, cfg.MysqlConfig)
+	case "sqlite":
+		repo, err := sqlite.New(cfg.Storage.SQLite.FormatDSN())
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Storage.SQLite.AutoMigrate {
+			if err := repo.Migrate(ctx); err != nil {
+				return nil, err
+			}
+		}
+		return repo, nil
+	case "memory":
+		return memory.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+}
+
+// newBlobStore creates the blob store of uploaded images selected by assets.store.
+func newBlobStore(cfg assetsConfig) (blob.Store, error) {
+	switch cfg.Store {
+	case "", "local":
+		return local.New(cmp.Or(cfg.Local.Dir, "assets"))
+	default:
+		return nil, fmt.Errorf("unknown asset store %q", cfg.Store)
+	}
+}
@@ -0,0 +1,21 @@
+// Package blob defines the storage of the contents of uploaded assets.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when a blob is not found.
+var ErrNotFound = errors.New("blob not found")
+
+// Store defines a storage of blobs by key. Keys are slash-separated paths, e.g. "ab/abcd/original".
+type Store interface {
+	// Put writes a blob, replacing the blob of the same key. Readers never see partial blobs.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open opens a blob for reading.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Exists reports whether a blob exists.
+	Exists(ctx context.Context, key string) (bool, error)
+}
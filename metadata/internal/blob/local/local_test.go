@@ -0,0 +1,47 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/meirongdev/movie-microservice/metadata/internal/blob"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	s, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if ok, err := s.Exists(ctx, "ab/abcd/original"); err != nil || ok {
+		t.Fatalf("Exists of a missing blob: got %v, error %v, want false", ok, err)
+	}
+	if _, err := s.Open(ctx, "ab/abcd/original"); !errors.Is(err, blob.ErrNotFound) {
+		t.Fatalf("Open of a missing blob: got error %v, want %v", err, blob.ErrNotFound)
+	}
+	for _, content := range []string{"first", "second"} {
+		if err := s.Put(ctx, "ab/abcd/original", strings.NewReader(content)); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		r, err := s.Open(ctx, "ab/abcd/original")
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		b, err := io.ReadAll(r)
+		r.Close()
+		if err != nil || string(b) != content {
+			t.Fatalf("Open after Put: got %q, error %v, want %q", b, err, content)
+		}
+	}
+	if ok, err := s.Exists(ctx, "ab/abcd/original"); err != nil || !ok {
+		t.Fatalf("Exists: got %v, error %v, want true", ok, err)
+	}
+	for _, key := range []string{"../escape", "/etc/passwd", ""} {
+		if err := s.Put(ctx, key, strings.NewReader("x")); err == nil {
+			t.Errorf("Put of key %q: got no error", key)
+		}
+	}
+}
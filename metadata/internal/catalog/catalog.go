@@ -0,0 +1,245 @@
+// Package catalog reads and writes movie metadata as CSV or JSON lines, one movie per row, for
+// importing and exporting the catalog.
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+)
+
+// Format defines a file format of movie metadata.
+type Format string
+
+// Formats of movie metadata files.
+const (
+	FormatCSV       = Format("csv")
+	FormatJSONLines = Format("jsonl")
+)
+
+// FormatOf returns the format of a file by its extension: CSV for .csv, JSON lines otherwise.
+func FormatOf(path string) Format {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return FormatCSV
+	}
+	return FormatJSONLines
+}
+
+// RowError is returned when a row cannot be read. Reading can continue with the next row.
+type RowError struct {
+	// Line is the line of the row in the file, starting at 1.
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// column defines a column of movie metadata files, named as the JSON field of the metadata.
+type column struct {
+	name   string
+	format func(m *model.Metadata) string
+	parse  func(m *model.Metadata, s string) error
+}
+
+var columns = []column{
+	{"id", func(m *model.Metadata) string { return m.ID }, func(m *model.Metadata, s string) error { m.ID = s; return nil }},
+	{"title", func(m *model.Metadata) string { return m.Title }, func(m *model.Metadata, s string) error { m.Title = s; return nil }},
+	{"description", func(m *model.Metadata) string { return m.Description }, func(m *model.Metadata, s string) error { m.Description = s; return nil }},
+	{"director", func(m *model.Metadata) string { return m.Director }, func(m *model.Metadata, s string) error { m.Director = s; return nil }},
+	{"genres", func(m *model.Metadata) string { return formatList(m.Genres) }, parseGenres},
+	{"credits", func(m *model.Metadata) string { return formatList(m.Credits) }, parseCredits},
+	{"releaseDate", func(m *model.Metadata) string { return m.ReleaseDate }, func(m *model.Metadata, s string) error { m.ReleaseDate = s; return nil }},
+	{"runtimeMinutes", formatRuntime, parseRuntime},
+	{"language", func(m *model.Metadata) string { return m.Language }, func(m *model.Metadata, s string) error { m.Language = s; return nil }},
+	{"country", func(m *model.Metadata) string { return m.Country }, func(m *model.Metadata, s string) error { m.Country = s; return nil }},
+	{"ageRating", func(m *model.Metadata) string { return m.AgeRating }, func(m *model.Metadata, s string) error { m.AgeRating = s; return nil }},
+	{"externalIds", func(m *model.Metadata) string { return formatMap(m.ExternalIDs) }, parseExternalIDs},
+	{"localizations", func(m *model.Metadata) string { return formatMap(m.Localizations) }, parseLocalizations},
+	{"assets", func(m *model.Metadata) string { return formatList(m.Assets) }, parseAssets},
+}
+
+// Mapping maps the names of metadata fields to the columns of CSV files or the keys of JSON lines
+// holding them. Fields not in the mapping are read from the column or key of their own name.
+type Mapping map[string]string
+
+// ParseMapping parses a mapping of comma-separated field=column pairs, e.g.
+// "title=name,releaseDate=released".
+func ParseMapping(s string) (Mapping, error) {
+	m := Mapping{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		field, source, ok := strings.Cut(pair, "=")
+		field, source = strings.TrimSpace(field), strings.TrimSpace(source)
+		if !ok || source == "" {
+			return nil, fmt.Errorf("invalid mapping %q, want field=column", pair)
+		}
+		if !slices.ContainsFunc(columns, func(c column) bool { return c.name == field }) {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		m[field] = source
+	}
+	return m, nil
+}
+
+// source returns the column or key holding a field.
+func (m Mapping) source(field string) string {
+	if s, ok := m[field]; ok {
+		return s
+	}
+	return field
+}
+
+// formatList formats a list as a JSON array, empty if it has no elements.
+func formatList[T any](list []T) string {
+	if len(list) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(list)
+	return string(b)
+}
+
+// parseGenres parses genres as a JSON array or separated by semicolons.
+func parseGenres(m *model.Metadata, s string) error {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") {
+		return json.Unmarshal([]byte(s), &m.Genres)
+	}
+	m.Genres = nil
+	for _, g := range strings.Split(s, ";") {
+		if g = strings.TrimSpace(g); g != "" {
+			m.Genres = append(m.Genres, g)
+		}
+	}
+	return nil
+}
+
+// parseCredits parses credits as a JSON array.
+func parseCredits(m *model.Metadata, s string) error {
+	m.Credits = nil
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(s), &m.Credits); err != nil {
+		return fmt.Errorf("credits are not a JSON array of credits: %w", err)
+	}
+	return nil
+}
+
+// formatMap formats a map as a JSON object, empty if it has no entries.
+func formatMap[V any](m map[string]V) string {
+	if len(m) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+// parseExternalIDs parses external ids as a JSON object of ids by namespace.
+func parseExternalIDs(m *model.Metadata, s string) error {
+	m.ExternalIDs = nil
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(s), &m.ExternalIDs); err != nil {
+		return fmt.Errorf("external ids are not a JSON object of ids by namespace: %w", err)
+	}
+	return nil
+}
+
+// parseLocalizations parses localizations as a JSON object of titles and descriptions by locale.
+func parseLocalizations(m *model.Metadata, s string) error {
+	m.Localizations = nil
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(s), &m.Localizations); err != nil {
+		return fmt.Errorf("localizations are not a JSON object of localizations by locale: %w", err)
+	}
+	return nil
+}
+
+// parseAssets parses the images of a movie as a JSON array of kinds and hashes of uploaded images.
+func parseAssets(m *model.Metadata, s string) error {
+	m.Assets = nil
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(s), &m.Assets); err != nil {
+		return fmt.Errorf("assets are not a JSON array of asset references: %w", err)
+	}
+	return nil
+}
+
+func formatRuntime(m *model.Metadata) string {
+	if m.RuntimeMinutes == 0 {
+		return ""
+	}
+	return strconv.Itoa(int(m.RuntimeMinutes))
+}
+
+func parseRuntime(m *model.Metadata, s string) error {
+	m.RuntimeMinutes = 0
+	if s = strings.TrimSpace(s); s == "" {
+		return nil
+	}
+	n, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return fmt.Errorf("runtime %q is not a number of minutes", s)
+	}
+	m.RuntimeMinutes = int32(n)
+	return nil
+}
+
+// Reader defines a reader of movie metadata rows.
+type Reader interface {
+	// Read returns the metadata of the next row, a *RowError if the row is malformed, or io.EOF
+	// after the last row.
+	Read() (*model.Metadata, error)
+	// Line returns the line of the last row read.
+	Line() int
+}
+
+// Writer defines a writer of movie metadata rows.
+type Writer interface {
+	Write(m *model.Metadata) error
+	// Flush writes buffered rows to the underlying writer.
+	Flush() error
+}
+
+// NewReader creates a reader of a file in a format, reading fields from the columns or keys given
+// by the mapping. CSV files must start with a header naming their columns.
+func NewReader(r io.Reader, format Format, mapping Mapping) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r, mapping)
+	case FormatJSONLines:
+		return newJSONLinesReader(r, mapping), nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// NewWriter creates a writer of a file in a format. CSV files start with a header naming the
+// metadata fields.
+func NewWriter(w io.Writer, format Format) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatJSONLines:
+		return newJSONLinesWriter(w), nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
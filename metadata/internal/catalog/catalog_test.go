@@ -0,0 +1,213 @@
+package catalog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+)
+
+var testMovies = []*model.Metadata{
+	{
+		ID:             "1",
+		Title:          "Alien",
+		Description:    "In space, no one can hear you scream.\nA classic, \"quoted\".",
+		Director:       "Ridley Scott",
+		Genres:         []string{"Horror", "Sci-Fi"},
+		Credits:        []model.Credit{{Name: "Sigourney Weaver", Role: model.RoleActor, Character: "Ripley"}, {Name: "Ridley Scott", Role: model.RoleDirector}},
+		ReleaseDate:    "1979-05-25",
+		RuntimeMinutes: 117,
+		Language:       "en",
+		Country:        "US",
+		AgeRating:      "R",
+		ExternalIDs:    map[string]string{model.NamespaceIMDb: "tt0078748"},
+		Localizations:  map[string]model.Localization{"de": {Title: "Alien - Das unheimliche Wesen aus einer fremden Welt"}},
+		Assets:         []model.AssetRef{{Kind: model.AssetPoster, Hash: strings.Repeat("ab", 32)}},
+	},
+	{ID: "2", Title: "Untitled"},
+}
+
+// readAll reads the rows of a file, collecting the row errors.
+func readAll(t *testing.T, r Reader) ([]*model.Metadata, []*RowError) {
+	t.Helper()
+	var res []*model.Metadata
+	var rowErrs []*RowError
+	for {
+		m, err := r.Read()
+		var rowErr *RowError
+		if err == io.EOF {
+			return res, rowErrs
+		} else if errors.As(err, &rowErr) {
+			rowErrs = append(rowErrs, rowErr)
+			continue
+		} else if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		res = append(res, m)
+	}
+}
+
+func newTestReader(t *testing.T, content string, format Format, mapping Mapping) Reader {
+	t.Helper()
+	r, err := NewReader(strings.NewReader(content), format, mapping)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	return r
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatCSV, FormatJSONLines} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, format)
+			if err != nil {
+				t.Fatalf("NewWriter: %v", err)
+			}
+			for _, m := range testMovies {
+				if err := w.Write(m); err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+			got, rowErrs := readAll(t, newTestReader(t, buf.String(), format, nil))
+			if len(rowErrs) > 0 {
+				t.Fatalf("Read: got row errors %v", rowErrs)
+			}
+			if !reflect.DeepEqual(got, testMovies) {
+				t.Fatalf("round trip:\n got %+v\nwant %+v", got, testMovies)
+			}
+		})
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for path, want := range map[string]Format{"movies.csv": FormatCSV, "MOVIES.CSV": FormatCSV, "movies.jsonl": FormatJSONLines, "-": FormatJSONLines} {
+		if got := FormatOf(path); got != want {
+			t.Errorf("FormatOf(%q): got %q, want %q", path, got, want)
+		}
+	}
+	if _, err := NewReader(strings.NewReader(""), "xml", nil); err == nil {
+		t.Error("NewReader of an unknown format: got no error")
+	}
+	if _, err := NewWriter(io.Discard, "xml"); err == nil {
+		t.Error("NewWriter of an unknown format: got no error")
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Mapping
+		wantErr bool
+	}{
+		{"", Mapping{}, false},
+		{"title=name", Mapping{"title": "name"}, false},
+		{" title = name , releaseDate=released,", Mapping{"title": "name", "releaseDate": "released"}, false},
+		{"title", nil, true},
+		{"title=", nil, true},
+		{"name=title", nil, true},
+		{"release_date=released", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMapping(tt.s)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("ParseMapping(%q): got %v, error %v, want %v", tt.s, got, err, tt.want)
+		}
+	}
+}
+
+func TestMapping(t *testing.T) {
+	mapping := Mapping{"id": "imdb_id", "title": "name", "releaseDate": "released"}
+	want := []*model.Metadata{{ID: "tt1", Title: "Alien", ReleaseDate: "1979-05-25", Director: "Ridley Scott"}}
+	t.Run("csv", func(t *testing.T) {
+		content := "imdb_id,name,released,director,title\ntt1,Alien,1979-05-25,Ridley Scott,ignored\n"
+		got, rowErrs := readAll(t, newTestReader(t, content, FormatCSV, mapping))
+		if len(rowErrs) > 0 || !reflect.DeepEqual(got, want) {
+			t.Fatalf("Read: got %+v, errors %v, want %+v", got, rowErrs, want)
+		}
+	})
+	t.Run("jsonl", func(t *testing.T) {
+		content := `{"imdb_id":"tt1","name":"Alien","released":"1979-05-25","director":"Ridley Scott","title":"ignored"}` + "\n"
+		got, rowErrs := readAll(t, newTestReader(t, content, FormatJSONLines, mapping))
+		if len(rowErrs) > 0 || !reflect.DeepEqual(got, want) {
+			t.Fatalf("Read: got %+v, errors %v, want %+v", got, rowErrs, want)
+		}
+	})
+	t.Run("missing columns", func(t *testing.T) {
+		for _, content := range []string{"name,released\nAlien,1979\n", "imdb_id,title\ntt1,Alien\n", ""} {
+			if _, err := NewReader(strings.NewReader(content), FormatCSV, mapping); err == nil {
+				t.Errorf("NewReader of CSV %q: got no error", content)
+			}
+		}
+	})
+}
+
+func TestCSVRowErrors(t *testing.T) {
+	content := "\ufeffid,title,description,runtimeMinutes,credits\n" +
+		"1,Alien,\"first line\nsecond line\",117,\n" +
+		"\n" +
+		"2,Aliens,,long,\n" +
+		"3,Alien 3,\"multi\nline\",114,\"[{\"\"name\"\":\"\"David Fincher\"\"\"\n" +
+		",Untitled,,,\n" +
+		"4,Alien Resurrection,,109,\"[{\"\"name\"\":\"\"Jean-Pierre Jeunet\"\",\"\"role\"\":\"\"director\"\"}]\"\n" +
+		"5,\"unterminated\n"
+	r := newTestReader(t, content, FormatCSV, nil)
+	wantRows := []struct {
+		line int
+		id   string
+		err  string
+	}{
+		{2, "1", ""},
+		{5, "", "runtimeMinutes"},
+		{6, "", "credits"},
+		{8, "", "empty id"},
+		{9, "4", ""},
+		{10, "", "quote"},
+	}
+	for _, want := range wantRows {
+		m, err := r.Read()
+		var rowErr *RowError
+		switch {
+		case want.err == "" && err != nil:
+			t.Fatalf("line %d: %v", want.line, err)
+		case want.err == "" && (m.ID != want.id || r.Line() != want.line):
+			t.Fatalf("got movie %q at line %d, want %q at line %d", m.ID, r.Line(), want.id, want.line)
+		case want.err != "" && !errors.As(err, &rowErr):
+			t.Fatalf("line %d: got %v, error %v, want a row error", want.line, m, err)
+		case want.err != "" && (rowErr.Line != want.line || !strings.Contains(rowErr.Error(), want.err)):
+			t.Fatalf("got error %q, want line %d: %s", rowErr, want.line, want.err)
+		}
+	}
+	if m, err := r.Read(); err != io.EOF {
+		t.Fatalf("Read after the last row: got %v, error %v, want EOF", m, err)
+	}
+}
+
+func TestJSONLinesRowErrors(t *testing.T) {
+	content := "\ufeff{\"id\":\"1\",\"title\":\"Alien\"}\n" +
+		"\n" +
+		"   \n" +
+		"{\"id\":\"2\",\"runtimeMinutes\":\"long\"}\n" +
+		"{\"id\":\"3\",\"credits\":{\"name\":\"David Fincher\"}}\n" +
+		"{\"title\":\"Untitled\"}\n" +
+		"not json\n" +
+		"{\"id\":\"4\",\"title\":\"Alien Resurrection\"}"
+	got, rowErrs := readAll(t, newTestReader(t, content, FormatJSONLines, Mapping{"description": "summary"}))
+	if len(got) != 2 || got[0].ID != "1" || got[1].ID != "4" {
+		t.Fatalf("Read: got %+v, want movies 1 and 4", got)
+	}
+	var lines []int
+	for _, err := range rowErrs {
+		lines = append(lines, err.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{4, 5, 6, 7}) {
+		t.Fatalf("Read: got row errors at lines %v, want 4, 5, 6 and 7", lines)
+	}
+}
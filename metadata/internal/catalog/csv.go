@@ -0,0 +1,107 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+)
+
+type csvReader struct {
+	r *csv.Reader
+	// indexes are the indexes of the columns holding the fields in columns, -1 if missing.
+	indexes []int
+	line    int
+}
+
+func newCSVReader(r io.Reader, mapping Mapping) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("missing CSV header")
+	} else if err != nil {
+		return nil, err
+	}
+	names := map[string]int{}
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		names[strings.TrimSpace(name)] = i
+	}
+	res := &csvReader{r: cr, indexes: make([]int, len(columns))}
+	for i, c := range columns {
+		index, ok := names[mapping.source(c.name)]
+		if !ok {
+			if _, mapped := mapping[c.name]; mapped || c.name == "id" {
+				return nil, fmt.Errorf("missing CSV column %q of field %s", mapping.source(c.name), c.name)
+			}
+			index = -1
+		}
+		res.indexes[i] = index
+	}
+	return res, nil
+}
+
+func (r *csvReader) Read() (*model.Metadata, error) {
+	record, err := r.r.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		r.line = parseErr.StartLine
+		return nil, &RowError{Line: r.line, Err: parseErr.Err}
+	} else if err != nil {
+		return nil, err
+	}
+	r.line, _ = r.r.FieldPos(0)
+	m := &model.Metadata{}
+	for i, c := range columns {
+		index := r.indexes[i]
+		if index < 0 || index >= len(record) {
+			continue
+		}
+		if err := c.parse(m, record[index]); err != nil {
+			return nil, &RowError{Line: r.line, Err: fmt.Errorf("%s: %w", c.name, err)}
+		}
+	}
+	if m.ID == "" {
+		return nil, &RowError{Line: r.line, Err: errors.New("empty id")}
+	}
+	return m, nil
+}
+
+func (r *csvReader) Line() int {
+	return r.line
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	res := &csvWriter{w: csv.NewWriter(w), record: make([]string, len(columns))}
+	for i, c := range columns {
+		res.record[i] = c.name
+	}
+	if err := res.w.Write(res.record); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (w *csvWriter) Write(m *model.Metadata) error {
+	for i, c := range columns {
+		w.record[i] = c.format(m)
+	}
+	return w.w.Write(w.record)
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
@@ -0,0 +1,101 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+)
+
+type jsonLinesReader struct {
+	scanner *bufio.Scanner
+	mapping Mapping
+	line    int
+}
+
+func newJSONLinesReader(r io.Reader, mapping Mapping) *jsonLinesReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	return &jsonLinesReader{scanner: scanner, mapping: mapping}
+}
+
+func (r *jsonLinesReader) Read() (*model.Metadata, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := r.scanner.Bytes()
+		if r.line == 1 {
+			line = bytes.TrimPrefix(line, []byte("\ufeff"))
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		m, err := r.decode(line)
+		if err != nil {
+			return nil, &RowError{Line: r.line, Err: err}
+		}
+		return m, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func (r *jsonLinesReader) Line() int {
+	return r.line
+}
+
+// decode decodes a line of metadata in JSON, renaming the keys of mapped fields first.
+func (r *jsonLinesReader) decode(line []byte) (*model.Metadata, error) {
+	if len(r.mapping) > 0 {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(line, &obj); err != nil {
+			return nil, err
+		}
+		renamed := make(map[string]json.RawMessage, len(obj))
+		for key, value := range obj {
+			renamed[key] = value
+		}
+		for _, source := range r.mapping {
+			delete(renamed, source)
+		}
+		for field, source := range r.mapping {
+			if value, ok := obj[source]; ok {
+				renamed[field] = value
+			}
+		}
+		var err error
+		if line, err = json.Marshal(renamed); err != nil {
+			return nil, err
+		}
+	}
+	var m model.Metadata
+	if err := json.Unmarshal(line, &m); err != nil {
+		return nil, err
+	}
+	if m.ID == "" {
+		return nil, errors.New("empty id")
+	}
+	return &m, nil
+}
+
+type jsonLinesWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newJSONLinesWriter(w io.Writer) *jsonLinesWriter {
+	bw := bufio.NewWriter(w)
+	return &jsonLinesWriter{bw, json.NewEncoder(bw)}
+}
+
+func (w *jsonLinesWriter) Write(m *model.Metadata) error {
+	return w.enc.Encode(m)
+}
+
+func (w *jsonLinesWriter) Flush() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,197 @@
+package asset
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"regexp"
+	"slices"
+
+	"github.com/meirongdev/movie-microservice/metadata/internal/blob"
+	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ErrNotFound is returned when a requested asset is not found.
+var ErrNotFound = errors.New("asset not found")
+
+// ErrInvalidArgument is returned when an upload is not a supported image or is too large.
+var ErrInvalidArgument = errors.New("invalid argument")
+
+const (
+	// MaxSize is the maximum size of uploaded images in bytes.
+	MaxSize = 20 << 20
+	// maxPixels bounds the pixels of uploaded images, which are decoded in memory: a 4000x6000
+	// poster takes 96 MiB decoded.
+	maxPixels = 4000 * 6000
+	// maxDecodes bounds the images decoded at the same time.
+	maxDecodes = 2
+	// thumbnailQuality is the JPEG quality of thumbnails.
+	thumbnailQuality = 85
+)
+
+var hashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ValidHash reports whether a hash is a hex-encoded SHA-256 hash, as assets are stored under.
+func ValidHash(hash string) bool {
+	return hashPattern.MatchString(hash)
+}
+
+// Controller defines an asset controller, storing uploaded images once per content.
+type Controller struct {
+	store   blob.Store
+	decodes chan struct{}
+}
+
+// New creates an asset controller storing images in a blob store.
+func New(store blob.Store) *Controller {
+	return &Controller{store, make(chan struct{}, maxDecodes)}
+}
+
+// Upload stores a JPEG, PNG, GIF or WebP image under the hash of its content, with JPEG thumbnails
+// of every size of model.ThumbnailSizes, and returns the asset. Images whose content is already
+// stored are not stored again, which is reported by deduplicated.
+func (c *Controller) Upload(ctx context.Context, r io.Reader) (a *model.Asset, deduplicated bool, err error) {
+	var buf bytes.Buffer
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(&buf, h), io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if n > MaxSize {
+		return nil, false, fmt.Errorf("%w: image larger than %d bytes", ErrInvalidArgument, MaxSize)
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+	if a, err := c.Get(ctx, hash); err == nil {
+		return a, true, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, false, err
+	}
+	content := buf.Bytes()
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: not a supported image: %v", ErrInvalidArgument, err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, false, fmt.Errorf("%w: image of %dx%d pixels is too large", ErrInvalidArgument, cfg.Width, cfg.Height)
+	}
+	thumbnails, err := c.thumbnails(ctx, content)
+	if err != nil {
+		return nil, false, err
+	}
+	a = &model.Asset{Hash: hash, ContentType: "image/" + format, Size: n, Width: cfg.Width, Height: cfg.Height}
+	for i, size := range model.ThumbnailSizes {
+		if err := c.store.Put(ctx, key(hash, size.Name), bytes.NewReader(thumbnails[i])); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := c.store.Put(ctx, key(hash, "original"), bytes.NewReader(content)); err != nil {
+		return nil, false, err
+	}
+	// The asset is written last, so that stored assets have their images and thumbnails.
+	info, err := json.Marshal(a)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := c.store.Put(ctx, key(hash, "asset.json"), bytes.NewReader(info)); err != nil {
+		return nil, false, err
+	}
+	return a, false, nil
+}
+
+// thumbnails decodes an image and returns its JPEG thumbnails of the sizes of
+// model.ThumbnailSizes. At most maxDecodes images are decoded at the same time.
+func (c *Controller) thumbnails(ctx context.Context, content []byte) ([][]byte, error) {
+	select {
+	case c.decodes <- struct{}{}:
+		defer func() { <-c.decodes }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a supported image: %v", ErrInvalidArgument, err)
+	}
+	res := make([][]byte, len(model.ThumbnailSizes))
+	for i, size := range model.ThumbnailSizes {
+		var thumbnail bytes.Buffer
+		if err := jpeg.Encode(&thumbnail, resize(img, size.Width), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+			return nil, err
+		}
+		res[i] = thumbnail.Bytes()
+	}
+	return res, nil
+}
+
+// Get returns an asset by the hash of its content.
+func (c *Controller) Get(ctx context.Context, hash string) (*model.Asset, error) {
+	if !ValidHash(hash) {
+		return nil, ErrNotFound
+	}
+	r, err := c.store.Open(ctx, key(hash, "asset.json"))
+	if err != nil && errors.Is(err, blob.ErrNotFound) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var a model.Asset
+	if err := json.NewDecoder(r).Decode(&a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Open opens the image of an asset, or its thumbnail of a size if size is not empty, and returns
+// its content type.
+func (c *Controller) Open(ctx context.Context, hash, size string) (io.ReadCloser, string, error) {
+	a, err := c.Get(ctx, hash)
+	if err != nil {
+		return nil, "", err
+	}
+	name, contentType := "original", a.ContentType
+	if size != "" {
+		if !slices.ContainsFunc(model.ThumbnailSizes, func(s model.ThumbnailSize) bool { return s.Name == size }) {
+			return nil, "", ErrNotFound
+		}
+		name, contentType = size, "image/jpeg"
+	}
+	r, err := c.store.Open(ctx, key(hash, name))
+	if err != nil && errors.Is(err, blob.ErrNotFound) {
+		return nil, "", ErrNotFound
+	}
+	return r, contentType, err
+}
+
+// key returns the key of a blob of an asset. Assets are spread over directories by the first byte
+// of their hash.
+func key(hash, name string) string {
+	return hash[:2] + "/" + hash + "/" + name
+}
+
+// resize scales an image down to a width, keeping its aspect ratio, on a white background for
+// transparent images. Narrower images keep their size.
+func resize(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	height := b.Dy()
+	if b.Dx() > width {
+		height = max(b.Dy()*width/b.Dx(), 1)
+	} else {
+		width = b.Dx()
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	return dst
+}
@@ -0,0 +1,148 @@
+package asset
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/meirongdev/movie-microservice/metadata/internal/blob/local"
+	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+)
+
+func newTestController(t *testing.T) *Controller {
+	t.Helper()
+	store, err := local.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(store)
+}
+
+// encodePNG returns a PNG image of a size.
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := range width {
+		img.Set(x, x*height/width, color.NRGBA{R: 200, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUpload(t *testing.T) {
+	ctx := context.Background()
+	c := newTestController(t)
+	content := encodePNG(t, 1000, 500)
+	a, deduplicated, err := c.Upload(ctx, bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if deduplicated || !ValidHash(a.Hash) || a.ContentType != "image/png" || a.Size != int64(len(content)) || a.Width != 1000 || a.Height != 500 {
+		t.Fatalf("Upload: got %+v, deduplicated %v, want a 1000x500 PNG", a, deduplicated)
+	}
+	again, deduplicated, err := c.Upload(ctx, bytes.NewReader(content))
+	if err != nil || !deduplicated || *again != *a {
+		t.Fatalf("Upload of the same image: got %+v, deduplicated %v, error %v, want %+v deduplicated", again, deduplicated, err, a)
+	}
+	if got, err := c.Get(ctx, a.Hash); err != nil || *got != *a {
+		t.Fatalf("Get: got %+v, error %v, want %+v", got, err, a)
+	}
+
+	r, contentType, err := c.Open(ctx, a.Hash, "")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	original, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || contentType != "image/png" || !bytes.Equal(original, content) {
+		t.Fatalf("Open: got %d bytes of %s, error %v, want the uploaded PNG", len(original), contentType, err)
+	}
+	for _, size := range model.ThumbnailSizes {
+		r, contentType, err := c.Open(ctx, a.Hash, size.Name)
+		if err != nil {
+			t.Fatalf("Open of %s: %v", size.Name, err)
+		}
+		img, err := jpeg.Decode(r)
+		r.Close()
+		if err != nil || contentType != "image/jpeg" {
+			t.Fatalf("Open of %s: got %s, error %v, want a JPEG", size.Name, contentType, err)
+		}
+		if b := img.Bounds(); b.Dx() != size.Width || b.Dy() != size.Width/2 {
+			t.Fatalf("Open of %s: got %dx%d, want %dx%d", size.Name, b.Dx(), b.Dy(), size.Width, size.Width/2)
+		}
+	}
+}
+
+func TestUploadKeepsSmallImages(t *testing.T) {
+	ctx := context.Background()
+	c := newTestController(t)
+	a, _, err := c.Upload(ctx, bytes.NewReader(encodePNG(t, 300, 200)))
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	for _, size := range model.ThumbnailSizes {
+		want := min(size.Width, 300)
+		r, _, err := c.Open(ctx, a.Hash, size.Name)
+		if err != nil {
+			t.Fatalf("Open of %s: %v", size.Name, err)
+		}
+		cfg, err := jpeg.DecodeConfig(r)
+		r.Close()
+		if err != nil || cfg.Width != want {
+			t.Fatalf("Open of %s: got width %d, error %v, want %d", size.Name, cfg.Width, err, want)
+		}
+	}
+}
+
+func TestUploadInvalid(t *testing.T) {
+	ctx := context.Background()
+	c := newTestController(t)
+	var huge bytes.Buffer
+	if err := png.Encode(&huge, image.NewGray(image.Rect(0, 0, 5000, 5000))); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		r    io.Reader
+	}{
+		{"empty", strings.NewReader("")},
+		{"not an image", strings.NewReader("not an image")},
+		{"truncated", bytes.NewReader(encodePNG(t, 100, 100)[:100])},
+		{"too many pixels", &huge},
+		{"oversized", io.MultiReader(bytes.NewReader(encodePNG(t, 100, 100)), bytes.NewReader(make([]byte, MaxSize)))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if a, _, err := c.Upload(ctx, tt.r); !errors.Is(err, ErrInvalidArgument) {
+				t.Fatalf("Upload: got %+v, error %v, want %v", a, err, ErrInvalidArgument)
+			}
+		})
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	ctx := context.Background()
+	c := newTestController(t)
+	a, _, err := c.Upload(ctx, bytes.NewReader(encodePNG(t, 100, 100)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hash := range []string{strings.Repeat("0", 64), "../" + a.Hash[3:], strings.ToUpper(a.Hash), ""} {
+		if _, err := c.Get(ctx, hash); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q): got error %v, want %v", hash, err, ErrNotFound)
+		}
+	}
+	if _, _, err := c.Open(ctx, a.Hash, "w1000"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open of an unknown size: got error %v, want %v", err, ErrNotFound)
+	}
+}
@@ -3,33 +3,591 @@ package metadata
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/meirongdev/movie-microservice/metadata/internal/controller/asset"
 	"github.com/meirongdev/movie-microservice/metadata/internal/repository"
 	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+	"github.com/meirongdev/movie-microservice/pkg/locale"
 )
 
 // ErrNotFound is returned when a requested record is not found.
 var ErrNotFound = errors.New("not found")
 
+// ErrVersionMismatch is returned when a write expects another version of the metadata than the stored one.
+var ErrVersionMismatch = errors.New("metadata version mismatch")
+
+// AnyVersion is the expected version of unconditional writes.
+const AnyVersion = repository.AnyVersion
+
+// ErrInvalidArgument is returned when metadata, a listing or a search is malformed.
+var ErrInvalidArgument = errors.New("invalid argument")
+
+// ErrNotDeleted is returned when undeleting movie metadata which is not deleted.
+var ErrNotDeleted = errors.New("metadata not deleted")
+
+// ErrDuplicateExternalID is returned when a write assigns an external id of another movie.
+var ErrDuplicateExternalID = repository.ErrDuplicateExternalID
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
 type metadataRepository interface {
 	Get(ctx context.Context, id string) (*model.Metadata, error)
+	GetForUpdate(ctx context.Context, id string) (*model.Metadata, error)
+	Put(ctx context.Context, id string, metadata *model.Metadata, expectedVersion int64) (*model.Metadata, error)
+	List(ctx context.Context, q repository.ListQuery) ([]*model.Metadata, string, error)
+	Search(ctx context.Context, q repository.SearchQuery) ([]*model.Metadata, string, error)
+	ListRevisions(ctx context.Context, q repository.RevisionQuery) ([]*model.Revision, string, error)
+	GetRevision(ctx context.Context, id string, version int64) (*model.Revision, error)
+	GetRevisionAt(ctx context.Context, id string, t time.Time) (*model.Revision, error)
+	PutBatch(ctx context.Context, items []repository.BatchPut) ([]*model.Metadata, error)
+	Purge(ctx context.Context, before time.Time) (int, error)
+	ResolveExternalID(ctx context.Context, namespace, externalID string) (string, error)
+}
+
+type assetStore interface {
+	Get(ctx context.Context, hash string) (*model.Asset, error)
 }
 
 // Controller defines a metadata service controller.
 type Controller struct {
 	repo metadataRepository
+	config
+}
+
+type config struct {
+	assets assetStore
+}
+
+// Option configures a metadata controller.
+type Option func(*config)
+
+// WithAssets sets the store of uploaded images, so that writes referring to images which are not
+// stored fail. Without it, only the kinds and hashes of images are checked.
+func WithAssets(assets assetStore) Option {
+	return func(c *config) {
+		c.assets = assets
+	}
 }
 
 // New creates a metadata service controller.
-func New(repo metadataRepository) *Controller {
-	return &Controller{repo}
+func New(repo metadataRepository, options ...Option) *Controller {
+	c := &Controller{repo: repo}
+	for _, o := range options {
+		o(&c.config)
+	}
+	return c
 }
 
-// Get returns movie metadata by id.
+// Get returns movie metadata by id. Deleted metadata is not found.
 func (c *Controller) Get(ctx context.Context, id string) (*model.Metadata, error) {
+	res, err := c.GetIncludingDeleted(ctx, id)
+	if err == nil && res.Tombstone != nil {
+		return nil, ErrNotFound
+	}
+	return res, err
+}
+
+// GetIncludingDeleted returns movie metadata by id, with a tombstone if it is deleted.
+func (c *Controller) GetIncludingDeleted(ctx context.Context, id string) (*model.Metadata, error) {
 	res, err := c.repo.Get(ctx, id)
 	if err != nil && errors.Is(err, repository.ErrNotFound) {
 		return nil, ErrNotFound
 	}
 	return res, err
 }
+
+// getForUpdate returns movie metadata by id, with a tombstone if it is deleted, as stored by the
+// latest write, to be changed and written back.
+func (c *Controller) getForUpdate(ctx context.Context, id string) (*model.Metadata, error) {
+	res, err := c.repo.GetForUpdate(ctx, id)
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return res, err
+}
+
+// GetVersion returns movie metadata as written by a version. Unless includeDeleted is set, the
+// metadata of movies currently deleted is not found.
+func (c *Controller) GetVersion(ctx context.Context, id string, version int64, includeDeleted bool) (*model.Metadata, error) {
+	if err := c.checkDeleted(ctx, id, includeDeleted); err != nil {
+		return nil, err
+	}
+	return revisionMetadata(c.repo.GetRevision(ctx, id, version))
+}
+
+// GetAsOf returns movie metadata as it was at a time. Unless includeDeleted is set, the metadata
+// of movies currently deleted is not found.
+func (c *Controller) GetAsOf(ctx context.Context, id string, t time.Time, includeDeleted bool) (*model.Metadata, error) {
+	if err := c.checkDeleted(ctx, id, includeDeleted); err != nil {
+		return nil, err
+	}
+	return revisionMetadata(c.repo.GetRevisionAt(ctx, id, t))
+}
+
+// checkDeleted returns ErrNotFound if a movie is currently deleted, unless includeDeleted is set.
+func (c *Controller) checkDeleted(ctx context.Context, id string, includeDeleted bool) error {
+	if includeDeleted {
+		return nil
+	}
+	_, err := c.Get(ctx, id)
+	return err
+}
+
+func revisionMetadata(rev *model.Revision, err error) (*model.Metadata, error) {
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &rev.Metadata, nil
+}
+
+// ListRevisions returns a page of the change history of movie metadata, the latest revision first,
+// and the token of the next page, empty on the last page.
+func (c *Controller) ListRevisions(ctx context.Context, id string, pageSize int, pageToken string) ([]*model.Revision, string, error) {
+	res, next, err := c.repo.ListRevisions(ctx, repository.RevisionQuery{MovieID: id, PageSize: normalizePageSize(pageSize), Cursor: pageToken})
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		err = fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	return res, next, err
+}
+
+// Restore writes movie metadata as written by a version, if its stored version is the expected
+// one or with AnyVersion, and returns the metadata as written.
+func (c *Controller) Restore(ctx context.Context, id string, version int64, editor string, expectedVersion int64) (*model.Metadata, error) {
+	m, err := c.GetVersion(ctx, id, version, true)
+	if err != nil {
+		return nil, err
+	}
+	m.UpdatedBy = editor
+	return c.Put(ctx, m, expectedVersion)
+}
+
+// maxUpdateAttempts bounds the attempts of updates conflicting with concurrent writes.
+const maxUpdateAttempts = 5
+
+// Update writes the fields of movie metadata named by paths (see model.Metadata.UpdateFields),
+// keeping the other stored fields, if its stored version is the expected one or with AnyVersion,
+// and returns the metadata as written. The editor is taken from src. Updates without an expected
+// version are retried when concurrent writes change the metadata.
+func (c *Controller) Update(ctx context.Context, src *model.Metadata, paths []string, expectedVersion int64) (*model.Metadata, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%w: no fields to update", ErrInvalidArgument)
+	}
+	for attempt := 1; ; attempt++ {
+		current, err := c.getForUpdate(ctx, src.ID)
+		if err == nil && current.Tombstone != nil {
+			err = ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if expectedVersion != AnyVersion && current.Version != expectedVersion {
+			return nil, ErrVersionMismatch
+		}
+		m := current.Clone()
+		if err := m.UpdateFields(src, paths); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+		}
+		m.UpdatedBy = src.UpdatedBy
+		res, err := c.Put(ctx, m, current.Version)
+		if errors.Is(err, ErrVersionMismatch) && expectedVersion == AnyVersion && attempt < maxUpdateAttempts {
+			continue
+		}
+		return res, err
+	}
+}
+
+// Delete marks movie metadata as deleted with a reason, if its stored version is the expected one
+// or with AnyVersion, and returns the metadata as written. Deleted metadata keeps its revisions and
+// can be undeleted until it is purged.
+func (c *Controller) Delete(ctx context.Context, id, reason, editor string, expectedVersion int64) (*model.Metadata, error) {
+	return c.modify(ctx, id, editor, expectedVersion, func(m *model.Metadata) error {
+		if m.Tombstone != nil {
+			return ErrNotFound
+		}
+		m.Tombstone = &model.Tombstone{DeletedAt: time.Now().UTC().Truncate(time.Microsecond), Reason: reason}
+		return nil
+	})
+}
+
+// Undelete clears the tombstone of deleted movie metadata, if its stored version is the expected
+// one or with AnyVersion, and returns the metadata as written.
+func (c *Controller) Undelete(ctx context.Context, id, editor string, expectedVersion int64) (*model.Metadata, error) {
+	return c.modify(ctx, id, editor, expectedVersion, func(m *model.Metadata) error {
+		if m.Tombstone == nil {
+			return ErrNotDeleted
+		}
+		m.Tombstone = nil
+		return nil
+	})
+}
+
+// modify writes the stored movie metadata as changed by set, retrying like Update.
+func (c *Controller) modify(ctx context.Context, id, editor string, expectedVersion int64, set func(*model.Metadata) error) (*model.Metadata, error) {
+	if expectedVersion < 0 && expectedVersion != AnyVersion {
+		return nil, fmt.Errorf("%w: expected version %d", ErrInvalidArgument, expectedVersion)
+	}
+	for attempt := 1; ; attempt++ {
+		current, err := c.getForUpdate(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if expectedVersion != AnyVersion && current.Version != expectedVersion {
+			return nil, ErrVersionMismatch
+		}
+		m := current.Clone()
+		if err := set(m); err != nil {
+			return nil, err
+		}
+		m.UpdatedBy = editor
+		res, err := c.repo.Put(ctx, id, m, current.Version)
+		if errors.Is(err, repository.ErrVersionMismatch) {
+			if expectedVersion == AnyVersion && attempt < maxUpdateAttempts {
+				continue
+			}
+			return nil, ErrVersionMismatch
+		}
+		return res, err
+	}
+}
+
+// Purge permanently removes movie metadata deleted before a time, with its revisions, and returns
+// the number of purged movies.
+func (c *Controller) Purge(ctx context.Context, before time.Time) (int, error) {
+	return c.repo.Purge(ctx, before)
+}
+
+// ResolveID returns the id of the movie with an external id in a namespace, such as an IMDb id.
+func (c *Controller) ResolveID(ctx context.Context, namespace, externalID string) (string, error) {
+	namespace, externalID = normalizeNamespace(namespace), strings.TrimSpace(externalID)
+	if namespace == "" || externalID == "" {
+		return "", fmt.Errorf("%w: empty namespace or external id", ErrInvalidArgument)
+	}
+	id, err := c.repo.ResolveExternalID(ctx, namespace, externalID)
+	if err != nil && errors.Is(err, repository.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	return id, err
+}
+
+// Put writes movie metadata if its stored version is the expected one, 0 if it must not exist yet,
+// or unconditionally with AnyVersion, and returns the metadata as written. Genres are trimmed and
+// deduplicated, ignoring case, external id namespaces are lowercased and localization locales are
+// canonicalized. Writing deleted metadata undeletes it.
+func (c *Controller) Put(ctx context.Context, m *model.Metadata, expectedVersion int64) (*model.Metadata, error) {
+	m, err := prepare(m, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkAssets(ctx, m.Assets); err != nil {
+		return nil, err
+	}
+	res, err := c.repo.Put(ctx, m.ID, m, expectedVersion)
+	if err != nil && errors.Is(err, repository.ErrVersionMismatch) {
+		return nil, ErrVersionMismatch
+	}
+	return res, err
+}
+
+// BatchPut defines movie metadata written by PutBatch, with its expected version as for Put.
+type BatchPut = repository.BatchPut
+
+// BatchFailure defines an item of a batch which was not written.
+type BatchFailure struct {
+	// Index is the index of the item in the batch.
+	Index int
+	Err   error
+}
+
+// PutBatch writes the movie metadata of a batch in a single transaction, each as written by Put,
+// and returns the metadata as written, nil for failed items, and the failures in item order.
+// Invalid items, including items referring to images which are not stored, and items the
+// repository fails to write, such as items whose stored version is not the expected one and items
+// assigning external ids of other movies, are left out of the transaction.
+func (c *Controller) PutBatch(ctx context.Context, items []BatchPut) ([]*model.Metadata, []BatchFailure, error) {
+	res := make([]*model.Metadata, len(items))
+	var failures []BatchFailure
+	var batch []BatchPut
+	// indexes are the indexes of the items of the batch.
+	var indexes []int
+	for i, item := range items {
+		m, err := prepare(item.Metadata, item.ExpectedVersion)
+		if err == nil {
+			err = c.checkAssets(ctx, m.Assets)
+		}
+		if err != nil && errors.Is(err, ErrInvalidArgument) {
+			failures = append(failures, BatchFailure{i, err})
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+		batch = append(batch, BatchPut{Metadata: m, ExpectedVersion: item.ExpectedVersion})
+		indexes = append(indexes, i)
+	}
+	for len(batch) > 0 {
+		stored, err := c.repo.PutBatch(ctx, batch)
+		var batchErr *repository.BatchError
+		if errors.As(err, &batchErr) && ctx.Err() == nil {
+			// Retry the batch without the failed item.
+			failureErr := batchErr.Err
+			if errors.Is(failureErr, repository.ErrVersionMismatch) {
+				failureErr = ErrVersionMismatch
+			}
+			failures = append(failures, BatchFailure{indexes[batchErr.Index], failureErr})
+			batch = slices.Delete(batch, batchErr.Index, batchErr.Index+1)
+			indexes = slices.Delete(indexes, batchErr.Index, batchErr.Index+1)
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+		for i, m := range stored {
+			res[indexes[i]] = m
+		}
+		break
+	}
+	slices.SortFunc(failures, func(a, b BatchFailure) int { return a.Index - b.Index })
+	return res, failures, nil
+}
+
+// prepare validates movie metadata written with an expected version and returns the metadata to
+// store, with normalized genres, external ids and localizations and without tombstone.
+func prepare(m *model.Metadata, expectedVersion int64) (*model.Metadata, error) {
+	if m == nil || m.ID == "" {
+		return nil, fmt.Errorf("%w: empty id", ErrInvalidArgument)
+	}
+	if err := validate(m); err != nil {
+		return nil, err
+	}
+	if expectedVersion < 0 && expectedVersion != AnyVersion {
+		return nil, fmt.Errorf("%w: expected version %d", ErrInvalidArgument, expectedVersion)
+	}
+	m = m.Clone()
+	m.Genres = normalizeGenres(m.Genres)
+	m.ExternalIDs = normalizeExternalIDs(m.ExternalIDs)
+	m.Localizations = normalizeLocalizations(m.Localizations)
+	m.Tombstone = nil
+	return m, nil
+}
+
+// Validate checks movie metadata as Put does, without writing it.
+func (c *Controller) Validate(m *model.Metadata) error {
+	return validate(m)
+}
+
+var roles = []model.Role{model.RoleActor, model.RoleDirector, model.RoleWriter, model.RoleProducer, model.RoleComposer}
+
+func validate(m *model.Metadata) error {
+	if m.ReleaseDate != "" {
+		if _, err := time.Parse(time.DateOnly, m.ReleaseDate); err != nil {
+			return fmt.Errorf("%w: release date %q is not formatted as YYYY-MM-DD", ErrInvalidArgument, m.ReleaseDate)
+		}
+	}
+	if m.RuntimeMinutes < 0 {
+		return fmt.Errorf("%w: negative runtime %d", ErrInvalidArgument, m.RuntimeMinutes)
+	}
+	for _, genre := range m.Genres {
+		if utf8.RuneCountInString(strings.TrimSpace(genre)) > maxGenreLength {
+			return fmt.Errorf("%w: genre %q longer than %d characters", ErrInvalidArgument, genre, maxGenreLength)
+		}
+	}
+	namespaces := map[string]bool{}
+	for namespace, externalID := range m.ExternalIDs {
+		n := normalizeNamespace(namespace)
+		if n == "" || strings.TrimSpace(externalID) == "" {
+			return fmt.Errorf("%w: empty namespace or external id", ErrInvalidArgument)
+		}
+		if namespaces[n] {
+			return fmt.Errorf("%w: several external ids in namespace %q", ErrInvalidArgument, n)
+		}
+		namespaces[n] = true
+	}
+	locales := map[string]bool{}
+	for l, localization := range m.Localizations {
+		canonical, err := locale.Canonical(l)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+		}
+		if locales[canonical] {
+			return fmt.Errorf("%w: several localizations in locale %q", ErrInvalidArgument, canonical)
+		}
+		locales[canonical] = true
+		if localization.Title == "" && localization.Description == "" {
+			return fmt.Errorf("%w: empty localization in locale %q", ErrInvalidArgument, canonical)
+		}
+	}
+	for _, a := range m.Assets {
+		if err := validateAsset(a); err != nil {
+			return err
+		}
+	}
+	for _, credit := range m.Credits {
+		if strings.TrimSpace(credit.Name) == "" {
+			return fmt.Errorf("%w: credit without a name", ErrInvalidArgument)
+		}
+		if !slices.Contains(roles, credit.Role) {
+			return fmt.Errorf("%w: unknown role %q of %s", ErrInvalidArgument, credit.Role, credit.Name)
+		}
+	}
+	return nil
+}
+
+// maxGenreLength is the maximum number of characters of genres.
+const maxGenreLength = 64
+
+// normalizeGenres trims genres, capitalizes their words, e.g. "Sci-Fi" for "sci-fi", and leaves out
+// empty and repeated genres.
+func normalizeGenres(genres []string) []string {
+	var res []string
+	seen := map[string]bool{}
+	for _, g := range genres {
+		g = capitalize(strings.TrimSpace(g))
+		if g == "" || seen[g] {
+			continue
+		}
+		seen[g] = true
+		res = append(res, g)
+	}
+	return res
+}
+
+// capitalize returns s in lower case with the first letter of every word in upper case.
+func capitalize(s string) string {
+	var b strings.Builder
+	inWord := false
+	for _, r := range s {
+		if inWord {
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(unicode.ToUpper(r))
+		}
+		// Apostrophes do not end words, as in "Children's".
+		inWord = unicode.IsLetter(r) || unicode.IsDigit(r) || (inWord && (r == '\'' || r == '’'))
+	}
+	return b.String()
+}
+
+// AttachAsset adds an uploaded image to movie metadata as its first asset of the kind, making an
+// uploaded poster the poster of the movie, and returns the metadata as written. Attaching an
+// attached image moves it first.
+func (c *Controller) AttachAsset(ctx context.Context, id string, ref model.AssetRef, editor string) (*model.Metadata, error) {
+	if err := validateAsset(ref); err != nil {
+		return nil, err
+	}
+	if err := c.checkAssets(ctx, []model.AssetRef{ref}); err != nil {
+		return nil, err
+	}
+	return c.modify(ctx, id, editor, AnyVersion, func(m *model.Metadata) error {
+		if m.Tombstone != nil {
+			return ErrNotFound
+		}
+		m.Assets = slices.DeleteFunc(m.Assets, func(a model.AssetRef) bool { return a == ref })
+		i := slices.IndexFunc(m.Assets, func(a model.AssetRef) bool { return a.Kind == ref.Kind })
+		if i < 0 {
+			i = len(m.Assets)
+		}
+		m.Assets = slices.Insert(m.Assets, i, ref)
+		return nil
+	})
+}
+
+// List returns a page of movie metadata and the token of the next page, empty on the last page.
+// orderBy is a sort field (id, title or director), optionally followed by " desc"; it defaults to id.
+func (c *Controller) List(ctx context.Context, orderBy string, pageSize int, pageToken string) ([]*model.Metadata, string, error) {
+	q := repository.ListQuery{SortBy: repository.SortByID, PageSize: normalizePageSize(pageSize), Cursor: pageToken}
+	if fields := strings.Fields(strings.ToLower(orderBy)); len(fields) > 0 {
+		q.SortBy = repository.SortField(fields[0])
+		if !q.SortBy.Valid() || len(fields) > 2 || (len(fields) == 2 && fields[1] != "asc" && fields[1] != "desc") {
+			return nil, "", fmt.Errorf("%w: order by %q", ErrInvalidArgument, orderBy)
+		}
+		q.Descending = len(fields) == 2 && fields[1] == "desc"
+	}
+	return invalidCursor(c.repo.List(ctx, q))
+}
+
+// Search returns a page of movie metadata whose title, director or description contain all words
+// of the query, ignoring case, and the token of the next page, empty on the last page.
+func (c *Controller) Search(ctx context.Context, query string, pageSize int, pageToken string) ([]*model.Metadata, string, error) {
+	if len(repository.Tokenize(query)) == 0 {
+		return nil, "", fmt.Errorf("%w: empty query", ErrInvalidArgument)
+	}
+	return invalidCursor(c.repo.Search(ctx, repository.SearchQuery{Query: query, PageSize: normalizePageSize(pageSize), Cursor: pageToken}))
+}
+
+func normalizePageSize(size int) int {
+	if size <= 0 {
+		return defaultPageSize
+	}
+	return min(size, maxPageSize)
+}
+
+// invalidCursor reports invalid page tokens as invalid arguments.
+func invalidCursor(res []*model.Metadata, next string, err error) ([]*model.Metadata, string, error) {
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		err = fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	return res, next, err
+}
+
+func normalizeNamespace(namespace string) string {
+	return strings.ToLower(strings.TrimSpace(namespace))
+}
+
+func normalizeExternalIDs(ids map[string]string) map[string]string {
+	if len(ids) == 0 {
+		return nil
+	}
+	res := make(map[string]string, len(ids))
+	for namespace, externalID := range ids {
+		res[normalizeNamespace(namespace)] = strings.TrimSpace(externalID)
+	}
+	return res
+}
+
+// normalizeLocalizations canonicalizes the locales of valid localizations.
+func normalizeLocalizations(localizations map[string]model.Localization) map[string]model.Localization {
+	if len(localizations) == 0 {
+		return nil
+	}
+	res := make(map[string]model.Localization, len(localizations))
+	for l, localization := range localizations {
+		canonical, _ := locale.Canonical(l)
+		res[canonical] = localization
+	}
+	return res
+}
+
+func validateAsset(a model.AssetRef) error {
+	if !a.Kind.Valid() {
+		return fmt.Errorf("%w: unknown asset kind %q", ErrInvalidArgument, a.Kind)
+	}
+	if !asset.ValidHash(a.Hash) {
+		return fmt.Errorf("%w: asset hash %q is not a hex-encoded SHA-256 hash", ErrInvalidArgument, a.Hash)
+	}
+	return nil
+}
+
+// checkAssets returns an ErrInvalidArgument error if an image is not stored in the asset store.
+func (c *Controller) checkAssets(ctx context.Context, refs []model.AssetRef) error {
+	if c.assets == nil {
+		return nil
+	}
+	for _, ref := range refs {
+		_, err := c.assets.Get(ctx, ref.Hash)
+		if err != nil && errors.Is(err, asset.ErrNotFound) {
+			return fmt.Errorf("%w: asset %s is not uploaded", ErrInvalidArgument, ref.Hash)
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
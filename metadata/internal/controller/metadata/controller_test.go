@@ -0,0 +1,468 @@
+package metadata
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meirongdev/movie-microservice/metadata/internal/blob/local"
+	"github.com/meirongdev/movie-microservice/metadata/internal/controller/asset"
+	"github.com/meirongdev/movie-microservice/metadata/internal/repository"
+	"github.com/meirongdev/movie-microservice/metadata/internal/repository/memory"
+	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+)
+
+// conflictingRepository is a memory repository where the next conflicts writes race with a
+// concurrent write of the director, which makes them fail with a version mismatch.
+type conflictingRepository struct {
+	*memory.Repository
+	conflicts int
+	puts      int
+}
+
+func (r *conflictingRepository) Put(ctx context.Context, id string, metadata *model.Metadata, expectedVersion int64) (*model.Metadata, error) {
+	r.puts++
+	if r.conflicts > 0 {
+		r.conflicts--
+		current, err := r.Repository.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		current.Director = "concurrent"
+		if _, err := r.Repository.Put(ctx, id, current, AnyVersion); err != nil {
+			return nil, err
+		}
+	}
+	return r.Repository.Put(ctx, id, metadata, expectedVersion)
+}
+
+// newTestController returns a controller over a conflicting repository storing a movie of id 1
+// at version 1.
+func newTestController(t *testing.T) (*Controller, *conflictingRepository) {
+	t.Helper()
+	repo := &conflictingRepository{Repository: memory.New()}
+	c := New(repo)
+	if _, err := c.Put(context.Background(), &model.Metadata{ID: "1", Title: "Alien", Director: "Ridley Scott", Genres: []string{"Horror"}}, 0); err != nil {
+		t.Fatal(err)
+	}
+	repo.puts = 0
+	return c, repo
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestController(t)
+	m, err := c.Update(ctx, &model.Metadata{ID: "1", Title: "Aliens", Genres: []string{" Sci-Fi ", "sci-fi"}, UpdatedBy: "editor"}, []string{"title", "genres"}, 1)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if m.Title != "Aliens" || m.Director != "Ridley Scott" || len(m.Genres) != 1 || m.Genres[0] != "Sci-Fi" || m.Version != 2 || m.UpdatedBy != "editor" {
+		t.Fatalf("Update: got %+v, want the title and normalized genres updated at version 2", m)
+	}
+	if _, err := c.Update(ctx, &model.Metadata{ID: "1", Title: "Alien 3"}, []string{"title"}, 1); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("Update with a stale version: got error %v, want %v", err, ErrVersionMismatch)
+	}
+	m, err = c.Update(ctx, &model.Metadata{ID: "1", Title: "Alien"}, []string{"*"}, AnyVersion)
+	if err != nil {
+		t.Fatalf("Update of all fields: %v", err)
+	}
+	if m.Title != "Alien" || m.Director != "" || m.Genres != nil {
+		t.Fatalf("Update of all fields: got %+v, want unset fields cleared", m)
+	}
+}
+
+func TestUpdateErrors(t *testing.T) {
+	ctx := context.Background()
+	c, repo := newTestController(t)
+	tests := []struct {
+		name  string
+		src   *model.Metadata
+		paths []string
+		want  error
+	}{
+		{"no paths", &model.Metadata{ID: "1"}, nil, ErrInvalidArgument},
+		{"unknown path", &model.Metadata{ID: "1"}, []string{"title", "version"}, ErrInvalidArgument},
+		{"JSON name", &model.Metadata{ID: "1"}, []string{"releaseDate"}, ErrInvalidArgument},
+		{"invalid field", &model.Metadata{ID: "1", ReleaseDate: "May 1979"}, []string{"release_date"}, ErrInvalidArgument},
+		{"long genre", &model.Metadata{ID: "1", Genres: []string{strings.Repeat("a", maxGenreLength+1)}}, []string{"genres"}, ErrInvalidArgument},
+		{"not found", &model.Metadata{ID: "2"}, []string{"title"}, ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.Update(ctx, tt.src, tt.paths, AnyVersion); !errors.Is(err, tt.want) {
+				t.Fatalf("Update: got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if repo.puts != 0 {
+		t.Fatalf("got %d writes of failed updates, want none", repo.puts)
+	}
+}
+
+func TestNormalizeGenres(t *testing.T) {
+	tests := []struct {
+		genres []string
+		want   []string
+	}{
+		{[]string{" sci-fi", "SCI-FI", "Sci-Fi "}, []string{"Sci-Fi"}},
+		{[]string{"film noir", "Film-Noir", ""}, []string{"Film Noir", "Film-Noir"}},
+		{[]string{"children's", "ÉPOPÉE"}, []string{"Children's", "Épopée"}},
+	}
+	for _, tt := range tests {
+		if got := normalizeGenres(tt.genres); !slices.Equal(got, tt.want) {
+			t.Errorf("normalizeGenres(%q): got %q, want %q", tt.genres, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateRetriesConflicts(t *testing.T) {
+	ctx := context.Background()
+	c, repo := newTestController(t)
+	repo.conflicts = 2
+	m, err := c.Update(ctx, &model.Metadata{ID: "1", Title: "Aliens"}, []string{"title"}, AnyVersion)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	// The update is applied to the metadata as written concurrently.
+	if m.Title != "Aliens" || m.Director != "concurrent" || m.Version != 4 || repo.puts != 3 {
+		t.Fatalf("Update: got %+v after %d writes, want the title and the concurrent director at version 4 after 3 writes", m, repo.puts)
+	}
+
+	repo.conflicts, repo.puts = 1, 0
+	if _, err := c.Update(ctx, &model.Metadata{ID: "1", Title: "Alien 3"}, []string{"title"}, m.Version); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("Update with an expected version: got error %v, want %v", err, ErrVersionMismatch)
+	}
+	if repo.puts != 1 {
+		t.Fatalf("Update with an expected version: got %d writes, want 1", repo.puts)
+	}
+
+	repo.conflicts, repo.puts = maxUpdateAttempts, 0
+	if _, err := c.Update(ctx, &model.Metadata{ID: "1", Title: "Alien 3"}, []string{"title"}, AnyVersion); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("Update conflicting every time: got error %v, want %v", err, ErrVersionMismatch)
+	}
+	if repo.puts != maxUpdateAttempts {
+		t.Fatalf("Update conflicting every time: got %d writes, want %d", repo.puts, maxUpdateAttempts)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	c, repo := newTestController(t)
+	if _, err := c.Delete(ctx, "2", "duplicate", "editor", AnyVersion); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Delete of a missing movie: got error %v, want %v", err, ErrNotFound)
+	}
+	if _, err := c.Delete(ctx, "1", "duplicate", "editor", 2); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("Delete with a stale version: got error %v, want %v", err, ErrVersionMismatch)
+	}
+	if _, err := c.Delete(ctx, "1", "duplicate", "editor", -2); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Delete with a negative version: got error %v, want %v", err, ErrInvalidArgument)
+	}
+	if repo.puts != 0 {
+		t.Fatalf("got %d writes of failed deletes, want none", repo.puts)
+	}
+	m, err := c.Delete(ctx, "1", "duplicate", "editor", 1)
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if m.Tombstone == nil || m.Tombstone.Reason != "duplicate" || m.Tombstone.DeletedAt.IsZero() || m.UpdatedBy != "editor" || m.Version != 2 {
+		t.Fatalf("Delete: got %+v, want a tombstone at version 2", m)
+	}
+	if _, err := c.Get(ctx, "1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get of a deleted movie: got error %v, want %v", err, ErrNotFound)
+	}
+	if m, err := c.GetIncludingDeleted(ctx, "1"); err != nil || m.Tombstone == nil {
+		t.Fatalf("GetIncludingDeleted: got %+v, error %v, want a tombstone", m, err)
+	}
+	if _, err := c.Delete(ctx, "1", "again", "editor", AnyVersion); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Delete of a deleted movie: got error %v, want %v", err, ErrNotFound)
+	}
+	if _, err := c.GetVersion(ctx, "1", 1, false); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetVersion of a deleted movie: got error %v, want %v", err, ErrNotFound)
+	}
+	if _, err := c.GetAsOf(ctx, "1", time.Now(), false); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetAsOf of a deleted movie: got error %v, want %v", err, ErrNotFound)
+	}
+	if m, err := c.GetVersion(ctx, "1", 1, true); err != nil || m.Title != "Alien" || m.Tombstone != nil {
+		t.Fatalf("GetVersion of a deleted movie including deleted: got %+v, error %v, want version 1", m, err)
+	}
+	if m, err := c.GetAsOf(ctx, "1", time.Now(), true); err != nil || m.Tombstone == nil {
+		t.Fatalf("GetAsOf of a deleted movie including deleted: got %+v, error %v, want a tombstone", m, err)
+	}
+}
+
+func TestUndelete(t *testing.T) {
+	ctx := context.Background()
+	c, repo := newTestController(t)
+	if _, err := c.Undelete(ctx, "2", "editor", AnyVersion); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Undelete of a missing movie: got error %v, want %v", err, ErrNotFound)
+	}
+	if _, err := c.Undelete(ctx, "1", "editor", AnyVersion); !errors.Is(err, ErrNotDeleted) {
+		t.Fatalf("Undelete of a movie not deleted: got error %v, want %v", err, ErrNotDeleted)
+	}
+	if _, err := c.Delete(ctx, "1", "duplicate", "editor", AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Undelete(ctx, "1", "editor", 1); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("Undelete with a stale version: got error %v, want %v", err, ErrVersionMismatch)
+	}
+	repo.puts = 0
+	m, err := c.Undelete(ctx, "1", "restorer", 2)
+	if err != nil {
+		t.Fatalf("Undelete: %v", err)
+	}
+	if m.Tombstone != nil || m.UpdatedBy != "restorer" || m.Version != 3 || m.Title != "Alien" || repo.puts != 1 {
+		t.Fatalf("Undelete: got %+v after %d writes, want the movie without tombstone at version 3", m, repo.puts)
+	}
+	if _, err := c.Get(ctx, "1"); err != nil {
+		t.Fatalf("Get of an undeleted movie: %v", err)
+	}
+}
+
+func TestDeleteRetriesConflicts(t *testing.T) {
+	ctx := context.Background()
+	c, repo := newTestController(t)
+	repo.conflicts = 1
+	m, err := c.Delete(ctx, "1", "duplicate", "editor", AnyVersion)
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if m.Tombstone == nil || m.Director != "concurrent" || m.Version != 3 || repo.puts != 2 {
+		t.Fatalf("Delete: got %+v after %d writes, want the concurrent write deleted at version 3 after 2 writes", m, repo.puts)
+	}
+
+	repo.conflicts, repo.puts = 1, 0
+	if _, err := c.Undelete(ctx, "1", "editor", m.Version); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("Undelete with an expected version: got error %v, want %v", err, ErrVersionMismatch)
+	}
+	if repo.puts != 1 {
+		t.Fatalf("Undelete with an expected version: got %d writes, want 1", repo.puts)
+	}
+
+	repo.conflicts, repo.puts = maxUpdateAttempts, 0
+	if _, err := c.Undelete(ctx, "1", "editor", AnyVersion); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("Undelete conflicting every time: got error %v, want %v", err, ErrVersionMismatch)
+	}
+	if repo.puts != maxUpdateAttempts {
+		t.Fatalf("Undelete conflicting every time: got %d writes, want %d", repo.puts, maxUpdateAttempts)
+	}
+}
+
+// laggingRepository is a memory repository whose Get returns the metadata as first stored, like a
+// read replica lagging behind the writes, while GetForUpdate returns it as last written.
+type laggingRepository struct {
+	*conflictingRepository
+	stale map[string]*model.Metadata
+}
+
+func (r *laggingRepository) Get(ctx context.Context, id string) (*model.Metadata, error) {
+	if m, ok := r.stale[id]; ok {
+		return m.Clone(), nil
+	}
+	return r.conflictingRepository.Get(ctx, id)
+}
+
+func TestModifyReadsLatestWrite(t *testing.T) {
+	ctx := context.Background()
+	_, conflicting := newTestController(t)
+	stale, err := conflicting.Get(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &laggingRepository{conflicting, map[string]*model.Metadata{"1": stale}}
+	c := New(repo)
+	if _, err := c.Update(ctx, &model.Metadata{ID: "1", Title: "Aliens"}, []string{"title"}, AnyVersion); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	m, err := c.Update(ctx, &model.Metadata{ID: "1", Director: "James Cameron"}, []string{"director"}, 2)
+	if err != nil || m.Title != "Aliens" || m.Version != 3 {
+		t.Fatalf("Update of the written version: got %+v, error %v, want the written title at version 3", m, err)
+	}
+	if m, err = c.Delete(ctx, "1", "duplicate", "editor", 3); err != nil || m.Tombstone == nil || m.Version != 4 {
+		t.Fatalf("Delete of the written version: got %+v, error %v, want a tombstone at version 4", m, err)
+	}
+	if m, err = c.Undelete(ctx, "1", "editor", AnyVersion); err != nil || m.Tombstone != nil || m.Director != "James Cameron" {
+		t.Fatalf("Undelete: got %+v, error %v, want the written metadata", m, err)
+	}
+	if repo.puts != 4 {
+		t.Fatalf("got %d writes, want 4 without retries", repo.puts)
+	}
+}
+
+// uploadImages uploads n distinct images to an asset controller over a temporary directory and
+// returns the controller and the hashes of the images.
+func uploadImages(t *testing.T, n int) (*asset.Controller, []string) {
+	t.Helper()
+	store, err := local.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assets := asset.New(store)
+	var hashes []string
+	for i := range n {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 10+i, 10))); err != nil {
+			t.Fatal(err)
+		}
+		a, _, err := assets.Upload(context.Background(), &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, a.Hash)
+	}
+	return assets, hashes
+}
+
+func TestAttachAsset(t *testing.T) {
+	ctx := context.Background()
+	assets, hashes := uploadImages(t, 3)
+	c := New(memory.New(), WithAssets(assets))
+	if _, err := c.Put(ctx, &model.Metadata{ID: "1", Title: "Alien"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	poster := func(hash string) model.AssetRef { return model.AssetRef{Kind: model.AssetPoster, Hash: hash} }
+	still := model.AssetRef{Kind: model.AssetStill, Hash: hashes[2]}
+	var m *model.Metadata
+	var err error
+	for _, ref := range []model.AssetRef{poster(hashes[0]), still, poster(hashes[1]), poster(hashes[0])} {
+		if m, err = c.AttachAsset(ctx, "1", ref, "editor"); err != nil {
+			t.Fatalf("AttachAsset(%+v): %v", ref, err)
+		}
+	}
+	// Attaching an attached image moves it first among the images of its kind.
+	want := []model.AssetRef{poster(hashes[0]), poster(hashes[1]), still}
+	if !slices.Equal(m.Assets, want) || m.UpdatedBy != "editor" || m.Version != 5 {
+		t.Fatalf("AttachAsset: got %+v at version %d, want %+v at version 5", m.Assets, m.Version, want)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		ref  model.AssetRef
+		want error
+	}{
+		{"not uploaded", "1", poster(strings.Repeat("0", 64)), ErrInvalidArgument},
+		{"malformed hash", "1", poster("poster.jpg"), ErrInvalidArgument},
+		{"unknown kind", "1", model.AssetRef{Kind: "banner", Hash: hashes[0]}, ErrInvalidArgument},
+		{"not found", "2", poster(hashes[0]), ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.AttachAsset(ctx, tt.id, tt.ref, "editor"); !errors.Is(err, tt.want) {
+				t.Fatalf("AttachAsset: got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPutChecksAssets(t *testing.T) {
+	ctx := context.Background()
+	assets, hashes := uploadImages(t, 1)
+	c := New(memory.New(), WithAssets(assets))
+	uploaded := []model.AssetRef{{Kind: model.AssetPoster, Hash: hashes[0]}}
+	missing := []model.AssetRef{{Kind: model.AssetPoster, Hash: strings.Repeat("0", 64)}}
+	if _, err := c.Put(ctx, &model.Metadata{ID: "1", Title: "Alien", Assets: uploaded}, 0); err != nil {
+		t.Fatalf("Put with an uploaded image: %v", err)
+	}
+	if _, err := c.Put(ctx, &model.Metadata{ID: "2", Title: "Aliens", Assets: missing}, 0); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Put with an image not uploaded: got error %v, want %v", err, ErrInvalidArgument)
+	}
+	if _, err := c.Update(ctx, &model.Metadata{ID: "1", Assets: missing}, []string{"assets"}, AnyVersion); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("Update with an image not uploaded: got error %v, want %v", err, ErrInvalidArgument)
+	}
+	res, failures, err := c.PutBatch(ctx, []BatchPut{
+		{Metadata: &model.Metadata{ID: "2", Title: "Aliens", Assets: missing}, ExpectedVersion: 0},
+		{Metadata: &model.Metadata{ID: "3", Title: "Alien 3", Assets: uploaded}, ExpectedVersion: 0},
+	})
+	if err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+	if len(failures) != 1 || failures[0].Index != 0 || !errors.Is(failures[0].Err, ErrInvalidArgument) || res[0] != nil || res[1] == nil {
+		t.Fatalf("PutBatch: got %+v, failures %+v, want item 0 failed with %v", res, failures, ErrInvalidArgument)
+	}
+}
+
+// errDataTooLong is the error of writes of titles too long for failingRepository.
+var errDataTooLong = errors.New("data too long")
+
+// failingRepository is a memory repository failing batches with an item whose title is longer
+// than maxTitle, like a database rejecting a value too long for its column.
+type failingRepository struct {
+	*memory.Repository
+	maxTitle int
+	batches  int
+}
+
+func (r *failingRepository) PutBatch(ctx context.Context, items []repository.BatchPut) ([]*model.Metadata, error) {
+	r.batches++
+	for i, item := range items {
+		if len(item.Metadata.Title) > r.maxTitle {
+			return nil, &repository.BatchError{Index: i, Err: errDataTooLong}
+		}
+	}
+	return r.Repository.PutBatch(ctx, items)
+}
+
+func TestPutBatch(t *testing.T) {
+	ctx := context.Background()
+	repo := &failingRepository{Repository: memory.New(), maxTitle: 20}
+	c := New(repo)
+	if _, err := c.Put(ctx, &model.Metadata{ID: "1", Title: "Alien", ExternalIDs: map[string]string{model.NamespaceIMDb: "tt0078748"}}, 0); err != nil {
+		t.Fatal(err)
+	}
+	res, failures, err := c.PutBatch(ctx, []BatchPut{
+		{Metadata: &model.Metadata{ID: "2", Title: "Aliens"}, ExpectedVersion: 0},
+		{Metadata: &model.Metadata{ID: "1", Title: "Alien (1979)"}, ExpectedVersion: 2},
+		{Metadata: &model.Metadata{ID: "3", Title: "Alien 3", RuntimeMinutes: -1}, ExpectedVersion: 0},
+		{Metadata: &model.Metadata{ID: "4", Title: "Alien Resurrection, Special Edition"}, ExpectedVersion: 0},
+		{Metadata: &model.Metadata{ID: "5", Title: "Alien³", ExternalIDs: map[string]string{model.NamespaceIMDb: "tt0078748"}}, ExpectedVersion: 0},
+		{Metadata: &model.Metadata{ID: "1", Title: "Alien"}, ExpectedVersion: 1},
+		{Metadata: &model.Metadata{ID: "2", Title: "Aliens (1986)"}, ExpectedVersion: 0},
+		{Metadata: &model.Metadata{ID: "6", Title: "Prometheus"}, ExpectedVersion: AnyVersion},
+	})
+	if err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+	// Items are retried without the failed items, whose failures are reported in item order.
+	want := []struct {
+		index int
+		err   error
+	}{
+		{1, ErrVersionMismatch},
+		{2, ErrInvalidArgument},
+		{3, errDataTooLong},
+		{4, ErrDuplicateExternalID},
+		{6, ErrVersionMismatch},
+	}
+	if len(failures) != len(want) {
+		t.Fatalf("PutBatch: got failures %+v, want %d", failures, len(want))
+	}
+	for i, f := range failures {
+		if f.Index != want[i].index || !errors.Is(f.Err, want[i].err) {
+			t.Fatalf("PutBatch: got failure %d of item %d with %v, want item %d with %v", i, f.Index, f.Err, want[i].index, want[i].err)
+		}
+	}
+	for i, m := range res {
+		written := i == 0 || i == 5 || i == 7
+		if written != (m != nil) {
+			t.Fatalf("PutBatch: got %+v for item %d, want written %v", m, i, written)
+		}
+	}
+	if res[5].Version != 2 || res[0].Version != 1 {
+		t.Fatalf("PutBatch: got versions %d and %d, want 2 and 1", res[5].Version, res[0].Version)
+	}
+	if repo.batches != 5 {
+		t.Fatalf("PutBatch: got %d transactions, want 5", repo.batches)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, _, err := c.PutBatch(cancelled, []BatchPut{{Metadata: &model.Metadata{ID: "7", Title: "Alien: Covenant"}}}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("PutBatch with a cancelled context: got error %v, want %v", err, context.Canceled)
+	}
+}
@@ -3,8 +3,12 @@ package grpc
 import (
 	"context"
 	"errors"
+	"io"
+	"slices"
 
 	"github.com/meirongdev/movie-microservice/gen"
+	"github.com/meirongdev/movie-microservice/internal/grpcutil"
+	"github.com/meirongdev/movie-microservice/metadata/internal/controller/asset"
 	"github.com/meirongdev/movie-microservice/metadata/internal/controller/metadata"
 	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
 	"google.golang.org/grpc/codes"
@@ -14,24 +18,333 @@ import (
 // Handler defines a movie metadata gRPC handler.
 type Handler struct {
 	gen.UnimplementedMetadataServiceServer
-	ctrl *metadata.Controller
+	ctrl   *metadata.Controller
+	assets *asset.Controller
 }
 
 // New creates a new movie metadata gRPC handler.
-func New(ctrl *metadata.Controller) *Handler {
-	return &Handler{ctrl: ctrl}
+func New(ctrl *metadata.Controller, assets *asset.Controller) *Handler {
+	return &Handler{ctrl: ctrl, assets: assets}
 }
 
-// GetMetadata returns movie metadata.
+// GetMetadata returns movie metadata, current or as of a version or time. Metadata of deleted
+// movies, current or not, is only returned with show_deleted. The title and description are served in the requested locale, if
+// translated.
 func (h *Handler) GetMetadata(ctx context.Context, req *gen.GetMetadataRequest) (*gen.GetMetadataResponse, error) {
 	if req == nil || req.MovieId == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
 	}
-	m, err := h.ctrl.Get(ctx, req.MovieId)
+	locales, err := grpcutil.Locales(ctx, req.Locale)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+	var m *model.Metadata
+	switch {
+	case req.Version != 0 && req.AsOf != nil:
+		return nil, status.Errorf(codes.InvalidArgument, "both version and as_of set")
+	case req.Version != 0:
+		m, err = h.ctrl.GetVersion(ctx, req.MovieId, req.Version, req.ShowDeleted)
+	case req.AsOf != nil:
+		m, err = h.ctrl.GetAsOf(ctx, req.MovieId, req.AsOf.AsTime(), req.ShowDeleted)
+	case req.ShowDeleted:
+		m, err = h.ctrl.GetIncludingDeleted(ctx, req.MovieId)
+	default:
+		m, err = h.ctrl.Get(ctx, req.MovieId)
+	}
 	if err != nil && errors.Is(err, metadata.ErrNotFound) {
 		return nil, status.Errorf(codes.NotFound, err.Error())
 	} else if err != nil {
 		return nil, status.Errorf(codes.Internal, err.Error())
 	}
-	return &gen.GetMetadataResponse{Metadata: model.MetadataToProto(m)}, nil
+	m, served := m.Localize(locales)
+	return &gen.GetMetadataResponse{Metadata: model.MetadataToProto(m), ServedLocale: served}, nil
+}
+
+// PutMetadata writes movie metadata, if the stored version is the expected one.
+func (h *Handler) PutMetadata(ctx context.Context, req *gen.PutMetadataRequest) (*gen.PutMetadataResponse, error) {
+	if req == nil || req.Metadata == nil || req.Metadata.Id == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or metadata or empty id")
+	}
+	expectedVersion := metadata.AnyVersion
+	if req.ExpectedVersion != nil {
+		expectedVersion = *req.ExpectedVersion
+	}
+	m, err := h.ctrl.Put(ctx, model.MetadataFromProto(req.Metadata), expectedVersion)
+	if err != nil {
+		return nil, writeError(err)
+	}
+	return &gen.PutMetadataResponse{Metadata: model.MetadataToProto(m)}, nil
+}
+
+// UpdateMetadata writes the fields of movie metadata named by the update mask.
+func (h *Handler) UpdateMetadata(ctx context.Context, req *gen.UpdateMetadataRequest) (*gen.UpdateMetadataResponse, error) {
+	if req == nil || req.Metadata == nil || req.Metadata.Id == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or metadata or empty id")
+	}
+	if !req.UpdateMask.IsValid(req.Metadata) && !slices.Equal(req.UpdateMask.GetPaths(), []string{"*"}) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid update mask %v", req.UpdateMask.GetPaths())
+	}
+	// The id selects the metadata and updated_by is the editor, so they are not updated as fields.
+	var paths []string
+	for _, p := range req.UpdateMask.GetPaths() {
+		if p != "id" && p != "updated_by" {
+			paths = append(paths, p)
+		}
+	}
+	expectedVersion := metadata.AnyVersion
+	if req.ExpectedVersion != nil {
+		expectedVersion = *req.ExpectedVersion
+	}
+	m, err := h.ctrl.Update(ctx, model.MetadataFromProto(req.Metadata), paths, expectedVersion)
+	if err != nil {
+		return nil, writeError(err)
+	}
+	return &gen.UpdateMetadataResponse{Metadata: model.MetadataToProto(m)}, nil
+}
+
+// DeleteMetadata marks movie metadata as deleted.
+func (h *Handler) DeleteMetadata(ctx context.Context, req *gen.DeleteMetadataRequest) (*gen.DeleteMetadataResponse, error) {
+	if req == nil || req.MovieId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
+	}
+	expectedVersion := metadata.AnyVersion
+	if req.ExpectedVersion != nil {
+		expectedVersion = *req.ExpectedVersion
+	}
+	m, err := h.ctrl.Delete(ctx, req.MovieId, req.Reason, req.Editor, expectedVersion)
+	if err != nil {
+		return nil, writeError(err)
+	}
+	return &gen.DeleteMetadataResponse{Metadata: model.MetadataToProto(m)}, nil
+}
+
+// UndeleteMetadata clears the tombstone of deleted movie metadata.
+func (h *Handler) UndeleteMetadata(ctx context.Context, req *gen.UndeleteMetadataRequest) (*gen.UndeleteMetadataResponse, error) {
+	if req == nil || req.MovieId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
+	}
+	expectedVersion := metadata.AnyVersion
+	if req.ExpectedVersion != nil {
+		expectedVersion = *req.ExpectedVersion
+	}
+	m, err := h.ctrl.Undelete(ctx, req.MovieId, req.Editor, expectedVersion)
+	if err != nil {
+		return nil, writeError(err)
+	}
+	return &gen.UndeleteMetadataResponse{Metadata: model.MetadataToProto(m)}, nil
+}
+
+// ListMetadataRevisions returns a page of the change history of movie metadata.
+func (h *Handler) ListMetadataRevisions(ctx context.Context, req *gen.ListMetadataRevisionsRequest) (*gen.ListMetadataRevisionsResponse, error) {
+	if req == nil || req.MovieId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
+	}
+	revisions, next, err := h.ctrl.ListRevisions(ctx, req.MovieId, int(req.PageSize), req.PageToken)
+	if err != nil {
+		return nil, listError(err)
+	}
+	res := &gen.ListMetadataRevisionsResponse{NextPageToken: next}
+	for _, r := range revisions {
+		res.Revisions = append(res.Revisions, model.RevisionToProto(r))
+	}
+	return res, nil
+}
+
+// RestoreMetadataRevision writes movie metadata as written by a version.
+func (h *Handler) RestoreMetadataRevision(ctx context.Context, req *gen.RestoreMetadataRevisionRequest) (*gen.RestoreMetadataRevisionResponse, error) {
+	if req == nil || req.MovieId == "" || req.Version <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req, empty id or invalid version")
+	}
+	expectedVersion := metadata.AnyVersion
+	if req.ExpectedVersion != nil {
+		expectedVersion = *req.ExpectedVersion
+	}
+	m, err := h.ctrl.Restore(ctx, req.MovieId, req.Version, req.Editor, expectedVersion)
+	if err != nil {
+		return nil, writeError(err)
+	}
+	return &gen.RestoreMetadataRevisionResponse{Metadata: model.MetadataToProto(m)}, nil
+}
+
+// ListMetadata returns a page of movie metadata.
+func (h *Handler) ListMetadata(ctx context.Context, req *gen.ListMetadataRequest) (*gen.ListMetadataResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	ms, next, err := h.ctrl.List(ctx, req.OrderBy, int(req.PageSize), req.PageToken)
+	if err != nil {
+		return nil, listError(err)
+	}
+	return &gen.ListMetadataResponse{Metadata: metadataToProto(ms), NextPageToken: next}, nil
+}
+
+// SearchMetadata returns a page of movie metadata matching a query.
+func (h *Handler) SearchMetadata(ctx context.Context, req *gen.SearchMetadataRequest) (*gen.SearchMetadataResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	ms, next, err := h.ctrl.Search(ctx, req.Query, int(req.PageSize), req.PageToken)
+	if err != nil {
+		return nil, listError(err)
+	}
+	return &gen.SearchMetadataResponse{Metadata: metadataToProto(ms), NextPageToken: next}, nil
+}
+
+// ResolveMovieID returns the id of the movie with an external id in a namespace.
+func (h *Handler) ResolveMovieID(ctx context.Context, req *gen.ResolveMovieIDRequest) (*gen.ResolveMovieIDResponse, error) {
+	if req == nil || req.Namespace == "" || req.ExternalId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty namespace or external id")
+	}
+	id, err := h.ctrl.ResolveID(ctx, req.Namespace, req.ExternalId)
+	if err != nil {
+		return nil, writeError(err)
+	}
+	return &gen.ResolveMovieIDResponse{MovieId: id}, nil
+}
+
+// bulkBatchSize is the number of items of bulk writes written per transaction.
+const bulkBatchSize = 100
+
+// BulkPutMetadata writes a stream of movie metadata in transactional batches. Items that fail are
+// reported in the response; other errors abort the stream, keeping the batches written before.
+func (h *Handler) BulkPutMetadata(stream gen.MetadataService_BulkPutMetadataServer) error {
+	ctx := stream.Context()
+	res := &gen.BulkPutMetadataResponse{}
+	var batch []metadata.BatchPut
+	// first is the position of the first item of the batch in the stream.
+	var first int64
+	flush := func() error {
+		_, failures, err := h.ctrl.PutBatch(ctx, batch)
+		if err != nil {
+			return status.Errorf(codes.Internal, err.Error())
+		}
+		for _, f := range failures {
+			res.Failures = append(res.Failures, grpcutil.BulkPutFailure(first+int64(f.Index), batch[f.Index].Metadata.ID, writeError(f.Err)))
+		}
+		res.Written += int64(len(batch) - len(failures))
+		first += int64(len(batch))
+		batch = batch[:0]
+		return nil
+	}
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		m := &model.Metadata{}
+		if req.Metadata != nil {
+			m = model.MetadataFromProto(req.Metadata)
+		}
+		expectedVersion := metadata.AnyVersion
+		if req.ExpectedVersion != nil {
+			expectedVersion = *req.ExpectedVersion
+		}
+		batch = append(batch, metadata.BatchPut{Metadata: m, ExpectedVersion: expectedVersion})
+		if len(batch) < bulkBatchSize {
+			continue
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	return stream.SendAndClose(res)
+}
+
+// UploadAsset stores an image streamed in chunks after the upload info, and attaches it to a
+// movie if the info names one.
+func (h *Handler) UploadAsset(stream gen.MetadataService_UploadAssetServer) error {
+	ctx := stream.Context()
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	upload := req.GetUpload()
+	if upload == nil {
+		return status.Errorf(codes.InvalidArgument, "first message without upload info")
+	}
+	kind := model.AssetPoster
+	if upload.Kind != "" {
+		kind = model.AssetKind(upload.Kind)
+	}
+	if !kind.Valid() {
+		return status.Errorf(codes.InvalidArgument, "unknown asset kind %q", upload.Kind)
+	}
+	a, deduplicated, err := h.assets.Upload(ctx, &chunkReader{stream: stream})
+	if err != nil && errors.Is(err, asset.ErrInvalidArgument) {
+		return status.Errorf(codes.InvalidArgument, err.Error())
+	} else if _, ok := status.FromError(err); err != nil && ok {
+		// Errors of the stream are returned as they are.
+		return err
+	} else if err != nil {
+		return status.Errorf(codes.Internal, err.Error())
+	}
+	res := &gen.UploadAssetResponse{Asset: model.AssetToProto(a), Deduplicated: deduplicated}
+	if upload.MovieId != "" {
+		m, err := h.ctrl.AttachAsset(ctx, upload.MovieId, model.AssetRef{Kind: kind, Hash: a.Hash}, upload.Editor)
+		if err != nil {
+			return writeError(err)
+		}
+		res.Metadata = model.MetadataToProto(m)
+	}
+	return stream.SendAndClose(res)
+}
+
+// chunkReader reads the chunks of an asset upload stream.
+type chunkReader struct {
+	stream gen.MetadataService_UploadAssetServer
+	chunk  []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		if req.GetUpload() != nil {
+			return 0, status.Errorf(codes.InvalidArgument, "upload info after the first message")
+		}
+		r.chunk = req.GetChunk()
+	}
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return n, nil
+}
+
+func writeError(err error) error {
+	switch {
+	case errors.Is(err, metadata.ErrNotFound):
+		return status.Errorf(codes.NotFound, err.Error())
+	case errors.Is(err, metadata.ErrInvalidArgument):
+		return status.Errorf(codes.InvalidArgument, err.Error())
+	case errors.Is(err, metadata.ErrVersionMismatch):
+		return status.Errorf(codes.Aborted, err.Error())
+	case errors.Is(err, metadata.ErrNotDeleted):
+		return status.Errorf(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, metadata.ErrDuplicateExternalID):
+		return status.Errorf(codes.AlreadyExists, err.Error())
+	}
+	return status.Errorf(codes.Internal, err.Error())
+}
+
+func listError(err error) error {
+	if errors.Is(err, metadata.ErrInvalidArgument) {
+		return status.Errorf(codes.InvalidArgument, err.Error())
+	}
+	return status.Errorf(codes.Internal, err.Error())
+}
+
+func metadataToProto(ms []*model.Metadata) []*gen.Metadata {
+	res := make([]*gen.Metadata, 0, len(ms))
+	for _, m := range ms {
+		res = append(res, model.MetadataToProto(m))
+	}
+	return res
 }
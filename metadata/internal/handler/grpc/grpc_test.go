@@ -0,0 +1,69 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/meirongdev/movie-microservice/gen"
+	"github.com/meirongdev/movie-microservice/metadata/internal/controller/metadata"
+	"github.com/meirongdev/movie-microservice/metadata/internal/repository/memory"
+	"google.golang.org/grpc"
+)
+
+// bulkPutStream defines a stream of bulk writes receiving a list of requests.
+type bulkPutStream struct {
+	grpc.ServerStream
+	reqs []*gen.PutMetadataRequest
+	res  *gen.BulkPutMetadataResponse
+}
+
+func (s *bulkPutStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *bulkPutStream) Recv() (*gen.PutMetadataRequest, error) {
+	if len(s.reqs) == 0 {
+		return nil, io.EOF
+	}
+	req := s.reqs[0]
+	s.reqs = s.reqs[1:]
+	return req, nil
+}
+
+func (s *bulkPutStream) SendAndClose(res *gen.BulkPutMetadataResponse) error {
+	s.res = res
+	return nil
+}
+
+func TestBulkPutMetadata(t *testing.T) {
+	ctrl := metadata.New(memory.New())
+	stream := &bulkPutStream{}
+	// Items create movies with their index as id, except that item 3 creates movie 1 again in the
+	// first batch, and items 101 and 105 create movies 2 and 1 again in the second batch.
+	zero := int64(0)
+	for i := range bulkBatchSize + 10 {
+		id := fmt.Sprint(i)
+		switch i {
+		case 3, 105:
+			id = "1"
+		case 101:
+			id = "2"
+		}
+		stream.reqs = append(stream.reqs, &gen.PutMetadataRequest{Metadata: &gen.Metadata{Id: id, Title: "Movie " + id}, ExpectedVersion: &zero})
+	}
+	if err := New(ctrl, nil).BulkPutMetadata(stream); err != nil {
+		t.Fatalf("BulkPutMetadata: %v", err)
+	}
+	var got []int64
+	for _, f := range stream.res.Failures {
+		if f.Code != "ABORTED" {
+			t.Errorf("BulkPutMetadata: got failure %+v, want ABORTED", f)
+		}
+		got = append(got, f.Index)
+	}
+	if fmt.Sprint(got) != "[3 101 105]" || stream.res.Written != bulkBatchSize+7 {
+		t.Fatalf("BulkPutMetadata: got failures of items %v and %d written, want items [3 101 105] and %d written", got, stream.res.Written, bulkBatchSize+7)
+	}
+}
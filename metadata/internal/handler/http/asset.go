@@ -0,0 +1,148 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/meirongdev/movie-microservice/metadata/internal/controller/asset"
+	"github.com/meirongdev/movie-microservice/metadata/internal/controller/metadata"
+	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+)
+
+// AssetHandler defines an HTTP handler of images of movies.
+type AssetHandler struct {
+	assets *asset.Controller
+	ctrl   *metadata.Controller
+}
+
+// NewAssetHandler creates a new HTTP handler of images of movies.
+func NewAssetHandler(assets *asset.Controller, ctrl *metadata.Controller) *AssetHandler {
+	return &AssetHandler{assets, ctrl}
+}
+
+// uploadResponse defines the response to an upload.
+type uploadResponse struct {
+	Asset        *model.Asset `json:"asset"`
+	Deduplicated bool         `json:"deduplicated"`
+	// Metadata is the metadata of the movie the image is attached to, as written.
+	Metadata *model.Metadata `json:"metadata,omitempty"`
+}
+
+// UploadAsset handles POST /assets requests with a multipart/form-data body whose file part is an
+// image. The image is attached to the movie given by the movieId parameter, if any, as the kind
+// given by the kind parameter (poster by default), written by the editor parameter. The response
+// is 201 Created, or 200 OK if the image was already uploaded.
+func (h *AssetHandler) UploadAsset(w http.ResponseWriter, req *http.Request) {
+	movieID := req.URL.Query().Get("movieId")
+	kind := model.AssetPoster
+	if k := req.URL.Query().Get("kind"); k != "" {
+		kind = model.AssetKind(k)
+	}
+	if !kind.Valid() {
+		http.Error(w, "unknown asset kind", http.StatusBadRequest)
+		return
+	}
+	// The body holds the image and the headers of its part.
+	req.Body = http.MaxBytesReader(w, req.Body, asset.MaxSize+maxUploadOverhead)
+	mr, err := req.MultipartReader()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// The file part is read as it is received, without buffering the body to disk.
+	var file io.Reader
+	for file == nil {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			http.Error(w, "no file part", http.StatusBadRequest)
+			return
+		} else if tooLarge(err) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if part.FormName() == "file" {
+			file = part
+		}
+	}
+	ctx := req.Context()
+	a, deduplicated, err := h.assets.Upload(ctx, file)
+	if err != nil && errors.Is(err, asset.ErrInvalidArgument) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if tooLarge(err) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		log.Printf("Asset upload error: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	res := uploadResponse{Asset: a, Deduplicated: deduplicated}
+	if movieID != "" {
+		m, err := h.ctrl.AttachAsset(ctx, movieID, model.AssetRef{Kind: kind, Hash: a.Hash}, req.URL.Query().Get("editor"))
+		if err != nil && errors.Is(err, metadata.ErrNotFound) {
+			http.Error(w, "movie not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("Asset attach error: %v\n", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		res.Metadata = m
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if deduplicated {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Printf("Response encode error: %v\n", err)
+	}
+}
+
+// maxUploadOverhead bounds the bytes of upload bodies besides the image.
+const maxUploadOverhead = 64 << 10
+
+// tooLarge reports whether reading a request body failed because it is too large.
+func tooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// GetAsset handles GET /assets/{hash} and GET /assets/{hash}/{size} requests, serving an image or
+// its thumbnail of a size such as w185. Images never change, so they can be cached forever.
+func (h *AssetHandler) GetAsset(w http.ResponseWriter, req *http.Request) {
+	hash, size := req.PathValue("hash"), req.PathValue("size")
+	tag := `"` + hash + `"`
+	if size != "" {
+		tag = `"` + hash + "/" + size + `"`
+	}
+	r, contentType, err := h.assets.Open(req.Context(), hash, size)
+	if err != nil && errors.Is(err, asset.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Asset open error: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer r.Close()
+	w.Header().Set("ETag", tag)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	// The asset is looked up first, so that made-up hashes are not found rather than not modified.
+	if req.Header.Get("If-None-Match") == tag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	if _, err := io.Copy(w, r); err != nil {
+		log.Printf("Asset write error: %v\n", err)
+	}
+}
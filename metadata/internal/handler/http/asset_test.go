@@ -0,0 +1,97 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/meirongdev/movie-microservice/metadata/internal/blob/local"
+	"github.com/meirongdev/movie-microservice/metadata/internal/controller/asset"
+	"github.com/meirongdev/movie-microservice/metadata/internal/controller/metadata"
+	"github.com/meirongdev/movie-microservice/metadata/internal/repository/memory"
+)
+
+// newTestAssetHandler returns an asset handler over a temporary directory storing an image, and
+// the hash of the image.
+func newTestAssetHandler(t *testing.T) (*AssetHandler, string) {
+	t.Helper()
+	store, err := local.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assets := asset.New(store)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 10, 10))); err != nil {
+		t.Fatal(err)
+	}
+	a, _, err := assets.Upload(context.Background(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewAssetHandler(assets, metadata.New(memory.New())), a.Hash
+}
+
+func TestGetAsset(t *testing.T) {
+	h, hash := newTestAssetHandler(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /assets/{hash}", h.GetAsset)
+	mux.HandleFunc("GET /assets/{hash}/{size}", h.GetAsset)
+	invented := strings.Repeat("0", 64)
+	tests := []struct {
+		name        string
+		path        string
+		ifNoneMatch string
+		status      int
+	}{
+		{"image", "/assets/" + hash, "", http.StatusOK},
+		{"thumbnail", "/assets/" + hash + "/w185", "", http.StatusOK},
+		{"not modified", "/assets/" + hash, `"` + hash + `"`, http.StatusNotModified},
+		{"thumbnail not modified", "/assets/" + hash + "/w185", `"` + hash + `/w185"`, http.StatusNotModified},
+		{"other thumbnail", "/assets/" + hash + "/w342", `"` + hash + `/w185"`, http.StatusOK},
+		{"not found", "/assets/" + invented, "", http.StatusNotFound},
+		{"invented tag", "/assets/" + invented, `"` + invented + `"`, http.StatusNotFound},
+		{"unknown size", "/assets/" + hash + "/w1000", `"` + hash + `/w1000"`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("GetAsset: got status %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestUploadAssetTooLarge(t *testing.T) {
+	h, _ := newTestAssetHandler(t)
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	// Parts before the file part count towards the bounded size of the body.
+	if err := mw.WriteField("padding", strings.Repeat("x", asset.MaxSize+maxUploadOverhead)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mw.CreateFormFile("file", "poster.png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/assets", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	h.UploadAsset(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("UploadAsset: got status %d, want %d: %s", w.Code, http.StatusRequestEntityTooLarge, w.Body)
+	}
+}
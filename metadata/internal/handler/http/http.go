@@ -3,10 +3,16 @@ package http
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/meirongdev/movie-microservice/metadata/internal/controller/metadata"
+	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+	"github.com/meirongdev/movie-microservice/pkg/locale"
 )
 
 // Handler defines a movie metadata HTTP handler.
@@ -19,13 +25,21 @@ func New(ctrl *metadata.Controller) *Handler {
 	return &Handler{ctrl}
 }
 
-// GetMetadata handles GET /metadata requests.
+// GetMetadata handles GET /metadata requests. The title and description are served in the locale
+// parameter or a locale of the Accept-Language header, if translated, which is given as
+// Content-Language. Responses carry the version of the metadata as ETag, qualified by the served
+// locale, and requests whose If-None-Match lists it get 304 Not Modified.
 func (h *Handler) GetMetadata(w http.ResponseWriter, req *http.Request) {
 	id := req.FormValue("id")
 	if id == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	locales, err := locale.FromRequest(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	ctx := req.Context()
 	m, err := h.ctrl.Get(ctx, id)
 	if err != nil && errors.Is(err, metadata.ErrNotFound) {
@@ -36,7 +50,181 @@ func (h *Handler) GetMetadata(w http.ResponseWriter, req *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	m, served := m.Localize(locales)
+	tag := etag(m.Version, served)
+	w.Header().Set("ETag", tag)
+	w.Header().Set("Vary", "Accept-Language")
+	if served != "" {
+		w.Header().Set("Content-Language", served)
+	}
+	if noneMatch := req.Header.Get("If-None-Match"); noneMatch != "" && matchesETag(noneMatch, tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 	if err := json.NewEncoder(w).Encode(m); err != nil {
 		log.Printf("Response encode error: %v\n", err)
 	}
 }
+
+// PutMetadata handles PUT /metadata requests with JSON metadata. With If-Match the metadata is only
+// written if its stored version is the given ETag, or if it exists for *, and with If-None-Match: *
+// only if it does not exist yet; otherwise the response is 412 Precondition Failed.
+func (h *Handler) PutMetadata(w http.ResponseWriter, req *http.Request) {
+	id := req.FormValue("id")
+	var m model.Metadata
+	if err := json.NewDecoder(req.Body).Decode(&m); err != nil || id == "" || (m.ID != "" && m.ID != id) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	m.ID = id
+	ctx := req.Context()
+	expectedVersion := metadata.AnyVersion
+	match, noneMatch := req.Header.Get("If-Match"), req.Header.Get("If-None-Match")
+	switch {
+	case match != "" && noneMatch != "":
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	case strings.TrimSpace(noneMatch) == "*":
+		expectedVersion = 0
+	case noneMatch != "":
+		// Only creating missing metadata is supported.
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	case strings.TrimSpace(match) == "*":
+		current, err := h.ctrl.Get(ctx, id)
+		if err != nil && errors.Is(err, metadata.ErrNotFound) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		} else if err != nil {
+			log.Printf("Repository get error: %v\n", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		expectedVersion = current.Version
+	case match != "":
+		version, ok := parseETag(match)
+		if !ok {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		expectedVersion = version
+	}
+	stored, err := h.ctrl.Put(ctx, &m, expectedVersion)
+	writeResult(w, stored, err)
+}
+
+// PatchMetadata handles PATCH /metadata requests with a JSON merge patch (RFC 7396) of metadata:
+// the fields present in the patch are updated, and null resets them. With If-Match the metadata is
+// only updated if its stored version is the given ETag; otherwise the response is 412 Precondition Failed.
+func (h *Handler) PatchMetadata(w http.ResponseWriter, req *http.Request) {
+	id := req.FormValue("id")
+	body, err := io.ReadAll(req.Body)
+	var patch map[string]json.RawMessage
+	var m model.Metadata
+	if err != nil || id == "" || json.Unmarshal(body, &patch) != nil || json.Unmarshal(body, &m) != nil || (m.ID != "" && m.ID != id) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	m.ID = id
+	var paths []string
+	for name := range patch {
+		// The id selects the metadata and updatedBy is the editor, so they are not updated as fields.
+		if name == "id" || name == "updatedBy" {
+			continue
+		}
+		path, ok := model.FieldPath(name)
+		if !ok {
+			http.Error(w, fmt.Sprintf("field %q cannot be updated", name), http.StatusBadRequest)
+			return
+		}
+		paths = append(paths, path)
+	}
+	expectedVersion := metadata.AnyVersion
+	if match := strings.TrimSpace(req.Header.Get("If-Match")); match != "" && match != "*" {
+		version, ok := parseETag(match)
+		if !ok {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		expectedVersion = version
+	}
+	stored, err := h.ctrl.Update(req.Context(), &m, paths, expectedVersion)
+	writeResult(w, stored, err)
+}
+
+// DeleteMetadata handles DELETE /metadata requests, marking metadata as deleted with the reason and
+// editor given as query parameters. With If-Match the metadata is only deleted if its stored version
+// is the given ETag; otherwise the response is 412 Precondition Failed.
+func (h *Handler) DeleteMetadata(w http.ResponseWriter, req *http.Request) {
+	id := req.FormValue("id")
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	expectedVersion := metadata.AnyVersion
+	if match := strings.TrimSpace(req.Header.Get("If-Match")); match != "" && match != "*" {
+		version, ok := parseETag(match)
+		if !ok {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		expectedVersion = version
+	}
+	stored, err := h.ctrl.Delete(req.Context(), id, req.FormValue("reason"), req.FormValue("editor"), expectedVersion)
+	writeResult(w, stored, err)
+}
+
+// writeResult writes the metadata written by a request, with its ETag, or the error of the write.
+func writeResult(w http.ResponseWriter, stored *model.Metadata, err error) {
+	if err != nil && errors.Is(err, metadata.ErrVersionMismatch) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	} else if err != nil && errors.Is(err, metadata.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil && errors.Is(err, metadata.ErrInvalidArgument) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil && errors.Is(err, metadata.ErrDuplicateExternalID) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Repository write error: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", etag(stored.Version, ""))
+	if err := json.NewEncoder(w).Encode(stored); err != nil {
+		log.Printf("Response encode error: %v\n", err)
+	}
+}
+
+// etag returns the entity tag of a metadata version served in a locale, empty if not translated.
+func etag(version int64, locale string) string {
+	if locale != "" {
+		return fmt.Sprintf("%q", strconv.FormatInt(version, 10)+"-"+locale)
+	}
+	return fmt.Sprintf("%q", strconv.FormatInt(version, 10))
+}
+
+// parseETag returns the version of a single strong entity tag, which may be qualified by a locale.
+func parseETag(tag string) (int64, bool) {
+	unquoted, err := strconv.Unquote(strings.TrimSpace(tag))
+	if err != nil {
+		return 0, false
+	}
+	unquoted, _, _ = strings.Cut(unquoted, "-")
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	return version, err == nil && version > 0
+}
+
+// matchesETag reports whether a list of entity tags matches a tag, using the weak comparison of If-None-Match.
+func matchesETag(tags string, tag string) bool {
+	for _, t := range strings.Split(tags, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == tag {
+			return true
+		}
+	}
+	return false
+}
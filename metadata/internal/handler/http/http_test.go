@@ -0,0 +1,143 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/meirongdev/movie-microservice/metadata/internal/controller/metadata"
+	"github.com/meirongdev/movie-microservice/metadata/internal/repository/memory"
+	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+)
+
+func TestPatchMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		body    string
+		ifMatch string
+		status  int
+		want    func(m *model.Metadata)
+	}{
+		{"fields", "1", `{"title":"Aliens","runtimeMinutes":137,"updatedBy":"editor"}`, "", http.StatusOK, func(m *model.Metadata) {
+			m.Title, m.RuntimeMinutes, m.UpdatedBy = "Aliens", 137, "editor"
+		}},
+		{"null resets", "1", `{"releaseDate":null,"genres":null,"externalIds":null}`, "", http.StatusOK, func(m *model.Metadata) {
+			m.ReleaseDate, m.Genres, m.ExternalIDs = "", nil, nil
+		}},
+		{"lists and maps are replaced", "1", `{"genres":["Sci-Fi"],"localizations":{"de":{"title":"Alien - Das unheimliche Wesen"}}}`, "", http.StatusOK, func(m *model.Metadata) {
+			m.Genres, m.Localizations = []string{"Sci-Fi"}, map[string]model.Localization{"de": {Title: "Alien - Das unheimliche Wesen"}}
+		}},
+		{"matching id", "1", `{"id":"1","director":"James Cameron"}`, `"1"`, http.StatusOK, func(m *model.Metadata) {
+			m.Director = "James Cameron"
+		}},
+		{"stale version", "1", `{"title":"Aliens"}`, `"2"`, http.StatusPreconditionFailed, nil},
+		{"malformed version", "1", `{"title":"Aliens"}`, `2`, http.StatusPreconditionFailed, nil},
+		{"proto name", "1", `{"release_date":"1986-07-18"}`, "", http.StatusBadRequest, nil},
+		{"not editable", "1", `{"version":3}`, "", http.StatusBadRequest, nil},
+		{"unknown field", "1", `{"rating":5}`, "", http.StatusBadRequest, nil},
+		{"no fields", "1", `{}`, "", http.StatusBadRequest, nil},
+		{"other id", "1", `{"id":"2","title":"Aliens"}`, "", http.StatusBadRequest, nil},
+		{"no id", "", `{"title":"Aliens"}`, "", http.StatusBadRequest, nil},
+		{"malformed", "1", `{"title":`, "", http.StatusBadRequest, nil},
+		{"invalid value", "1", `{"releaseDate":"July 1986"}`, "", http.StatusBadRequest, nil},
+		{"not found", "2", `{"title":"Aliens"}`, "", http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := metadata.New(memory.New())
+			stored, err := ctrl.Put(context.Background(), &model.Metadata{
+				ID: "1", Title: "Alien", Director: "Ridley Scott", ReleaseDate: "1979-05-25", Genres: []string{"Horror"},
+				ExternalIDs: map[string]string{model.NamespaceIMDb: "tt0078748"},
+			}, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPatch, "/metadata?id="+tt.id, strings.NewReader(tt.body))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			New(ctrl).PatchMetadata(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("PatchMetadata: got status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			current, err := ctrl.Get(context.Background(), "1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if current.Version != stored.Version {
+					t.Fatalf("failed PatchMetadata wrote version %d", current.Version)
+				}
+				return
+			}
+			var got model.Metadata
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			want := stored.Clone()
+			tt.want(want)
+			if got.Title != want.Title || got.Director != want.Director || got.ReleaseDate != want.ReleaseDate ||
+				got.RuntimeMinutes != want.RuntimeMinutes || got.UpdatedBy != want.UpdatedBy ||
+				len(got.Genres) != len(want.Genres) || len(got.ExternalIDs) != len(want.ExternalIDs) ||
+				got.Localizations["de"] != want.Localizations["de"] {
+				t.Fatalf("PatchMetadata:\n got %+v\nwant %+v", got, want)
+			}
+			if got.Version != 2 || w.Header().Get("ETag") != `"2"` {
+				t.Fatalf("PatchMetadata: got version %d and ETag %s, want 2", got.Version, w.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestGetMetadataETag(t *testing.T) {
+	ctrl := metadata.New(memory.New())
+	if _, err := ctrl.Put(context.Background(), &model.Metadata{
+		ID: "1", Title: "City of God", Localizations: map[string]model.Localization{"pt": {Title: "Cidade de Deus"}},
+	}, 0); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		ifNoneMatch    string
+		status         int
+		etag           string
+	}{
+		{"untranslated", "", "", http.StatusOK, `"1"`},
+		{"translated", "pt-BR", "", http.StatusOK, `"1-pt"`},
+		{"not modified", "", `"1"`, http.StatusNotModified, `"1"`},
+		{"translation not modified", "pt-BR", `W/"1-pt"`, http.StatusNotModified, `"1-pt"`},
+		{"other locale", "pt-BR", `"1"`, http.StatusOK, `"1-pt"`},
+		{"untranslated after a translation", "de", `"1-pt"`, http.StatusOK, `"1"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metadata?id=1", nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+			New(ctrl).GetMetadata(w, req)
+			if w.Code != tt.status || w.Header().Get("ETag") != tt.etag {
+				t.Fatalf("GetMetadata: got status %d and ETag %s, want %d and %s", w.Code, w.Header().Get("ETag"), tt.status, tt.etag)
+			}
+		})
+	}
+
+	// A localized ETag can be used to write the metadata.
+	req := httptest.NewRequest(http.MethodPatch, "/metadata?id=1", strings.NewReader(`{"title":"City of God (2002)"}`))
+	req.Header.Set("If-Match", `"1-pt"`)
+	w := httptest.NewRecorder()
+	New(ctrl).PatchMetadata(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Fatalf("PatchMetadata with a localized ETag: got status %d and ETag %s, want 200 and \"2\"", w.Code, w.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+)
+
+// BatchPut defines movie metadata written by a batch, with the version it is expected to have as
+// for single writes.
+type BatchPut struct {
+	Metadata        *model.Metadata
+	ExpectedVersion int64
+}
+
+// BatchError is returned when an item of a batch cannot be written, which rolls back the batch.
+type BatchError struct {
+	// Index is the index of the item in the batch.
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
@@ -4,3 +4,12 @@ import "errors"
 
 // ErrNotFound is returned when a requested record is not found.
 var ErrNotFound = errors.New("not found")
+
+// ErrVersionMismatch is returned when a write expects another version of a record than the stored one.
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// AnyVersion is the expected version of unconditional writes. Writes expecting version 0 only create records.
+const AnyVersion int64 = -1
+
+// ErrDuplicateExternalID is returned when a write assigns an external id of another movie.
+var ErrDuplicateExternalID = errors.New("external id already assigned to another movie")
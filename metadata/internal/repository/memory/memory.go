@@ -1,8 +1,12 @@
 package memory
 
 import (
+	"cmp"
 	"context"
+	"fmt"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/meirongdev/movie-microservice/metadata/internal/repository"
 	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
@@ -11,29 +15,369 @@ import (
 // Repository defines a memory movie matadata repository.
 type Repository struct {
 	sync.RWMutex
-	data map[string]*model.Metadata
+	data map[string]model.Metadata
+	// revisions holds the revisions of every movie in version order.
+	revisions map[string][]model.Revision
+	// index maps search terms to the ids of the movies containing them, with the number of occurrences.
+	index map[string]map[string]int
+	// externalIDs maps the external ids claimed by movies to their ids.
+	externalIDs map[externalKey]string
+}
+
+// externalKey defines an external id in a namespace.
+type externalKey struct {
+	namespace, id string
 }
 
 // New creates a new memory repository.
 func New() *Repository {
-	return &Repository{data: map[string]*model.Metadata{}}
+	return &Repository{data: map[string]model.Metadata{}, revisions: map[string][]model.Revision{}, index: map[string]map[string]int{}, externalIDs: map[externalKey]string{}}
 }
 
 // Get retrieves movie metadata for by movie id.
-func (r *Repository) Get(_ context.Context, id string) (*model.Metadata, error) {
+func (r *Repository) Get(ctx context.Context, id string) (*model.Metadata, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	r.RLock()
 	defer r.RUnlock()
 	m, ok := r.data[id]
 	if !ok {
 		return nil, repository.ErrNotFound
 	}
-	return m, nil
+	return m.Clone(), nil
+}
+
+// GetForUpdate retrieves movie metadata by movie id, to be changed and written back with Put.
+// It is the same as Get, as there are no replicas to lag behind writes.
+func (r *Repository) GetForUpdate(ctx context.Context, id string) (*model.Metadata, error) {
+	return r.Get(ctx, id)
+}
+
+// Put adds movie metadata for a given movie id, replacing existing metadata if its version is the
+// expected one, and returns the metadata as stored.
+func (r *Repository) Put(ctx context.Context, id string, metadata *model.Metadata, expectedVersion int64) (*model.Metadata, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.Lock()
+	defer r.Unlock()
+	m := metadata.Clone()
+	m.ID = id
+	if err := r.check([]repository.BatchPut{{Metadata: m, ExpectedVersion: expectedVersion}}); err != nil {
+		return nil, err.Err
+	}
+	return r.put(id, metadata), nil
 }
 
-// Put adds movie metadata for a given movie id.
-func (r *Repository) Put(_ context.Context, id string, metadata *model.Metadata) error {
+// PutBatch writes the metadata of a batch at once, each as written by Put, and returns the
+// metadata as stored. If an item cannot be written, nothing is written and a
+// *repository.BatchError is returned.
+func (r *Repository) PutBatch(ctx context.Context, items []repository.BatchPut) ([]*model.Metadata, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	r.Lock()
 	defer r.Unlock()
-	r.data[id] = metadata
+	if err := r.check(items); err != nil {
+		return nil, err
+	}
+	res := make([]*model.Metadata, len(items))
+	for i, item := range items {
+		res[i] = r.put(item.Metadata.ID, item.Metadata)
+	}
+	return res, nil
+}
+
+// check returns an error for the first item of a batch that cannot be written, counting the
+// writes of earlier items.
+func (r *Repository) check(items []repository.BatchPut) *repository.BatchError {
+	versions := map[string]int64{}
+	owners := map[externalKey]string{}
+	externalIDs := map[string]map[string]string{}
+	for i, item := range items {
+		id := item.Metadata.ID
+		version, ok := versions[id]
+		if !ok {
+			version = r.data[id].Version
+		}
+		if item.ExpectedVersion != repository.AnyVersion && item.ExpectedVersion != version {
+			return &repository.BatchError{Index: i, Err: repository.ErrVersionMismatch}
+		}
+		versions[id] = version + 1
+		current, ok := externalIDs[id]
+		if !ok {
+			old := r.data[id]
+			current = claims(&old)
+		}
+		for namespace, externalID := range current {
+			owners[externalKey{namespace, externalID}] = ""
+		}
+		for namespace, externalID := range claims(item.Metadata) {
+			key := externalKey{namespace, externalID}
+			owner, ok := owners[key]
+			if !ok {
+				owner = r.externalIDs[key]
+			}
+			if owner != "" && owner != id {
+				return &repository.BatchError{Index: i, Err: fmt.Errorf("%w: %s id %q", repository.ErrDuplicateExternalID, namespace, externalID)}
+			}
+			owners[key] = id
+		}
+		externalIDs[id] = claims(item.Metadata)
+	}
 	return nil
 }
+
+// put writes movie metadata and its revision, with the version following the stored one.
+func (r *Repository) put(id string, metadata *model.Metadata) *model.Metadata {
+	old, ok := r.data[id]
+	m := *metadata.Clone()
+	m.ID = id
+	m.Version = old.Version + 1
+	m.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	rev := model.Revision{MovieID: id, Version: m.Version, Editor: m.UpdatedBy, Time: m.UpdatedAt, Metadata: *m.Clone()}
+	if ok {
+		rev.Changes = model.Diff(&old, &m)
+		r.unindex(&old)
+		r.unassign(&old)
+	} else {
+		rev.Changes = model.Diff(nil, &m)
+	}
+	r.data[id] = m
+	r.revisions[id] = append(r.revisions[id], rev)
+	r.indexMetadata(&m)
+	for namespace, externalID := range claims(&m) {
+		r.externalIDs[externalKey{namespace, externalID}] = id
+	}
+	return m.Clone()
+}
+
+// claims returns the external ids claimed by a movie. Deleted movies keep their external ids but
+// release them, so that a replacement can claim them.
+func claims(m *model.Metadata) map[string]string {
+	if m.Tombstone != nil {
+		return nil
+	}
+	return m.ExternalIDs
+}
+
+// unassign removes the external ids claimed by a movie.
+func (r *Repository) unassign(m *model.Metadata) {
+	for namespace, externalID := range claims(m) {
+		delete(r.externalIDs, externalKey{namespace, externalID})
+	}
+}
+
+// ResolveExternalID returns the id of the movie with an external id in a namespace, ignoring
+// deleted movies.
+func (r *Repository) ResolveExternalID(ctx context.Context, namespace, externalID string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	r.RLock()
+	defer r.RUnlock()
+	id, ok := r.externalIDs[externalKey{namespace, externalID}]
+	if !ok {
+		return "", repository.ErrNotFound
+	}
+	return id, nil
+}
+
+// Purge removes the movies deleted before a time, with their change history, and returns their number.
+func (r *Repository) Purge(ctx context.Context, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.Lock()
+	defer r.Unlock()
+	n := 0
+	for id, m := range r.data {
+		if m.Tombstone != nil && m.Tombstone.DeletedAt.Before(before) {
+			r.unindex(&m)
+			r.unassign(&m)
+			delete(r.data, id)
+			delete(r.revisions, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// ListRevisions returns a page of the revisions of a movie, the latest first, and the cursor of the next page.
+func (r *Repository) ListRevisions(ctx context.Context, q repository.RevisionQuery) ([]*model.Revision, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	cursor, err := repository.DecodeCursor(q.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	r.RLock()
+	defer r.RUnlock()
+	revisions := r.revisions[q.MovieID]
+	var res []*model.Revision
+	for i := len(revisions) - 1; i >= 0 && len(res) <= q.PageSize; i-- {
+		if cursor.Version == 0 || revisions[i].Version < cursor.Version {
+			res = append(res, cloneRevision(&revisions[i]))
+		}
+	}
+	more := len(res) > q.PageSize
+	if more {
+		res = res[:q.PageSize]
+	}
+	return res, repository.NextRevisionCursor(res, more), nil
+}
+
+// GetRevision retrieves a revision of a movie by the version it wrote.
+func (r *Repository) GetRevision(ctx context.Context, id string, version int64) (*model.Revision, error) {
+	return r.latestRevision(ctx, id, func(rev *model.Revision) bool { return rev.Version == version })
+}
+
+// GetRevisionAt retrieves the latest revision of a movie written at or before a time.
+func (r *Repository) GetRevisionAt(ctx context.Context, id string, t time.Time) (*model.Revision, error) {
+	return r.latestRevision(ctx, id, func(rev *model.Revision) bool { return !rev.Time.After(t) })
+}
+
+// latestRevision returns the latest revision of a movie satisfying match.
+func (r *Repository) latestRevision(ctx context.Context, id string, match func(rev *model.Revision) bool) (*model.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.RLock()
+	defer r.RUnlock()
+	revisions := r.revisions[id]
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if match(&revisions[i]) {
+			return cloneRevision(&revisions[i]), nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func cloneRevision(rev *model.Revision) *model.Revision {
+	c := *rev
+	c.Changes = slices.Clone(rev.Changes)
+	c.Metadata = *rev.Metadata.Clone()
+	return &c
+}
+
+// List returns a page of movie metadata sorted as requested, and the cursor of the next page.
+func (r *Repository) List(ctx context.Context, q repository.ListQuery) ([]*model.Metadata, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	cursor, err := repository.ListCursor(q)
+	if err != nil {
+		return nil, "", err
+	}
+	r.RLock()
+	all := make([]*model.Metadata, 0, len(r.data))
+	for _, m := range r.data {
+		if m.Tombstone == nil {
+			all = append(all, m.Clone())
+		}
+	}
+	r.RUnlock()
+	compare := func(a, b *model.Metadata) int {
+		c := cmp.Or(cmp.Compare(q.SortBy.Value(a), q.SortBy.Value(b)), cmp.Compare(a.ID, b.ID))
+		if q.Descending {
+			return -c
+		}
+		return c
+	}
+	slices.SortFunc(all, compare)
+	start := 0
+	if q.Cursor != "" {
+		last := &model.Metadata{ID: cursor.ID}
+		switch q.SortBy {
+		case repository.SortByTitle:
+			last.Title = cursor.Value
+		case repository.SortByDirector:
+			last.Director = cursor.Value
+		}
+		start, _ = slices.BinarySearchFunc(all, last, compare)
+		if start < len(all) && all[start].ID == cursor.ID {
+			start++
+		}
+	}
+	return page(all[start:], q.PageSize, func(res []*model.Metadata, more bool) string {
+		return repository.NextListCursor(q, res, more)
+	})
+}
+
+// Search returns a page of movie metadata matching all terms of the query, the movies with the most
+// occurrences of the terms first, and the cursor of the next page.
+func (r *Repository) Search(ctx context.Context, q repository.SearchQuery) ([]*model.Metadata, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	cursor, err := repository.SearchCursor(q)
+	if err != nil {
+		return nil, "", err
+	}
+	terms := repository.Tokenize(q.Query)
+	if len(terms) == 0 {
+		return nil, "", nil
+	}
+	r.RLock()
+	scores := map[string]int{}
+	for id, n := range r.index[terms[0]] {
+		scores[id] = n
+	}
+	for _, term := range terms[1:] {
+		for id, score := range scores {
+			if n, ok := r.index[term][id]; ok {
+				scores[id] = score + n
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+	matches := make([]*model.Metadata, 0, len(scores))
+	for id := range scores {
+		if m := r.data[id]; m.Tombstone == nil {
+			matches = append(matches, m.Clone())
+		}
+	}
+	r.RUnlock()
+	slices.SortFunc(matches, func(a, b *model.Metadata) int {
+		return cmp.Or(cmp.Compare(scores[b.ID], scores[a.ID]), cmp.Compare(a.ID, b.ID))
+	})
+	offset := min(cursor.Offset, len(matches))
+	return page(matches[offset:], q.PageSize, func(res []*model.Metadata, more bool) string {
+		return repository.NextSearchCursor(q, offset+len(res), more)
+	})
+}
+
+// page returns up to size results and the cursor built by next.
+func page(results []*model.Metadata, size int, next func(res []*model.Metadata, more bool) string) ([]*model.Metadata, string, error) {
+	more := len(results) > size
+	if more {
+		results = results[:size]
+	}
+	return results, next(results, more), nil
+}
+
+func (r *Repository) indexMetadata(m *model.Metadata) {
+	for _, term := range terms(m) {
+		if r.index[term] == nil {
+			r.index[term] = map[string]int{}
+		}
+		r.index[term][m.ID]++
+	}
+}
+
+func (r *Repository) unindex(m *model.Metadata) {
+	for _, term := range terms(m) {
+		delete(r.index[term], m.ID)
+		if len(r.index[term]) == 0 {
+			delete(r.index, term)
+		}
+	}
+}
+
+// terms returns the search terms of movie metadata.
+func terms(m *model.Metadata) []string {
+	return repository.Tokenize(m.Title + " " + m.Director + " " + m.Description)
+}
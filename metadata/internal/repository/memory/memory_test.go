@@ -0,0 +1,13 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/meirongdev/movie-microservice/metadata/internal/repository/repositorytest"
+)
+
+func TestRepository(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repository {
+		return New()
+	})
+}
@@ -0,0 +1,5 @@
+ALTER TABLE movies
+    DROP INDEX movies_director,
+    DROP INDEX movies_title,
+    DROP PRIMARY KEY,
+    MODIFY id VARCHAR(255) NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE movies
+    MODIFY id VARCHAR(255) NOT NULL,
+    ADD PRIMARY KEY (id),
+    ADD INDEX movies_title (title),
+    ADD INDEX movies_director (director);
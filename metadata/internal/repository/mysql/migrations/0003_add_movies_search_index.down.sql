@@ -0,0 +1 @@
+ALTER TABLE movies DROP INDEX movies_search;
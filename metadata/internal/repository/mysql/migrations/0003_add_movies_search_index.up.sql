@@ -0,0 +1 @@
+ALTER TABLE movies ADD FULLTEXT INDEX movies_search (title, director, description);
@@ -0,0 +1,9 @@
+DROP TABLE movie_credits;
+DROP TABLE movie_genres;
+DROP TABLE genres;
+ALTER TABLE movies
+    DROP COLUMN age_rating,
+    DROP COLUMN country,
+    DROP COLUMN language,
+    DROP COLUMN runtime_minutes,
+    DROP COLUMN release_date;
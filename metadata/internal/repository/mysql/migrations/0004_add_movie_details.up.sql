@@ -0,0 +1,32 @@
+ALTER TABLE movies
+    ADD COLUMN release_date DATE NULL,
+    ADD COLUMN runtime_minutes INT NOT NULL DEFAULT 0,
+    ADD COLUMN language VARCHAR(8) NOT NULL DEFAULT '',
+    ADD COLUMN country VARCHAR(8) NOT NULL DEFAULT '',
+    ADD COLUMN age_rating VARCHAR(16) NOT NULL DEFAULT '';
+CREATE TABLE genres (
+    id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    UNIQUE KEY genres_name (name)
+);
+-- Genres of movies, in the order they are listed.
+CREATE TABLE movie_genres (
+    movie_id VARCHAR(255) NOT NULL,
+    genre_id INT NOT NULL,
+    position INT NOT NULL,
+    PRIMARY KEY (movie_id, genre_id),
+    KEY movie_genres_genre (genre_id),
+    CONSTRAINT movie_genres_movie FOREIGN KEY (movie_id) REFERENCES movies (id) ON DELETE CASCADE,
+    CONSTRAINT movie_genres_genre FOREIGN KEY (genre_id) REFERENCES genres (id)
+);
+-- Cast and crew of movies, in billing order.
+CREATE TABLE movie_credits (
+    movie_id VARCHAR(255) NOT NULL,
+    position INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    role VARCHAR(32) NOT NULL,
+    character_name VARCHAR(255) NOT NULL DEFAULT '',
+    PRIMARY KEY (movie_id, position),
+    KEY movie_credits_name (name, role),
+    CONSTRAINT movie_credits_movie FOREIGN KEY (movie_id) REFERENCES movies (id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+ALTER TABLE movies
+    DROP COLUMN updated_at,
+    DROP COLUMN version;
@@ -0,0 +1,5 @@
+-- Existing movies start at version 1, as version 0 means that a movie does not exist.
+ALTER TABLE movies
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1,
+    -- Unix time in microseconds, 0 if unknown.
+    ADD COLUMN updated_at BIGINT NOT NULL DEFAULT 0;
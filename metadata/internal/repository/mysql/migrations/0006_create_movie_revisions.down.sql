@@ -0,0 +1,2 @@
+DROP TABLE movie_revisions;
+ALTER TABLE movies DROP COLUMN updated_by;
@@ -0,0 +1,37 @@
+ALTER TABLE movies ADD COLUMN updated_by VARCHAR(255) NOT NULL DEFAULT '';
+-- Append-only change history of movies, one revision per written version. Times are unix
+-- microseconds, changes and snapshots are JSON.
+CREATE TABLE movie_revisions (
+    movie_id VARCHAR(255) NOT NULL,
+    version BIGINT NOT NULL,
+    editor VARCHAR(255) NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL,
+    changes MEDIUMTEXT NOT NULL,
+    snapshot MEDIUMTEXT NOT NULL,
+    PRIMARY KEY (movie_id, version),
+    KEY movie_revisions_time (movie_id, created_at)
+);
+-- Existing movies get a revision of their current version, so that their history starts with
+-- their state when revisions were introduced. Lists are built with GROUP_CONCAT, which keeps
+-- their order, so its length limit is raised for long credits.
+SET SESSION group_concat_max_len = 16777216;
+INSERT INTO movie_revisions (movie_id, version, editor, created_at, changes, snapshot)
+SELECT m.id, m.version, m.updated_by, m.updated_at, '[]', JSON_OBJECT(
+    'id', m.id,
+    'title', COALESCE(m.title, ''),
+    'description', COALESCE(m.description, ''),
+    'director', COALESCE(m.director, ''),
+    'genres', (SELECT CAST(CONCAT('[', GROUP_CONCAT(JSON_QUOTE(g.name) ORDER BY mg.position SEPARATOR ','), ']') AS JSON)
+        FROM movie_genres mg JOIN genres g ON g.id = mg.genre_id WHERE mg.movie_id = m.id),
+    'credits', (SELECT CAST(CONCAT('[', GROUP_CONCAT(JSON_OBJECT('name', c.name, 'role', c.role, 'character', c.character_name)
+        ORDER BY c.position SEPARATOR ','), ']') AS JSON) FROM movie_credits c WHERE c.movie_id = m.id),
+    'releaseDate', COALESCE(DATE_FORMAT(m.release_date, '%Y-%m-%d'), ''),
+    'runtimeMinutes', m.runtime_minutes,
+    'language', m.language,
+    'country', m.country,
+    'ageRating', m.age_rating,
+    'version', m.version,
+    'updatedAt', IF(m.updated_at = 0, '0001-01-01T00:00:00Z',
+        DATE_FORMAT(TIMESTAMPADD(MICROSECOND, m.updated_at, '1970-01-01 00:00:00'), '%Y-%m-%dT%H:%i:%s.%fZ')),
+    'updatedBy', m.updated_by
+) FROM movies m WHERE m.id IS NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE movies
+    DROP INDEX movies_deleted_at,
+    DROP COLUMN delete_reason,
+    DROP COLUMN deleted_at;
@@ -0,0 +1,5 @@
+-- Deleted movies keep a tombstone until they are purged. deleted_at is in unix microseconds.
+ALTER TABLE movies
+    ADD COLUMN deleted_at BIGINT NULL,
+    ADD COLUMN delete_reason VARCHAR(1024) NOT NULL DEFAULT '',
+    ADD INDEX movies_deleted_at (deleted_at);
@@ -0,0 +1 @@
+DROP TABLE movie_external_ids;
@@ -0,0 +1,9 @@
+-- Ids of movies in external namespaces, each identifying at most one movie in its namespace.
+CREATE TABLE movie_external_ids (
+    movie_id VARCHAR(255) NOT NULL,
+    namespace VARCHAR(64) NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    PRIMARY KEY (movie_id, namespace),
+    UNIQUE KEY movie_external_ids_external_id (namespace, external_id),
+    CONSTRAINT movie_external_ids_movie FOREIGN KEY (movie_id) REFERENCES movies (id) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+-- Titles and descriptions of movies translated to BCP 47 locales.
+CREATE TABLE movie_localizations (
+    movie_id VARCHAR(255) NOT NULL,
+    locale VARCHAR(35) NOT NULL,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    description TEXT NOT NULL,
+    PRIMARY KEY (movie_id, locale),
+    CONSTRAINT movie_localizations_movie FOREIGN KEY (movie_id) REFERENCES movies (id) ON DELETE CASCADE
+);
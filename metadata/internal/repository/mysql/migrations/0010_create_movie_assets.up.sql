@@ -0,0 +1,9 @@
+-- Images of movies by the hash of their content, in order.
+CREATE TABLE movie_assets (
+    movie_id VARCHAR(255) NOT NULL,
+    position INT NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    hash CHAR(64) NOT NULL,
+    PRIMARY KEY (movie_id, position),
+    CONSTRAINT movie_assets_movie FOREIGN KEY (movie_id) REFERENCES movies (id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+ALTER TABLE movie_external_ids
+    DROP INDEX movie_external_ids_external_id,
+    DROP COLUMN claimed,
+    MODIFY external_id VARCHAR(255) NOT NULL,
+    ADD UNIQUE KEY movie_external_ids_external_id (namespace, external_id);
@@ -0,0 +1,9 @@
+-- Deleted movies keep their external ids but release them: claimed is NULL for them, and NULLs
+-- are distinct in unique keys, so a replacement movie can claim the ids. External ids are
+-- compared byte for byte, so that ids differing in case or accents are distinct.
+ALTER TABLE movie_external_ids
+    MODIFY external_id VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+    ADD COLUMN claimed TINYINT NULL DEFAULT 1,
+    DROP INDEX movie_external_ids_external_id,
+    ADD UNIQUE KEY movie_external_ids_external_id (namespace, external_id, claimed);
+UPDATE movie_external_ids e JOIN movies m ON m.id = e.movie_id SET e.claimed = NULL WHERE m.deleted_at IS NOT NULL;
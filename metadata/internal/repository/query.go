@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+)
+
+// ErrInvalidCursor is returned when a page cursor is malformed or was issued for another query.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SortField defines a field movie metadata is listed by.
+type SortField string
+
+// Sort fields of movie metadata listings.
+const (
+	SortByID       = SortField("id")
+	SortByTitle    = SortField("title")
+	SortByDirector = SortField("director")
+)
+
+// Valid reports whether movie metadata can be sorted by the field.
+func (f SortField) Valid() bool {
+	switch f {
+	case SortByID, SortByTitle, SortByDirector:
+		return true
+	}
+	return false
+}
+
+// Value returns the value of the field in movie metadata.
+func (f SortField) Value(m *model.Metadata) string {
+	switch f {
+	case SortByTitle:
+		return m.Title
+	case SortByDirector:
+		return m.Director
+	}
+	return m.ID
+}
+
+// ListQuery defines a page of movie metadata sorted by a field, with ties broken by id.
+type ListQuery struct {
+	SortBy     SortField
+	Descending bool
+	// PageSize is the maximum number of results, which must be positive.
+	PageSize int
+	// Cursor is the cursor returned with the previous page, empty for the first page.
+	Cursor string
+}
+
+// SearchQuery defines a page of movie metadata matching all terms of a query in their title,
+// director or description, ordered by relevance with ties broken by id. The memory and SQLite
+// repositories rank movies by the number of occurrences of the terms; MySQL uses the relevance of
+// its full-text index, which also weighs how rare the terms are.
+type SearchQuery struct {
+	Query string
+	// PageSize is the maximum number of results, which must be positive.
+	PageSize int
+	// Cursor is the cursor returned with the previous page, empty for the first page.
+	Cursor string
+}
+
+// RevisionQuery defines a page of the revisions of a movie, the latest first.
+type RevisionQuery struct {
+	MovieID string
+	// PageSize is the maximum number of results, which must be positive.
+	PageSize int
+	// Cursor is the cursor returned with the previous page, empty for the first page.
+	Cursor string
+}
+
+// Cursor defines a position in a listing: the sort value and id of the last listed movie, the
+// terms of a search and the number of its results already returned, or the version of the last
+// listed revision.
+type Cursor struct {
+	SortBy  SortField `json:"s,omitempty"`
+	Value   string    `json:"v,omitempty"`
+	ID      string    `json:"i,omitempty"`
+	Query   string    `json:"q,omitempty"`
+	Offset  int       `json:"o,omitempty"`
+	Version int64     `json:"r,omitempty"`
+}
+
+// Encode returns the opaque form of the cursor handed to clients.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses an encoded cursor. An empty string is the zero cursor.
+func DecodeCursor(s string) (Cursor, error) {
+	var c Cursor
+	if s == "" {
+		return c, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	return c, nil
+}
+
+// NextListCursor returns the cursor of the page after the given results, or an empty string
+// if more is false.
+func NextListCursor(q ListQuery, results []*model.Metadata, more bool) string {
+	if !more || len(results) == 0 {
+		return ""
+	}
+	last := results[len(results)-1]
+	return Cursor{SortBy: q.SortBy, Value: q.SortBy.Value(last), ID: last.ID}.Encode()
+}
+
+// ListCursor decodes the cursor of a listing, checking that it was issued for the same sort field.
+func ListCursor(q ListQuery) (Cursor, error) {
+	c, err := DecodeCursor(q.Cursor)
+	if err == nil && q.Cursor != "" && c.SortBy != q.SortBy {
+		err = fmt.Errorf("%w: issued for sort field %q", ErrInvalidCursor, c.SortBy)
+	}
+	return c, err
+}
+
+// NextSearchCursor returns the cursor of the search results after offset, or an empty string if
+// more is false.
+func NextSearchCursor(q SearchQuery, offset int, more bool) string {
+	if !more {
+		return ""
+	}
+	return Cursor{Query: strings.Join(Tokenize(q.Query), " "), Offset: offset}.Encode()
+}
+
+// SearchCursor decodes the cursor of a search, checking that it was issued for the same terms.
+func SearchCursor(q SearchQuery) (Cursor, error) {
+	c, err := DecodeCursor(q.Cursor)
+	if err == nil && q.Cursor != "" && c.Query != strings.Join(Tokenize(q.Query), " ") {
+		err = fmt.Errorf("%w: issued for search %q", ErrInvalidCursor, c.Query)
+	}
+	return c, err
+}
+
+// NextRevisionCursor returns the cursor of the page after the given revisions, or an empty string
+// if more is false.
+func NextRevisionCursor(results []*model.Revision, more bool) string {
+	if !more || len(results) == 0 {
+		return ""
+	}
+	return Cursor{Version: results[len(results)-1].Version}.Encode()
+}
+
+// Tokenize splits text into lowercase terms of letters and digits, as matched by searches.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
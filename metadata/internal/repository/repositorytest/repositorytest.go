@@ -0,0 +1,693 @@
+// Package repositorytest implements a conformance suite for movie metadata repositories.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/meirongdev/movie-microservice/metadata/internal/repository"
+	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+)
+
+// Repository defines the movie metadata repository contract.
+type Repository interface {
+	Get(ctx context.Context, id string) (*model.Metadata, error)
+	GetForUpdate(ctx context.Context, id string) (*model.Metadata, error)
+	Put(ctx context.Context, id string, metadata *model.Metadata, expectedVersion int64) (*model.Metadata, error)
+	PutBatch(ctx context.Context, items []repository.BatchPut) ([]*model.Metadata, error)
+	List(ctx context.Context, q repository.ListQuery) ([]*model.Metadata, string, error)
+	Search(ctx context.Context, q repository.SearchQuery) ([]*model.Metadata, string, error)
+	ListRevisions(ctx context.Context, q repository.RevisionQuery) ([]*model.Revision, string, error)
+	GetRevision(ctx context.Context, id string, version int64) (*model.Revision, error)
+	GetRevisionAt(ctx context.Context, id string, t time.Time) (*model.Revision, error)
+	Purge(ctx context.Context, before time.Time) (int, error)
+	ResolveExternalID(ctx context.Context, namespace, externalID string) (string, error)
+}
+
+// Run runs the conformance suite. newRepository must return an empty repository for every call.
+func Run(t *testing.T, newRepository func(t *testing.T) Repository) {
+	t.Run("NotFound", func(t *testing.T) {
+		r := newRepository(t)
+		if _, err := r.Get(context.Background(), "missing"); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("Get of a missing movie: got error %v, want %v", err, repository.ErrNotFound)
+		}
+		if _, err := r.GetForUpdate(context.Background(), "missing"); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("GetForUpdate of a missing movie: got error %v, want %v", err, repository.ErrNotFound)
+		}
+	})
+	t.Run("PutGet", func(t *testing.T) {
+		r := newRepository(t)
+		want := mustPut(t, r, metadata("1", "The Movie"), repository.AnyVersion)
+		if want.Version != 1 || want.UpdatedAt.IsZero() {
+			t.Fatalf("Put: got version %d updated at %v, want version 1 and an update time", want.Version, want.UpdatedAt)
+		}
+		got := mustGet(t, r, want.ID)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Get: got %+v, want %+v", got, want)
+		}
+	})
+	t.Run("IDFromKey", func(t *testing.T) {
+		r := newRepository(t)
+		if _, err := r.Put(context.Background(), "1", &model.Metadata{Title: "The Movie"}, repository.AnyVersion); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		if got := mustGet(t, r, "1"); got.ID != "1" {
+			t.Fatalf("Get: got id %q, want the id metadata was put with", got.ID)
+		}
+	})
+	t.Run("NoDetails", func(t *testing.T) {
+		r := newRepository(t)
+		want := mustPut(t, r, &model.Metadata{ID: "1", Title: "The Movie"}, repository.AnyVersion)
+		if got := mustGet(t, r, "1"); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Get: got %+v, want %+v", got, want)
+		}
+	})
+	t.Run("Overwrite", func(t *testing.T) {
+		r := newRepository(t)
+		mustPut(t, r, metadata("1", "Old"), repository.AnyVersion)
+		m := metadata("1", "New")
+		m.Genres = []string{"Comedy"}
+		m.Credits = m.Credits[1:]
+		want := mustPut(t, r, m, repository.AnyVersion)
+		if want.Version != 2 {
+			t.Fatalf("Put over existing metadata: got version %d, want 2", want.Version)
+		}
+		if got := mustGet(t, r, "1"); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Get after overwrite: got %+v, want %+v", got, want)
+		}
+	})
+	t.Run("NoAliasing", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+		m := metadata("1", "The Movie")
+		stored, err := r.Put(ctx, m.ID, m, repository.AnyVersion)
+		if err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		m.Title = "Changed after put"
+		m.Genres[0] = "Changed after put"
+		want := stored.Clone()
+		stored.Credits[0].Name = "Changed after put"
+		got := mustGet(t, r, "1")
+		got.Title = "Changed after get"
+		got.Credits[0].Name = "Changed after get"
+		if got := mustGet(t, r, "1"); !reflect.DeepEqual(got, want) {
+			t.Fatalf("stored metadata changed through caller values: %+v", got)
+		}
+	})
+	t.Run("Concurrency", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+		const n = 20
+		var wg sync.WaitGroup
+		errs := make(chan error, 2*n)
+		for i := range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				id := fmt.Sprint(i)
+				if _, err := r.Put(ctx, id, metadata(id, "Movie "+id), repository.AnyVersion); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := r.Get(ctx, id); err != nil {
+					errs <- err
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("concurrent Put/Get: %v", err)
+		}
+		for i := range n {
+			id := fmt.Sprint(i)
+			if got := mustGet(t, r, id); got.Title != "Movie "+id {
+				t.Errorf("Get %s: got title %q", id, got.Title)
+			}
+		}
+	})
+	t.Run("PutBatch", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+		mustPut(t, r, metadata("1", "The Movie"), 0)
+		stored, err := r.PutBatch(ctx, []repository.BatchPut{
+			{Metadata: metadata("2", "Another Movie"), ExpectedVersion: 0},
+			{Metadata: metadata("1", "Second"), ExpectedVersion: 1},
+			{Metadata: metadata("1", "Third"), ExpectedVersion: repository.AnyVersion},
+		})
+		if err != nil {
+			t.Fatalf("PutBatch: %v", err)
+		}
+		var versions []int64
+		for _, m := range stored {
+			versions = append(versions, m.Version)
+		}
+		if !slices.Equal(versions, []int64{1, 2, 3}) {
+			t.Fatalf("PutBatch: got versions %v, want [1 2 3]", versions)
+		}
+		if got := mustGet(t, r, "1"); !reflect.DeepEqual(got, stored[2]) {
+			t.Errorf("Get after PutBatch: got %+v, want %+v", got, stored[2])
+		}
+		if got := listRevisions(t, r, repository.RevisionQuery{MovieID: "1", PageSize: 10}); !slices.Equal(got, []int64{3, 2, 1}) {
+			t.Errorf("ListRevisions after PutBatch: got versions %v, want [3 2 1]", got)
+		}
+		_, err = r.PutBatch(ctx, []repository.BatchPut{
+			{Metadata: metadata("3", "Rolled back"), ExpectedVersion: 0},
+			{Metadata: metadata("1", "Stale"), ExpectedVersion: 1},
+		})
+		var batchErr *repository.BatchError
+		if !errors.As(err, &batchErr) || batchErr.Index != 1 || !errors.Is(err, repository.ErrVersionMismatch) {
+			t.Fatalf("PutBatch with a stale item: got error %v, want a version mismatch of item 1", err)
+		}
+		if _, err := r.Get(ctx, "3"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Get of a movie of a failed batch: got error %v, want %v", err, repository.ErrNotFound)
+		}
+		if got := mustGet(t, r, "1"); got.Version != 3 {
+			t.Errorf("Get after a failed batch: got version %d, want 3", got.Version)
+		}
+		if res, err := r.PutBatch(ctx, nil); err != nil || len(res) != 0 {
+			t.Errorf("PutBatch without items: got %v, error %v", res, err)
+		}
+	})
+	t.Run("ExpectedVersion", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+		if _, err := r.Put(ctx, "1", metadata("1", "The Movie"), 1); !errors.Is(err, repository.ErrVersionMismatch) {
+			t.Fatalf("Put of a missing movie expecting version 1: got error %v, want %v", err, repository.ErrVersionMismatch)
+		}
+		v1 := mustPut(t, r, metadata("1", "The Movie"), 0)
+		if _, err := r.Put(ctx, "1", metadata("1", "Created twice"), 0); !errors.Is(err, repository.ErrVersionMismatch) {
+			t.Fatalf("Put of an existing movie expecting version 0: got error %v, want %v", err, repository.ErrVersionMismatch)
+		}
+		v2 := mustPut(t, r, metadata("1", "Second"), v1.Version)
+		if v2.Version != v1.Version+1 || v2.UpdatedAt.Before(v1.UpdatedAt) {
+			t.Fatalf("Put expecting version %d: got version %d updated at %v after %v", v1.Version, v2.Version, v2.UpdatedAt, v1.UpdatedAt)
+		}
+		if _, err := r.Put(ctx, "1", metadata("1", "Lost update"), v1.Version); !errors.Is(err, repository.ErrVersionMismatch) {
+			t.Fatalf("Put expecting a stale version: got error %v, want %v", err, repository.ErrVersionMismatch)
+		}
+		if got := mustGet(t, r, "1"); !reflect.DeepEqual(got, v2) {
+			t.Fatalf("Get after a failed conditional Put: got %+v, want %+v", got, v2)
+		}
+	})
+	t.Run("ConcurrentConditionalPuts", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+		v1 := mustPut(t, r, metadata("1", "The Movie"), repository.AnyVersion)
+		const n = 10
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		written := 0
+		for i := range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := r.Put(ctx, "1", metadata("1", fmt.Sprint("Writer ", i)), v1.Version)
+				if err != nil && !errors.Is(err, repository.ErrVersionMismatch) {
+					t.Errorf("concurrent Put: %v", err)
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if err == nil {
+					written++
+				}
+			}()
+		}
+		wg.Wait()
+		if written != 1 {
+			t.Fatalf("concurrent Puts expecting the same version: %d succeeded, want 1", written)
+		}
+		if got := mustGet(t, r, "1"); got.Version != v1.Version+1 {
+			t.Fatalf("Get after concurrent Puts: got version %d, want %d", got.Version, v1.Version+1)
+		}
+	})
+	t.Run("Revisions", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+		m := metadata("1", "The Movie")
+		m.UpdatedBy = "alice"
+		v1 := mustPut(t, r, m, repository.AnyVersion)
+		// Revisions are timed in microseconds, so they are apart.
+		time.Sleep(time.Millisecond)
+		m.UpdatedBy, m.Description = "bob", "A better description."
+		v2 := mustPut(t, r, m, v1.Version)
+		if _, err := r.Put(ctx, "1", metadata("1", "Lost update"), v1.Version); !errors.Is(err, repository.ErrVersionMismatch) {
+			t.Fatalf("Put expecting a stale version: got error %v, want %v", err, repository.ErrVersionMismatch)
+		}
+		time.Sleep(time.Millisecond)
+		m.UpdatedBy, m.Genres = "carol", []string{"Drama"}
+		v3 := mustPut(t, r, m, repository.AnyVersion)
+		mustPut(t, r, metadata("2", "Another Movie"), repository.AnyVersion)
+
+		for _, size := range []int{1, 2, 10} {
+			if got := listRevisions(t, r, repository.RevisionQuery{MovieID: "1", PageSize: size}); !slices.Equal(got, []int64{3, 2, 1}) {
+				t.Errorf("ListRevisions with page size %d: got versions %v, want [3 2 1]", size, got)
+			}
+		}
+		if got := listRevisions(t, r, repository.RevisionQuery{MovieID: "missing", PageSize: 10}); len(got) != 0 {
+			t.Errorf("ListRevisions of a missing movie: got versions %v, want none", got)
+		}
+		rev, err := r.GetRevision(ctx, "1", 2)
+		if err != nil {
+			t.Fatalf("GetRevision: %v", err)
+		}
+		want := &model.Revision{
+			MovieID:  "1",
+			Version:  2,
+			Editor:   "bob",
+			Time:     v2.UpdatedAt,
+			Changes:  []model.FieldChange{{Field: "description", Old: "A movie.", New: "A better description."}},
+			Metadata: *v2,
+		}
+		if !reflect.DeepEqual(rev, want) {
+			t.Fatalf("GetRevision: got %+v, want %+v", rev, want)
+		}
+		if rev, err := r.GetRevision(ctx, "1", 1); err != nil || len(rev.Changes) == 0 || rev.Changes[0].Field != "title" || rev.Changes[0].New != "The Movie" {
+			t.Fatalf("GetRevision of the first version: got %+v, error %v, want changes from empty fields", rev, err)
+		}
+		if _, err := r.GetRevision(ctx, "1", 4); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("GetRevision of a missing version: got error %v, want %v", err, repository.ErrNotFound)
+		}
+		tests := []struct {
+			at   time.Time
+			want *model.Metadata
+		}{
+			{v1.UpdatedAt, v1},
+			{v2.UpdatedAt.Add(time.Microsecond), v2},
+			{v3.UpdatedAt.Add(time.Hour), v3},
+		}
+		for _, tt := range tests {
+			rev, err := r.GetRevisionAt(ctx, "1", tt.at)
+			if err != nil {
+				t.Fatalf("GetRevisionAt %v: %v", tt.at, err)
+			}
+			if !reflect.DeepEqual(&rev.Metadata, tt.want) {
+				t.Errorf("GetRevisionAt %v: got %+v, want %+v", tt.at, rev.Metadata, tt.want)
+			}
+		}
+		if _, err := r.GetRevisionAt(ctx, "1", v1.UpdatedAt.Add(-time.Microsecond)); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("GetRevisionAt before the first revision: got error %v, want %v", err, repository.ErrNotFound)
+		}
+	})
+	t.Run("Tombstones", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+		putAll(t, r, []*model.Metadata{
+			{ID: "1", Title: "Dark City"},
+			{ID: "2", Title: "Dark Star"},
+			{ID: "3", Title: "The Dark Knight"},
+		})
+		deletedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		m := mustGet(t, r, "2")
+		m.Tombstone = &model.Tombstone{DeletedAt: deletedAt, Reason: "Duplicate of 1"}
+		deleted := mustPut(t, r, m, m.Version)
+		if got := mustGet(t, r, "2"); !reflect.DeepEqual(got, deleted) {
+			t.Fatalf("Get of a deleted movie: got %+v, want %+v", got, deleted)
+		}
+		if got, err := r.GetForUpdate(ctx, "2"); err != nil || !reflect.DeepEqual(got, deleted) {
+			t.Fatalf("GetForUpdate of a deleted movie: got %+v, error %v, want %+v", got, err, deleted)
+		}
+		if got := listAll(t, r, repository.ListQuery{SortBy: repository.SortByID, PageSize: 1}); !slices.Equal(got, []string{"1", "3"}) {
+			t.Errorf("List with a deleted movie: got ids %v, want [1 3]", got)
+		}
+		got := searchAll(t, r, repository.SearchQuery{Query: "dark", PageSize: 1})
+		if slices.Sort(got); !slices.Equal(got, []string{"1", "3"}) {
+			t.Errorf("Search with a deleted movie: got ids %v, want [1 3]", got)
+		}
+		if n, err := r.Purge(ctx, deletedAt); err != nil || n != 0 {
+			t.Fatalf("Purge of movies deleted before the deletion: got %d, error %v, want 0", n, err)
+		}
+		if n, err := r.Purge(ctx, deletedAt.Add(time.Microsecond)); err != nil || n != 1 {
+			t.Fatalf("Purge of movies deleted after the deletion: got %d, error %v, want 1", n, err)
+		}
+		if _, err := r.Get(ctx, "2"); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("Get of a purged movie: got error %v, want %v", err, repository.ErrNotFound)
+		}
+		if got := listRevisions(t, r, repository.RevisionQuery{MovieID: "2", PageSize: 10}); len(got) != 0 {
+			t.Errorf("ListRevisions of a purged movie: got versions %v, want none", got)
+		}
+		if recreated := mustPut(t, r, &model.Metadata{ID: "2", Title: "Dark Star"}, 0); recreated.Version != 1 {
+			t.Errorf("Put of a purged movie: got version %d, want 1", recreated.Version)
+		}
+		if got := mustGet(t, r, "1"); got.Tombstone != nil {
+			t.Errorf("Purge changed a movie that is not deleted: %+v", got)
+		}
+	})
+	t.Run("ExternalIDs", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+		alien := mustPut(t, r, &model.Metadata{ID: "1", Title: "Alien", ExternalIDs: map[string]string{"imdb": "tt0078748", "tmdb": "348"}}, 0)
+		if got := mustGet(t, r, "1"); !reflect.DeepEqual(got, alien) {
+			t.Fatalf("Get: got %+v, want %+v", got, alien)
+		}
+		if id, err := r.ResolveExternalID(ctx, "tmdb", "348"); err != nil || id != "1" {
+			t.Fatalf("ResolveExternalID: got %q, error %v, want 1", id, err)
+		}
+		if _, err := r.ResolveExternalID(ctx, "imdb", "348"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("ResolveExternalID in another namespace: got error %v, want %v", err, repository.ErrNotFound)
+		}
+		_, err := r.Put(ctx, "2", &model.Metadata{ID: "2", Title: "Aliens", ExternalIDs: map[string]string{"imdb": "tt0078748"}}, 0)
+		if !errors.Is(err, repository.ErrDuplicateExternalID) {
+			t.Fatalf("Put with an external id of another movie: got error %v, want %v", err, repository.ErrDuplicateExternalID)
+		}
+		if _, err := r.Get(ctx, "2"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Get after a failed Put: got error %v, want %v", err, repository.ErrNotFound)
+		}
+		_, err = r.PutBatch(ctx, []repository.BatchPut{
+			{Metadata: &model.Metadata{ID: "2", Title: "Aliens", ExternalIDs: map[string]string{"imdb": "tt0090605"}}, ExpectedVersion: 0},
+			{Metadata: &model.Metadata{ID: "3", Title: "Alien 3", ExternalIDs: map[string]string{"imdb": "tt0090605"}}, ExpectedVersion: 0},
+		})
+		var batchErr *repository.BatchError
+		if !errors.As(err, &batchErr) || batchErr.Index != 1 || !errors.Is(err, repository.ErrDuplicateExternalID) {
+			t.Fatalf("PutBatch assigning an external id twice: got error %v, want a duplicate external id of item 1", err)
+		}
+		// Moving an id to another movie in the same batch succeeds.
+		alien.ExternalIDs = map[string]string{"imdb": "tt0078748"}
+		if _, err := r.PutBatch(ctx, []repository.BatchPut{
+			{Metadata: alien, ExpectedVersion: alien.Version},
+			{Metadata: &model.Metadata{ID: "2", Title: "Alien", ExternalIDs: map[string]string{"tmdb": "348"}}, ExpectedVersion: 0},
+		}); err != nil {
+			t.Fatalf("PutBatch moving an external id: %v", err)
+		}
+		if id, err := r.ResolveExternalID(ctx, "tmdb", "348"); err != nil || id != "2" {
+			t.Fatalf("ResolveExternalID of a moved id: got %q, error %v, want 2", id, err)
+		}
+		// External ids differing in case are distinct.
+		mustPut(t, r, &model.Metadata{ID: "3", Title: "Alien", ExternalIDs: map[string]string{"imdb": "TT0078748"}}, 0)
+		if id, err := r.ResolveExternalID(ctx, "imdb", "tt0078748"); err != nil || id != "1" {
+			t.Fatalf("ResolveExternalID of an id differing in case: got %q, error %v, want 1", id, err)
+		}
+
+		// Deleted movies keep their external ids, but release them to other movies.
+		m := mustGet(t, r, "2")
+		m.Tombstone = &model.Tombstone{DeletedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+		deleted := mustPut(t, r, m, m.Version)
+		if got := mustGet(t, r, "2"); !reflect.DeepEqual(got.ExternalIDs, m.ExternalIDs) {
+			t.Fatalf("Get of a deleted movie: got external ids %v, want %v", got.ExternalIDs, m.ExternalIDs)
+		}
+		if _, err := r.ResolveExternalID(ctx, "tmdb", "348"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("ResolveExternalID of a deleted movie: got error %v, want %v", err, repository.ErrNotFound)
+		}
+		mustPut(t, r, &model.Metadata{ID: "4", Title: "Alien", ExternalIDs: map[string]string{"tmdb": "348"}}, 0)
+		if id, err := r.ResolveExternalID(ctx, "tmdb", "348"); err != nil || id != "4" {
+			t.Fatalf("ResolveExternalID of a replacement: got %q, error %v, want 4", id, err)
+		}
+		deleted.Tombstone = nil
+		if _, err := r.Put(ctx, "2", deleted, deleted.Version); !errors.Is(err, repository.ErrDuplicateExternalID) {
+			t.Fatalf("Put restoring a movie with a claimed external id: got error %v, want %v", err, repository.ErrDuplicateExternalID)
+		}
+		if n, err := r.Purge(ctx, m.Tombstone.DeletedAt.Add(time.Microsecond)); err != nil || n != 1 {
+			t.Fatalf("Purge: got %d, error %v, want 1", n, err)
+		}
+		if id, err := r.ResolveExternalID(ctx, "tmdb", "348"); err != nil || id != "4" {
+			t.Errorf("ResolveExternalID after a purge: got %q, error %v, want 4", id, err)
+		}
+		mustPut(t, r, &model.Metadata{ID: "2", Title: "Aliens", ExternalIDs: map[string]string{"imdb": "tt0090605"}}, 0)
+	})
+	t.Run("Localizations", func(t *testing.T) {
+		r := newRepository(t)
+		m := mustPut(t, r, &model.Metadata{ID: "1", Title: "City of God", Description: "Two boys grow up in Rio.", Localizations: map[string]model.Localization{
+			"pt":    {Title: "Cidade de Deus", Description: "Dois meninos crescem no Rio."},
+			"pt-BR": {Title: "Cidade de Deus"},
+		}}, 0)
+		if got := mustGet(t, r, "1"); !reflect.DeepEqual(got, m) {
+			t.Fatalf("Get: got %+v, want %+v", got, m)
+		}
+		m.Localizations = map[string]model.Localization{"fr": {Title: "La Cité de Dieu"}}
+		m = mustPut(t, r, m, m.Version)
+		if got := mustGet(t, r, "1"); !reflect.DeepEqual(got, m) {
+			t.Fatalf("Get after replacing localizations: got %+v, want %+v", got, m)
+		}
+	})
+	t.Run("Assets", func(t *testing.T) {
+		r := newRepository(t)
+		poster := strings.Repeat("a", 64)
+		still := strings.Repeat("b", 64)
+		m := mustPut(t, r, &model.Metadata{ID: "1", Title: "Metropolis", Assets: []model.AssetRef{
+			{Kind: model.AssetPoster, Hash: poster},
+			{Kind: model.AssetStill, Hash: still},
+			{Kind: model.AssetPoster, Hash: still},
+		}}, 0)
+		if got := mustGet(t, r, "1"); !reflect.DeepEqual(got, m) {
+			t.Fatalf("Get: got %+v, want %+v", got, m)
+		}
+		m.Assets = m.Assets[1:]
+		m = mustPut(t, r, m, m.Version)
+		if got := mustGet(t, r, "1"); !reflect.DeepEqual(got, m) {
+			t.Fatalf("Get after removing an asset: got %+v, want %+v", got, m)
+		}
+	})
+	t.Run("List", func(t *testing.T) {
+		r := newRepository(t)
+		putAll(t, r, []*model.Metadata{
+			{ID: "1", Title: "Casablanca", Director: "Curtiz"},
+			{ID: "2", Title: "Alien", Director: "Scott"},
+			{ID: "3", Title: "Brazil", Director: "Gilliam"},
+			{ID: "4", Title: "Alien", Director: "Fincher"},
+			{ID: "5", Title: "Downfall", Director: "Hirschbiegel"},
+		})
+		tests := []struct {
+			q    repository.ListQuery
+			want []string
+		}{
+			{repository.ListQuery{SortBy: repository.SortByID}, []string{"1", "2", "3", "4", "5"}},
+			{repository.ListQuery{SortBy: repository.SortByTitle}, []string{"2", "4", "3", "1", "5"}},
+			{repository.ListQuery{SortBy: repository.SortByTitle, Descending: true}, []string{"5", "1", "3", "4", "2"}},
+			{repository.ListQuery{SortBy: repository.SortByDirector}, []string{"1", "4", "3", "5", "2"}},
+		}
+		for _, tt := range tests {
+			for _, size := range []int{1, 2, 5, 10} {
+				tt.q.PageSize = size
+				if got := listAll(t, r, tt.q); !slices.Equal(got, tt.want) {
+					t.Errorf("List %+v: got ids %v, want %v", tt.q, got, tt.want)
+				}
+			}
+		}
+		ctx := context.Background()
+		_, cursor, err := r.List(ctx, repository.ListQuery{SortBy: repository.SortByTitle, PageSize: 1})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		for _, c := range []string{cursor, "not a cursor"} {
+			q := repository.ListQuery{SortBy: repository.SortByID, PageSize: 1, Cursor: c}
+			if _, _, err := r.List(ctx, q); !errors.Is(err, repository.ErrInvalidCursor) {
+				t.Errorf("List with cursor %q of another listing: got error %v, want %v", c, err, repository.ErrInvalidCursor)
+			}
+		}
+	})
+	t.Run("Search", func(t *testing.T) {
+		r := newRepository(t)
+		putAll(t, r, []*model.Metadata{
+			{ID: "1", Title: "The Dark Knight", Director: "Christopher Nolan", Description: "Batman faces the Joker."},
+			{ID: "2", Title: "Dark City", Director: "Alex Proyas", Description: "A man wakes up with no memory."},
+			{ID: "3", Title: "Inception", Director: "Christopher Nolan", Description: "A thief steals secrets through dream-sharing."},
+			{ID: "4", Title: "Up", Director: "Pete Docter", Description: "An old man flies his house to South America."},
+			{ID: "5", Title: "The Thing", Director: "John Carpenter", Description: "Researchers in Antarctica meet a shapeshifter."},
+		})
+		tests := []struct {
+			query string
+			want  []string
+		}{
+			{"dark", []string{"1", "2"}},
+			{"NOLAN dark", []string{"1"}},
+			{"Christopher", []string{"1", "3"}},
+			{"thief", []string{"3"}},
+			{"dream", []string{"3"}},
+			{"western", nil},
+			{"", nil},
+			// Short words and common words such as "the" are matched too.
+			{"Up", []string{"2", "4"}},
+			{"the", []string{"1", "5"}},
+			{"The Thing", []string{"5"}},
+			{"up old", []string{"4"}},
+		}
+		for _, tt := range tests {
+			for _, size := range []int{1, 10} {
+				got := searchAll(t, r, repository.SearchQuery{Query: tt.query, PageSize: size})
+				slices.Sort(got)
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("Search %q with page size %d: got ids %v, want %v", tt.query, size, got, tt.want)
+				}
+			}
+		}
+		ctx := context.Background()
+		_, cursor, err := r.Search(ctx, repository.SearchQuery{Query: "Dark", PageSize: 1})
+		if err != nil || cursor == "" {
+			t.Fatalf("Search: got cursor %q, error %v, want a cursor", cursor, err)
+		}
+		if _, _, err := r.Search(ctx, repository.SearchQuery{Query: " DARK ", PageSize: 1, Cursor: cursor}); err != nil {
+			t.Errorf("Search with the cursor of the same terms: %v", err)
+		}
+		for _, c := range []string{cursor, "not a cursor"} {
+			q := repository.SearchQuery{Query: "nolan", PageSize: 1, Cursor: c}
+			if _, _, err := r.Search(ctx, q); !errors.Is(err, repository.ErrInvalidCursor) {
+				t.Errorf("Search with cursor %q of another search: got error %v, want %v", c, err, repository.ErrInvalidCursor)
+			}
+		}
+		putAll(t, r, []*model.Metadata{{ID: "1", Title: "Batman Begins", Director: "Christopher Nolan"}})
+		if got := searchAll(t, r, repository.SearchQuery{Query: "dark", PageSize: 10}); !slices.Equal(got, []string{"2"}) {
+			t.Errorf("Search after an overwrite: got ids %v, want [2]", got)
+		}
+		if got := searchAll(t, r, repository.SearchQuery{Query: "batman", PageSize: 10}); !slices.Equal(got, []string{"1"}) {
+			t.Errorf("Search after an overwrite: got ids %v, want [1]", got)
+		}
+	})
+	t.Run("SearchOrder", func(t *testing.T) {
+		r := newRepository(t)
+		putAll(t, r, []*model.Metadata{
+			{ID: "1", Title: "Dark Star", Director: "John Carpenter", Description: "Astronauts drift through space."},
+			{ID: "2", Title: "Dark City", Director: "Alex Proyas", Description: "A dark city where the dark never ends."},
+			{ID: "3", Title: "Alien", Director: "Ridley Scott", Description: "A crew meets a creature in space."},
+			{ID: "4", Title: "Inception", Director: "Christopher Nolan", Description: "A thief steals secrets."},
+		})
+		// The movie with the most occurrences of the terms is the most relevant.
+		for _, size := range []int{1, 10} {
+			if got := searchAll(t, r, repository.SearchQuery{Query: "dark", PageSize: size}); !slices.Equal(got, []string{"2", "1"}) {
+				t.Errorf("Search with page size %d: got ids %v, want [2 1]", size, got)
+			}
+		}
+	})
+	t.Run("ContextCancellation", func(t *testing.T) {
+		r := newRepository(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := r.Put(ctx, "1", metadata("1", "The Movie"), repository.AnyVersion); !errors.Is(err, context.Canceled) {
+			t.Errorf("Put with a cancelled context: got error %v, want %v", err, context.Canceled)
+		}
+		if _, err := r.Get(ctx, "1"); !errors.Is(err, context.Canceled) {
+			t.Errorf("Get with a cancelled context: got error %v, want %v", err, context.Canceled)
+		}
+		if _, err := r.Get(context.Background(), "1"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Put with a cancelled context stored metadata: got error %v, want %v", err, repository.ErrNotFound)
+		}
+	})
+}
+
+func putAll(t *testing.T, r Repository, ms []*model.Metadata) {
+	t.Helper()
+	for _, m := range ms {
+		mustPut(t, r, m, repository.AnyVersion)
+	}
+}
+
+// listAll follows the cursors of a listing and returns the ids of all pages.
+func listAll(t *testing.T, r Repository, q repository.ListQuery) []string {
+	t.Helper()
+	var ids []string
+	for page := 0; ; page++ {
+		res, cursor, err := r.List(context.Background(), q)
+		if err != nil {
+			t.Fatalf("List %+v: %v", q, err)
+		}
+		if len(res) > q.PageSize {
+			t.Fatalf("List %+v: got %d results", q, len(res))
+		}
+		for _, m := range res {
+			ids = append(ids, m.ID)
+		}
+		if cursor == "" {
+			return ids
+		}
+		if page > 100 {
+			t.Fatalf("List %+v: cursors do not end", q)
+		}
+		q.Cursor = cursor
+	}
+}
+
+// listRevisions follows the cursors of a revision listing and returns the versions of all pages.
+func listRevisions(t *testing.T, r Repository, q repository.RevisionQuery) []int64 {
+	t.Helper()
+	var versions []int64
+	for page := 0; ; page++ {
+		res, cursor, err := r.ListRevisions(context.Background(), q)
+		if err != nil {
+			t.Fatalf("ListRevisions %+v: %v", q, err)
+		}
+		for _, rev := range res {
+			versions = append(versions, rev.Version)
+		}
+		if cursor == "" {
+			return versions
+		}
+		if page > 100 {
+			t.Fatalf("ListRevisions %+v: cursors do not end", q)
+		}
+		q.Cursor = cursor
+	}
+}
+
+// searchAll follows the cursors of a search and returns the ids of all pages.
+func searchAll(t *testing.T, r Repository, q repository.SearchQuery) []string {
+	t.Helper()
+	var ids []string
+	for page := 0; ; page++ {
+		res, cursor, err := r.Search(context.Background(), q)
+		if err != nil {
+			t.Fatalf("Search %+v: %v", q, err)
+		}
+		for _, m := range res {
+			ids = append(ids, m.ID)
+		}
+		if cursor == "" {
+			return ids
+		}
+		if page > 100 {
+			t.Fatalf("Search %+v: cursors do not end", q)
+		}
+		q.Cursor = cursor
+	}
+}
+
+func metadata(id, title string) *model.Metadata {
+	return &model.Metadata{
+		ID:          id,
+		Title:       title,
+		Description: "A movie.",
+		Director:    "Someone",
+		Genres:      []string{"Drama", "Crime"},
+		Credits: []model.Credit{
+			{Name: "Someone", Role: model.RoleDirector},
+			{Name: "Someone Else", Role: model.RoleActor, Character: "The Hero"},
+			{Name: "Someone Else", Role: model.RoleWriter},
+		},
+		ReleaseDate:    "1999-12-31",
+		RuntimeMinutes: 121,
+		Language:       "en",
+		Country:        "US",
+		AgeRating:      "PG-13",
+	}
+}
+
+// mustPut puts metadata and checks that it is stored as given, apart from its version and update time.
+func mustPut(t *testing.T, r Repository, m *model.Metadata, expectedVersion int64) *model.Metadata {
+	t.Helper()
+	stored, err := r.Put(context.Background(), m.ID, m, expectedVersion)
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	unversioned := stored.Clone()
+	unversioned.Version, unversioned.UpdatedAt = m.Version, m.UpdatedAt
+	if !reflect.DeepEqual(unversioned, m) {
+		t.Fatalf("Put: stored %+v, want %+v", stored, m)
+	}
+	return stored
+}
+
+func mustGet(t *testing.T, r Repository, id string) *model.Metadata {
+	t.Helper()
+	m, err := r.Get(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Get %s: %v", id, err)
+	}
+	return m
+}
@@ -0,0 +1,8 @@
+CREATE TABLE movies (
+    id TEXT NOT NULL PRIMARY KEY,
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    director TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX movies_title ON movies (title);
+CREATE INDEX movies_director ON movies (director);
@@ -0,0 +1,5 @@
+DROP TRIGGER movies_search_after_insert;
+DROP TRIGGER movies_search_after_update;
+DROP TRIGGER movies_search_before_delete;
+DROP TRIGGER movies_search_before_update;
+DROP TABLE movies_search;
@@ -0,0 +1,7 @@
+-- Full-text index of movies, kept in sync with the movies table by triggers.
+CREATE VIRTUAL TABLE movies_search USING fts4(content="movies", title, director, description, tokenize=unicode61);
+CREATE TRIGGER movies_search_before_update BEFORE UPDATE ON movies BEGIN DELETE FROM movies_search WHERE docid = old.rowid; END;
+CREATE TRIGGER movies_search_before_delete BEFORE DELETE ON movies BEGIN DELETE FROM movies_search WHERE docid = old.rowid; END;
+CREATE TRIGGER movies_search_after_update AFTER UPDATE ON movies BEGIN INSERT INTO movies_search (docid, title, director, description) VALUES (new.rowid, new.title, new.director, new.description); END;
+CREATE TRIGGER movies_search_after_insert AFTER INSERT ON movies BEGIN INSERT INTO movies_search (docid, title, director, description) VALUES (new.rowid, new.title, new.director, new.description); END;
+INSERT INTO movies_search (movies_search) VALUES ('rebuild');
@@ -0,0 +1,8 @@
+DROP TABLE movie_credits;
+DROP TABLE movie_genres;
+DROP TABLE genres;
+ALTER TABLE movies DROP COLUMN age_rating;
+ALTER TABLE movies DROP COLUMN country;
+ALTER TABLE movies DROP COLUMN language;
+ALTER TABLE movies DROP COLUMN runtime_minutes;
+ALTER TABLE movies DROP COLUMN release_date;
@@ -0,0 +1,27 @@
+ALTER TABLE movies ADD COLUMN release_date TEXT NOT NULL DEFAULT '';
+ALTER TABLE movies ADD COLUMN runtime_minutes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE movies ADD COLUMN language TEXT NOT NULL DEFAULT '';
+ALTER TABLE movies ADD COLUMN country TEXT NOT NULL DEFAULT '';
+ALTER TABLE movies ADD COLUMN age_rating TEXT NOT NULL DEFAULT '';
+CREATE TABLE genres (
+    id INTEGER NOT NULL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE COLLATE NOCASE
+);
+-- Genres of movies, in the order they are listed.
+CREATE TABLE movie_genres (
+    movie_id TEXT NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    genre_id INTEGER NOT NULL REFERENCES genres (id),
+    position INTEGER NOT NULL,
+    PRIMARY KEY (movie_id, genre_id)
+);
+CREATE INDEX movie_genres_genre ON movie_genres (genre_id);
+-- Cast and crew of movies, in billing order.
+CREATE TABLE movie_credits (
+    movie_id TEXT NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    role TEXT NOT NULL,
+    character_name TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (movie_id, position)
+);
+CREATE INDEX movie_credits_name ON movie_credits (name, role);
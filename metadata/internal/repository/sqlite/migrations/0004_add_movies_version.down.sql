@@ -0,0 +1,2 @@
+ALTER TABLE movies DROP COLUMN updated_at;
+ALTER TABLE movies DROP COLUMN version;
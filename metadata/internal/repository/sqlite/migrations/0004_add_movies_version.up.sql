@@ -0,0 +1,4 @@
+-- Existing movies start at version 1, as version 0 means that a movie does not exist.
+ALTER TABLE movies ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+-- Unix time in microseconds, 0 if unknown.
+ALTER TABLE movies ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0;
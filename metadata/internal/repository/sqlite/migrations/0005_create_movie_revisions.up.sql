@@ -0,0 +1,35 @@
+ALTER TABLE movies ADD COLUMN updated_by TEXT NOT NULL DEFAULT '';
+-- Append-only change history of movies, one revision per written version. Times are unix
+-- microseconds, changes and snapshots are JSON.
+CREATE TABLE movie_revisions (
+    movie_id TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    editor TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL,
+    changes TEXT NOT NULL,
+    snapshot TEXT NOT NULL,
+    PRIMARY KEY (movie_id, version)
+);
+CREATE INDEX movie_revisions_time ON movie_revisions (movie_id, created_at);
+-- Existing movies get a revision of their current version, so that their history starts with
+-- their state when revisions were introduced.
+INSERT INTO movie_revisions (movie_id, version, editor, created_at, changes, snapshot)
+SELECT id, version, updated_by, updated_at, '[]', json_object(
+    'id', id,
+    'title', title,
+    'description', description,
+    'director', director,
+    'genres', json(NULLIF((SELECT json_group_array(genres.name ORDER BY movie_genres.position)
+        FROM movie_genres JOIN genres ON genres.id = movie_genres.genre_id WHERE movie_genres.movie_id = movies.id), '[]')),
+    'credits', json(NULLIF((SELECT json_group_array(json_object('name', name, 'role', role, 'character', character_name) ORDER BY position)
+        FROM movie_credits WHERE movie_credits.movie_id = movies.id), '[]')),
+    'releaseDate', release_date,
+    'runtimeMinutes', runtime_minutes,
+    'language', language,
+    'country', country,
+    'ageRating', age_rating,
+    'version', version,
+    'updatedAt', CASE WHEN updated_at = 0 THEN '0001-01-01T00:00:00Z'
+        ELSE strftime('%Y-%m-%dT%H:%M:%S', updated_at / 1000000, 'unixepoch') || printf('.%06dZ', updated_at % 1000000) END,
+    'updatedBy', updated_by
+) FROM movies;
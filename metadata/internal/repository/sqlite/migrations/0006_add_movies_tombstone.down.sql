@@ -0,0 +1,3 @@
+DROP INDEX movies_deleted_at;
+ALTER TABLE movies DROP COLUMN delete_reason;
+ALTER TABLE movies DROP COLUMN deleted_at;
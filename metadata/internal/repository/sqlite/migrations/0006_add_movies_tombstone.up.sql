@@ -0,0 +1,4 @@
+-- Deleted movies keep a tombstone until they are purged. deleted_at is in unix microseconds.
+ALTER TABLE movies ADD COLUMN deleted_at INTEGER NULL;
+ALTER TABLE movies ADD COLUMN delete_reason TEXT NOT NULL DEFAULT '';
+CREATE INDEX movies_deleted_at ON movies (deleted_at);
@@ -0,0 +1,8 @@
+-- Ids of movies in external namespaces, each identifying at most one movie in its namespace.
+CREATE TABLE movie_external_ids (
+    movie_id TEXT NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    namespace TEXT NOT NULL,
+    external_id TEXT NOT NULL,
+    PRIMARY KEY (movie_id, namespace),
+    UNIQUE (namespace, external_id)
+);
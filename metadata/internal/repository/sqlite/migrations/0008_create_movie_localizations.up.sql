@@ -0,0 +1,8 @@
+-- Titles and descriptions of movies translated to BCP 47 locales.
+CREATE TABLE movie_localizations (
+    movie_id TEXT NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    locale TEXT NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (movie_id, locale)
+);
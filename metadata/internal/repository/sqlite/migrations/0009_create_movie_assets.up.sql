@@ -0,0 +1,8 @@
+-- Images of movies by the hash of their content, in order.
+CREATE TABLE movie_assets (
+    movie_id TEXT NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    PRIMARY KEY (movie_id, position)
+);
@@ -0,0 +1,11 @@
+CREATE TABLE movie_external_ids_unique (
+    movie_id TEXT NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    namespace TEXT NOT NULL,
+    external_id TEXT NOT NULL,
+    PRIMARY KEY (movie_id, namespace),
+    UNIQUE (namespace, external_id)
+);
+INSERT INTO movie_external_ids_unique (movie_id, namespace, external_id)
+SELECT movie_id, namespace, external_id FROM movie_external_ids;
+DROP TABLE movie_external_ids;
+ALTER TABLE movie_external_ids_unique RENAME TO movie_external_ids;
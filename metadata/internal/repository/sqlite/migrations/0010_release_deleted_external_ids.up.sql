@@ -0,0 +1,15 @@
+-- Deleted movies keep their external ids but release them: claimed is NULL for them, and NULLs
+-- are distinct in unique constraints, so a replacement movie can claim the ids.
+CREATE TABLE movie_external_ids_claimed (
+    movie_id TEXT NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    namespace TEXT NOT NULL,
+    external_id TEXT NOT NULL,
+    claimed INTEGER NULL DEFAULT 1,
+    PRIMARY KEY (movie_id, namespace),
+    UNIQUE (namespace, external_id, claimed)
+);
+INSERT INTO movie_external_ids_claimed (movie_id, namespace, external_id, claimed)
+SELECT e.movie_id, e.namespace, e.external_id, CASE WHEN m.deleted_at IS NULL THEN 1 END
+FROM movie_external_ids e JOIN movies m ON m.id = e.movie_id;
+DROP TABLE movie_external_ids;
+ALTER TABLE movie_external_ids_claimed RENAME TO movie_external_ids;
@@ -0,0 +1,582 @@
+package sqlite
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/meirongdev/movie-microservice/metadata/internal/repository"
+	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+)
+
+// Repository defines a SQLite-based movie metadata repository.
+type Repository struct {
+	db *sql.DB
+}
+
+// New creates a new SQLite-based repository.
+func New(dsn string) (*Repository, error) {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer, so a single connection avoids busy errors.
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+	return &Repository{db}, nil
+}
+
+// columns are the movie columns scanned by query.
+const columns = "movies.id, movies.title, movies.description, movies.director, movies.release_date, movies.runtime_minutes, movies.language, movies.country, movies.age_rating, movies.version, movies.updated_at, movies.updated_by, movies.deleted_at, movies.delete_reason"
+
+// Get retrieves movie metadata for by movie id.
+func (r *Repository) Get(ctx context.Context, id string) (*model.Metadata, error) {
+	res, err := r.query(ctx, "SELECT "+columns+" FROM movies WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return res[0], nil
+}
+
+// GetForUpdate retrieves movie metadata by movie id, to be changed and written back with Put.
+// It is the same as Get, as there are no replicas to lag behind writes.
+func (r *Repository) GetForUpdate(ctx context.Context, id string) (*model.Metadata, error) {
+	return r.Get(ctx, id)
+}
+
+// Put adds movie metadata for a given movie id, replacing existing metadata if its version is the
+// expected one, and returns the metadata as stored.
+func (r *Repository) Put(ctx context.Context, id string, metadata *model.Metadata, expectedVersion int64) (*model.Metadata, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	stored, err := put(ctx, tx, id, metadata, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	return stored, tx.Commit()
+}
+
+// PutBatch writes the metadata of a batch in a single transaction, each as written by Put, and
+// returns the metadata as stored. If an item cannot be written, the batch is rolled back and a
+// *repository.BatchError is returned.
+func (r *Repository) PutBatch(ctx context.Context, items []repository.BatchPut) ([]*model.Metadata, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	res := make([]*model.Metadata, len(items))
+	for i, item := range items {
+		if res[i], err = put(ctx, tx, item.Metadata.ID, item.Metadata, item.ExpectedVersion); err != nil {
+			return nil, &repository.BatchError{Index: i, Err: err}
+		}
+	}
+	return res, tx.Commit()
+}
+
+// put writes movie metadata, its details and its revision in a transaction.
+func put(ctx context.Context, tx *sql.Tx, id string, metadata *model.Metadata, expectedVersion int64) (*model.Metadata, error) {
+	stored := metadata.Clone()
+	stored.ID = id
+	stored.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	if err := writeMovie(ctx, tx, stored, expectedVersion); err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRowContext(ctx, "SELECT version FROM movies WHERE id = ?", id).Scan(&stored.Version); err != nil {
+		return nil, err
+	}
+	if err := writeDetails(ctx, tx, stored); err != nil {
+		return nil, err
+	}
+	// The movie row is locked by the write, so the previous revision is the latest one.
+	var previous *model.Metadata
+	if prev, err := queryRevisions(ctx, tx, "SELECT "+revisionColumns+" FROM movie_revisions WHERE movie_id = ? AND version = ?", id, stored.Version-1); err != nil {
+		return nil, err
+	} else if len(prev) > 0 {
+		previous = &prev[0].Metadata
+	}
+	if err := writeRevision(ctx, tx, stored, model.Diff(previous, stored)); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+const movieColumns = "title, description, director, release_date, runtime_minutes, language, country, age_rating, updated_at, updated_by, deleted_at, delete_reason"
+
+// writeMovie writes the movie row, with a version incremented from the expected one.
+func writeMovie(ctx context.Context, tx *sql.Tx, metadata *model.Metadata, expectedVersion int64) error {
+	var deletedAt sql.NullInt64
+	var deleteReason string
+	if metadata.Tombstone != nil {
+		deletedAt = sql.NullInt64{Int64: metadata.Tombstone.DeletedAt.UnixMicro(), Valid: true}
+		deleteReason = metadata.Tombstone.Reason
+	}
+	args := []any{metadata.Title, metadata.Description, metadata.Director, metadata.ReleaseDate, metadata.RuntimeMinutes,
+		metadata.Language, metadata.Country, metadata.AgeRating, metadata.UpdatedAt.UnixMicro(), metadata.UpdatedBy, deletedAt, deleteReason}
+	switch expectedVersion {
+	case repository.AnyVersion:
+		_, err := tx.ExecContext(ctx, "INSERT INTO movies (id, "+movieColumns+", version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1) "+
+			"ON CONFLICT (id) DO UPDATE SET title = excluded.title, description = excluded.description, "+
+			"director = excluded.director, release_date = excluded.release_date, runtime_minutes = excluded.runtime_minutes, "+
+			"language = excluded.language, country = excluded.country, age_rating = excluded.age_rating, "+
+			"updated_at = excluded.updated_at, updated_by = excluded.updated_by, "+
+			"deleted_at = excluded.deleted_at, delete_reason = excluded.delete_reason, version = version + 1",
+			append([]any{metadata.ID}, args...)...)
+		return err
+	case 0:
+		_, err := tx.ExecContext(ctx, "INSERT INTO movies (id, "+movieColumns+", version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1)",
+			append([]any{metadata.ID}, args...)...)
+		if err != nil && isDuplicateKey(err) {
+			return repository.ErrVersionMismatch
+		}
+		return err
+	default:
+		res, err := tx.ExecContext(ctx, "UPDATE movies SET title = ?, description = ?, director = ?, release_date = ?, runtime_minutes = ?, "+
+			"language = ?, country = ?, age_rating = ?, updated_at = ?, updated_by = ?, "+
+			"deleted_at = ?, delete_reason = ?, version = version + 1 WHERE id = ? AND version = ?",
+			append(args, metadata.ID, expectedVersion)...)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return repository.ErrVersionMismatch
+		}
+		return nil
+	}
+}
+
+// writeDetails replaces the genres, external ids, localizations, assets and credits of a movie.
+func writeDetails(ctx context.Context, tx *sql.Tx, metadata *model.Metadata) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM movie_genres WHERE movie_id = ?", metadata.ID); err != nil {
+		return err
+	}
+	for i, genre := range metadata.Genres {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO genres (name) VALUES (?) ON CONFLICT (name) DO NOTHING", genre); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO movie_genres (movie_id, genre_id, position) SELECT ?, id, ? FROM genres WHERE name = ?", metadata.ID, i, genre); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM movie_external_ids WHERE movie_id = ?", metadata.ID); err != nil {
+		return err
+	}
+	// Deleted movies release their external ids.
+	claimed := sql.NullInt64{Int64: 1, Valid: metadata.Tombstone == nil}
+	for _, namespace := range slices.Sorted(maps.Keys(metadata.ExternalIDs)) {
+		externalID := metadata.ExternalIDs[namespace]
+		_, err := tx.ExecContext(ctx, "INSERT INTO movie_external_ids (movie_id, namespace, external_id, claimed) VALUES (?, ?, ?, ?)", metadata.ID, namespace, externalID, claimed)
+		if err != nil && isDuplicateKey(err) {
+			return fmt.Errorf("%w: %s id %q", repository.ErrDuplicateExternalID, namespace, externalID)
+		} else if err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM movie_localizations WHERE movie_id = ?", metadata.ID); err != nil {
+		return err
+	}
+	for _, locale := range slices.Sorted(maps.Keys(metadata.Localizations)) {
+		l := metadata.Localizations[locale]
+		if _, err := tx.ExecContext(ctx, "INSERT INTO movie_localizations (movie_id, locale, title, description) VALUES (?, ?, ?, ?)", metadata.ID, locale, l.Title, l.Description); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM movie_assets WHERE movie_id = ?", metadata.ID); err != nil {
+		return err
+	}
+	for i, a := range metadata.Assets {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO movie_assets (movie_id, position, kind, hash) VALUES (?, ?, ?, ?)", metadata.ID, i, a.Kind, a.Hash); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM movie_credits WHERE movie_id = ?", metadata.ID); err != nil {
+		return err
+	}
+	if len(metadata.Credits) == 0 {
+		return nil
+	}
+	placeholders := make([]string, len(metadata.Credits))
+	args := make([]any, 0, len(metadata.Credits)*5)
+	for i, c := range metadata.Credits {
+		placeholders[i] = "(?, ?, ?, ?, ?)"
+		args = append(args, metadata.ID, i, c.Name, c.Role, c.Character)
+	}
+	_, err := tx.ExecContext(ctx, "INSERT INTO movie_credits (movie_id, position, name, role, character_name) VALUES "+
+		strings.Join(placeholders, ", "), args...)
+	return err
+}
+
+// ResolveExternalID returns the id of the movie with an external id in a namespace, ignoring
+// deleted movies.
+func (r *Repository) ResolveExternalID(ctx context.Context, namespace, externalID string) (string, error) {
+	var id string
+	err := r.db.QueryRowContext(ctx, "SELECT movie_id FROM movie_external_ids WHERE namespace = ? AND external_id = ? AND claimed IS NOT NULL", namespace, externalID).Scan(&id)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return "", repository.ErrNotFound
+	}
+	return id, err
+}
+
+// ListRevisions returns a page of the revisions of a movie, the latest first, and the cursor of the next page.
+func (r *Repository) ListRevisions(ctx context.Context, q repository.RevisionQuery) ([]*model.Revision, string, error) {
+	cursor, err := repository.DecodeCursor(q.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	query := "SELECT " + revisionColumns + " FROM movie_revisions WHERE movie_id = ?"
+	args := []any{q.MovieID}
+	if cursor.Version != 0 {
+		query += " AND version < ?"
+		args = append(args, cursor.Version)
+	}
+	res, err := queryRevisions(ctx, r.db, query+" ORDER BY version DESC LIMIT ?", append(args, q.PageSize+1)...)
+	if err != nil {
+		return nil, "", err
+	}
+	more := len(res) > q.PageSize
+	if more {
+		res = res[:q.PageSize]
+	}
+	return res, repository.NextRevisionCursor(res, more), nil
+}
+
+// GetRevision retrieves a revision of a movie by the version it wrote.
+func (r *Repository) GetRevision(ctx context.Context, id string, version int64) (*model.Revision, error) {
+	return firstRevision(queryRevisions(ctx, r.db, "SELECT "+revisionColumns+" FROM movie_revisions WHERE movie_id = ? AND version = ?", id, version))
+}
+
+// GetRevisionAt retrieves the latest revision of a movie written at or before a time.
+func (r *Repository) GetRevisionAt(ctx context.Context, id string, t time.Time) (*model.Revision, error) {
+	return firstRevision(queryRevisions(ctx, r.db, "SELECT "+revisionColumns+" FROM movie_revisions "+
+		"WHERE movie_id = ? AND created_at <= ? ORDER BY version DESC LIMIT 1", id, t.UnixMicro()))
+}
+
+// Purge removes the movies deleted before a time, with their change history, and returns their number.
+func (r *Repository) Purge(ctx context.Context, before time.Time) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	for _, table := range []string{"movie_revisions", "movie_genres", "movie_credits", "movie_external_ids", "movie_localizations", "movie_assets"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE movie_id IN (SELECT id FROM movies WHERE deleted_at < ?)", before.UnixMicro()); err != nil {
+			return 0, err
+		}
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM movies WHERE deleted_at < ?", before.UnixMicro())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), tx.Commit()
+}
+
+// List returns a page of movie metadata sorted as requested, and the cursor of the next page.
+func (r *Repository) List(ctx context.Context, q repository.ListQuery) ([]*model.Metadata, string, error) {
+	cursor, err := repository.ListCursor(q)
+	if err != nil {
+		return nil, "", err
+	}
+	order, op := "ASC", ">"
+	if q.Descending {
+		order, op = "DESC", "<"
+	}
+	// Sort fields are column names, validated by the controller.
+	column := string(q.SortBy)
+	query := "SELECT " + columns + " FROM movies WHERE deleted_at IS NULL"
+	var args []any
+	if q.Cursor != "" {
+		query += fmt.Sprintf(" AND (%s, id) %s (?, ?)", column, op)
+		args = append(args, cursor.Value, cursor.ID)
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT ?", column, order, order)
+	args = append(args, q.PageSize+1)
+	res, err := r.query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	more := len(res) > q.PageSize
+	if more {
+		res = res[:q.PageSize]
+	}
+	return res, repository.NextListCursor(q, res, more), nil
+}
+
+// Search returns a page of movie metadata matching all terms of the query using the full-text
+// index, the movies with the most occurrences of the terms first, and the cursor of the next page.
+func (r *Repository) Search(ctx context.Context, q repository.SearchQuery) ([]*model.Metadata, string, error) {
+	cursor, err := repository.SearchCursor(q)
+	if err != nil {
+		return nil, "", err
+	}
+	terms := repository.Tokenize(q.Query)
+	if len(terms) == 0 {
+		return nil, "", nil
+	}
+	// Terms separated by spaces are all required. Quoting keeps them from being read as operators.
+	match := `"` + strings.Join(terms, `" "`) + `"`
+	// FTS4 does not rank matches, so they are ranked here by the hits of matchinfo.
+	type scored struct {
+		id    string
+		score int
+	}
+	var matches []scored
+	if err := r.scanRows(ctx, "SELECT movies.id, matchinfo(movies_search, 'x') FROM movies_search s "+
+		"JOIN movies ON movies.rowid = s.docid WHERE movies_search MATCH ? AND movies.deleted_at IS NULL", []any{match}, func(rows *sql.Rows) error {
+		var m scored
+		var info []byte
+		if err := rows.Scan(&m.id, &info); err != nil {
+			return err
+		}
+		m.score = hits(info)
+		matches = append(matches, m)
+		return nil
+	}); err != nil {
+		return nil, "", err
+	}
+	slices.SortFunc(matches, func(a, b scored) int {
+		return cmp.Or(cmp.Compare(b.score, a.score), cmp.Compare(a.id, b.id))
+	})
+	matches = matches[min(cursor.Offset, len(matches)):]
+	more := len(matches) > q.PageSize
+	if more {
+		matches = matches[:q.PageSize]
+	}
+	if len(matches) == 0 {
+		return nil, "", nil
+	}
+	ids := make([]any, len(matches))
+	position := map[string]int{}
+	for i, m := range matches {
+		ids[i] = m.id
+		position[m.id] = i
+	}
+	res, err := r.query(ctx, "SELECT "+columns+" FROM movies WHERE id IN (?"+strings.Repeat(", ?", len(ids)-1)+")", ids...)
+	if err != nil {
+		return nil, "", err
+	}
+	slices.SortFunc(res, func(a, b *model.Metadata) int {
+		return cmp.Compare(position[a.ID], position[b.ID])
+	})
+	return res, repository.NextSearchCursor(q, cursor.Offset+len(res), more), nil
+}
+
+// hits returns the number of occurrences of the phrases of a match in a row, given the
+// matchinfo 'x' blob of the row: three native-endian 32-bit integers per phrase and column, the
+// first of which is the number of occurrences in the row.
+func hits(info []byte) int {
+	n := 0
+	for i := 0; i+4 <= len(info); i += 12 {
+		n += int(binary.NativeEndian.Uint32(info[i:]))
+	}
+	return n
+}
+
+// query returns the movies selected by a query of columns, with their genres and credits.
+func (r *Repository) query(ctx context.Context, query string, args ...any) ([]*model.Metadata, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var res []*model.Metadata
+	byID := map[string]*model.Metadata{}
+	for rows.Next() {
+		var m model.Metadata
+		var updatedAt int64
+		var deletedAt sql.NullInt64
+		var deleteReason string
+		if err := rows.Scan(&m.ID, &m.Title, &m.Description, &m.Director, &m.ReleaseDate, &m.RuntimeMinutes, &m.Language, &m.Country, &m.AgeRating, &m.Version, &updatedAt, &m.UpdatedBy, &deletedAt, &deleteReason); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if updatedAt != 0 {
+			m.UpdatedAt = time.UnixMicro(updatedAt).UTC()
+		}
+		if deletedAt.Valid {
+			m.Tombstone = &model.Tombstone{DeletedAt: time.UnixMicro(deletedAt.Int64).UTC(), Reason: deleteReason}
+		}
+		res = append(res, &m)
+		byID[m.ID] = &m
+	}
+	// The single connection must be released before the next queries.
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+	ids := make([]any, len(res))
+	for i, m := range res {
+		ids[i] = m.ID
+	}
+	in := "(?" + strings.Repeat(", ?", len(ids)-1) + ")"
+	if err := r.scanRows(ctx, "SELECT mg.movie_id, g.name FROM movie_genres mg JOIN genres g ON g.id = mg.genre_id "+
+		"WHERE mg.movie_id IN "+in+" ORDER BY mg.movie_id, mg.position", ids, func(rows *sql.Rows) error {
+		var id, genre string
+		if err := rows.Scan(&id, &genre); err != nil {
+			return err
+		}
+		byID[id].Genres = append(byID[id].Genres, genre)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := r.scanRows(ctx, "SELECT movie_id, name, role, character_name FROM movie_credits "+
+		"WHERE movie_id IN "+in+" ORDER BY movie_id, position", ids, func(rows *sql.Rows) error {
+		var id string
+		var c model.Credit
+		if err := rows.Scan(&id, &c.Name, &c.Role, &c.Character); err != nil {
+			return err
+		}
+		byID[id].Credits = append(byID[id].Credits, c)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := r.scanRows(ctx, "SELECT movie_id, namespace, external_id FROM movie_external_ids "+
+		"WHERE movie_id IN "+in, ids, func(rows *sql.Rows) error {
+		var id, namespace, externalID string
+		if err := rows.Scan(&id, &namespace, &externalID); err != nil {
+			return err
+		}
+		if byID[id].ExternalIDs == nil {
+			byID[id].ExternalIDs = map[string]string{}
+		}
+		byID[id].ExternalIDs[namespace] = externalID
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := r.scanRows(ctx, "SELECT movie_id, locale, title, description FROM movie_localizations "+
+		"WHERE movie_id IN "+in, ids, func(rows *sql.Rows) error {
+		var id, locale string
+		var l model.Localization
+		if err := rows.Scan(&id, &locale, &l.Title, &l.Description); err != nil {
+			return err
+		}
+		if byID[id].Localizations == nil {
+			byID[id].Localizations = map[string]model.Localization{}
+		}
+		byID[id].Localizations[locale] = l
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := r.scanRows(ctx, "SELECT movie_id, kind, hash FROM movie_assets "+
+		"WHERE movie_id IN "+in+" ORDER BY movie_id, position", ids, func(rows *sql.Rows) error {
+		var id string
+		var a model.AssetRef
+		if err := rows.Scan(&id, &a.Kind, &a.Hash); err != nil {
+			return err
+		}
+		byID[id].Assets = append(byID[id].Assets, a)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// scanRows calls scan for every row of a query.
+func (r *Repository) scanRows(ctx context.Context, query string, args []any, scan func(rows *sql.Rows) error) error {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// isDuplicateKey reports whether err is a primary key or uniqueness violation.
+func isDuplicateKey(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && (sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey || sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique)
+}
+
+// querier runs queries on a database or in a transaction.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// revisionColumns are the revision columns scanned by queryRevisions.
+const revisionColumns = "movie_id, version, editor, created_at, changes, snapshot"
+
+// queryRevisions returns the revisions selected by a query of revisionColumns.
+func queryRevisions(ctx context.Context, q querier, query string, args ...any) ([]*model.Revision, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []*model.Revision
+	for rows.Next() {
+		var rev model.Revision
+		var createdAt int64
+		var changes, snapshot string
+		if err := rows.Scan(&rev.MovieID, &rev.Version, &rev.Editor, &createdAt, &changes, &snapshot); err != nil {
+			return nil, err
+		}
+		rev.Time = time.UnixMicro(createdAt).UTC()
+		if err := json.Unmarshal([]byte(changes), &rev.Changes); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(snapshot), &rev.Metadata); err != nil {
+			return nil, err
+		}
+		res = append(res, &rev)
+	}
+	return res, rows.Err()
+}
+
+func firstRevision(revisions []*model.Revision, err error) (*model.Revision, error) {
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return revisions[0], nil
+}
+
+// writeRevision appends the revision writing metadata to the change history.
+func writeRevision(ctx context.Context, tx *sql.Tx, metadata *model.Metadata, changes []model.FieldChange) error {
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	snapshot, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO movie_revisions ("+revisionColumns+") VALUES (?, ?, ?, ?, ?, ?)",
+		metadata.ID, metadata.Version, metadata.UpdatedBy, metadata.UpdatedAt.UnixMicro(), changesJSON, snapshot)
+	return err
+}
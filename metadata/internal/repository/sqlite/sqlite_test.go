@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/meirongdev/movie-microservice/metadata/internal/repository/repositorytest"
+	"github.com/meirongdev/movie-microservice/pkg/config"
+	"github.com/meirongdev/movie-microservice/pkg/migrate"
+)
+
+func TestRepository(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repository {
+		cfg := config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "metadata.db")}
+		r, err := New(cfg.FormatDSN())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { r.db.Close() })
+		if err := r.Migrate(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	})
+}
+
+func TestRevisionBackfill(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "metadata.db")}
+	r, err := New(cfg.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.db.Close()
+	ms, err := migrate.Load(migrations, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Movies written before revisions were introduced, in version 5.
+	if _, err := migrate.New(r.db, ms[:4], migrate.WithTable(migrationsTable), migrate.WithLocker(migrate.SQLiteLocker{})).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"INSERT INTO movies (id, title, director, release_date, runtime_minutes, version, updated_at) VALUES " +
+			"('1', 'Alien', 'Ridley Scott', '1979-05-25', 117, 3, 1700000000123456), ('2', 'Untitled', '', '', 0, 1, 0)",
+		"INSERT INTO genres (id, name) VALUES (1, 'Sci-Fi'), (2, 'Horror')",
+		"INSERT INTO movie_genres (movie_id, genre_id, position) VALUES ('1', 2, 1), ('1', 1, 0)",
+		"INSERT INTO movie_credits (movie_id, position, name, role, character_name) VALUES " +
+			"('1', 1, 'Sigourney Weaver', 'actor', 'Ripley'), ('1', 0, 'Ridley Scott', 'director', '')",
+	} {
+		if _, err := r.db.ExecContext(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2"} {
+		m, err := r.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rev, err := r.GetRevision(ctx, id, m.Version)
+		if err != nil {
+			t.Fatalf("GetRevision(%s, %d): %v", id, m.Version, err)
+		}
+		if !rev.Time.Equal(m.UpdatedAt) && !(m.UpdatedAt.IsZero() && rev.Time.UnixMicro() == 0) {
+			t.Errorf("revision of movie %s: got time %v, want %v", id, rev.Time, m.UpdatedAt)
+		}
+		if rev.Editor != m.UpdatedBy || len(rev.Changes) != 0 {
+			t.Errorf("revision of movie %s: got editor %q and changes %v, want %q without changes", id, rev.Editor, rev.Changes, m.UpdatedBy)
+		}
+		if !reflect.DeepEqual(rev.Metadata, *m) {
+			t.Errorf("snapshot of movie %s:\n got %+v\nwant %+v", id, rev.Metadata, *m)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package model
+
+import "slices"
+
+// AssetKind defines the use of an image of a movie.
+type AssetKind string
+
+// Kinds of images of movies.
+const (
+	AssetPoster   = AssetKind("poster")
+	AssetBackdrop = AssetKind("backdrop")
+	AssetStill    = AssetKind("still")
+)
+
+// Valid reports whether the kind is a known kind of images.
+func (k AssetKind) Valid() bool {
+	return slices.Contains([]AssetKind{AssetPoster, AssetBackdrop, AssetStill}, k)
+}
+
+// AssetRef defines a reference of a movie to an uploaded image.
+type AssetRef struct {
+	Kind AssetKind `json:"kind"`
+	// Hash is the hex-encoded SHA-256 hash of the image.
+	Hash string `json:"hash"`
+}
+
+// Asset defines an uploaded image, which is stored once per content with its thumbnails.
+type Asset struct {
+	// Hash is the hex-encoded SHA-256 hash of the image.
+	Hash        string `json:"hash"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+}
+
+// ThumbnailSize defines a size of the thumbnails generated for every image.
+type ThumbnailSize struct {
+	// Name is the name of the size in asset URLs.
+	Name  string
+	Width int
+}
+
+// ThumbnailSizes are the sizes of the thumbnails of images, the smallest first.
+var ThumbnailSizes = []ThumbnailSize{{"w185", 185}, {"w342", 342}, {"w780", 780}}
+
+// AssetURL returns the URL of an image served under a base URL, or of its thumbnail of a size
+// if size is not empty.
+func AssetURL(baseURL, hash, size string) string {
+	url := baseURL + "/" + hash
+	if size != "" {
+		url += "/" + size
+	}
+	return url
+}
+
+// Poster returns the poster of the movie, its first poster asset.
+func (m *Metadata) Poster() (AssetRef, bool) {
+	i := slices.IndexFunc(m.Assets, func(a AssetRef) bool { return a.Kind == AssetPoster })
+	if i < 0 {
+		return AssetRef{}, false
+	}
+	return m.Assets[i], true
+}
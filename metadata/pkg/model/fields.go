@@ -0,0 +1,59 @@
+package model
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// field defines an editable metadata field, named as in the proto Metadata message.
+type field struct {
+	name     string
+	jsonName string
+	value    func(m *Metadata) string
+	copy     func(dst, src *Metadata)
+}
+
+var fields = []field{
+	{"title", "title", func(m *Metadata) string { return m.Title }, func(dst, src *Metadata) { dst.Title = src.Title }},
+	{"description", "description", func(m *Metadata) string { return m.Description }, func(dst, src *Metadata) { dst.Description = src.Description }},
+	{"director", "director", func(m *Metadata) string { return m.Director }, func(dst, src *Metadata) { dst.Director = src.Director }},
+	{"genres", "genres", func(m *Metadata) string { return formatList(m.Genres) }, func(dst, src *Metadata) { dst.Genres = slices.Clone(src.Genres) }},
+	{"credits", "credits", func(m *Metadata) string { return formatList(m.Credits) }, func(dst, src *Metadata) { dst.Credits = slices.Clone(src.Credits) }},
+	{"release_date", "releaseDate", func(m *Metadata) string { return m.ReleaseDate }, func(dst, src *Metadata) { dst.ReleaseDate = src.ReleaseDate }},
+	{"runtime_minutes", "runtimeMinutes", func(m *Metadata) string { return formatRuntime(m.RuntimeMinutes) }, func(dst, src *Metadata) { dst.RuntimeMinutes = src.RuntimeMinutes }},
+	{"language", "language", func(m *Metadata) string { return m.Language }, func(dst, src *Metadata) { dst.Language = src.Language }},
+	{"country", "country", func(m *Metadata) string { return m.Country }, func(dst, src *Metadata) { dst.Country = src.Country }},
+	{"age_rating", "ageRating", func(m *Metadata) string { return m.AgeRating }, func(dst, src *Metadata) { dst.AgeRating = src.AgeRating }},
+	{"external_ids", "externalIds", func(m *Metadata) string { return formatMap(m.ExternalIDs) }, func(dst, src *Metadata) { dst.ExternalIDs = maps.Clone(src.ExternalIDs) }},
+	{"localizations", "localizations", func(m *Metadata) string { return formatMap(m.Localizations) }, func(dst, src *Metadata) { dst.Localizations = maps.Clone(src.Localizations) }},
+	{"assets", "assets", func(m *Metadata) string { return formatList(m.Assets) }, func(dst, src *Metadata) { dst.Assets = slices.Clone(src.Assets) }},
+}
+
+// UpdateFields copies the fields named by paths from src, or all editable fields for the path "*".
+// Paths are the names of editable fields in the proto Metadata message.
+func (m *Metadata) UpdateFields(src *Metadata, paths []string) error {
+	for _, p := range paths {
+		if p == "*" {
+			for _, f := range fields {
+				f.copy(m, src)
+			}
+			continue
+		}
+		i := slices.IndexFunc(fields, func(f field) bool { return f.name == p })
+		if i < 0 {
+			return fmt.Errorf("field %q cannot be updated", p)
+		}
+		fields[i].copy(m, src)
+	}
+	return nil
+}
+
+// FieldPath returns the path of an editable field from its JSON name.
+func FieldPath(jsonName string) (string, bool) {
+	i := slices.IndexFunc(fields, func(f field) bool { return f.jsonName == jsonName })
+	if i < 0 {
+		return "", false
+	}
+	return fields[i].name, true
+}
@@ -0,0 +1,142 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fullMetadata returns metadata with every field set.
+func fullMetadata(id, suffix string) *Metadata {
+	return &Metadata{
+		ID:             id,
+		Title:          "title" + suffix,
+		Description:    "description" + suffix,
+		Director:       "director" + suffix,
+		Genres:         []string{"genre" + suffix},
+		Credits:        []Credit{{Name: "name" + suffix, Role: RoleActor, Character: "character" + suffix}},
+		ReleaseDate:    "2024-01-0" + suffix,
+		RuntimeMinutes: 90,
+		Language:       "en" + suffix,
+		Country:        "US" + suffix,
+		AgeRating:      "PG" + suffix,
+		ExternalIDs:    map[string]string{NamespaceIMDb: "tt" + suffix},
+		Localizations:  map[string]Localization{"de": {Title: "titel" + suffix}},
+		Assets:         []AssetRef{{Kind: AssetPoster, Hash: "hash" + suffix}},
+		Version:        3,
+		UpdatedAt:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		UpdatedBy:      "editor" + suffix,
+		Tombstone:      &Tombstone{Reason: "reason" + suffix},
+	}
+}
+
+func TestUpdateFields(t *testing.T) {
+	src := fullMetadata("src", "2")
+	src.RuntimeMinutes = 120
+	tests := []struct {
+		name   string
+		paths  []string
+		modify func(want *Metadata)
+	}{
+		{"none", nil, func(*Metadata) {}},
+		{"title", []string{"title"}, func(want *Metadata) { want.Title = src.Title }},
+		{"proto names", []string{"release_date", "runtime_minutes", "age_rating", "external_ids"}, func(want *Metadata) {
+			want.ReleaseDate, want.RuntimeMinutes, want.AgeRating, want.ExternalIDs = src.ReleaseDate, src.RuntimeMinutes, src.AgeRating, src.ExternalIDs
+		}},
+		{"lists", []string{"genres", "credits", "assets", "localizations"}, func(want *Metadata) {
+			want.Genres, want.Credits, want.Assets, want.Localizations = src.Genres, src.Credits, src.Assets, src.Localizations
+		}},
+		// Fields that are not editable, such as the id and version, are kept.
+		{"all", []string{"*"}, func(want *Metadata) {
+			id, version, updatedAt, updatedBy, tombstone := want.ID, want.Version, want.UpdatedAt, want.UpdatedBy, want.Tombstone
+			*want = *src.Clone()
+			want.ID, want.Version, want.UpdatedAt, want.UpdatedBy, want.Tombstone = id, version, updatedAt, updatedBy, tombstone
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := fullMetadata("dst", "1")
+			want := fullMetadata("dst", "1")
+			tt.modify(want)
+			if err := m.UpdateFields(src, tt.paths); err != nil {
+				t.Fatalf("UpdateFields: %v", err)
+			}
+			if !reflect.DeepEqual(m, want) {
+				t.Fatalf("UpdateFields:\n got %+v\nwant %+v", m, want)
+			}
+		})
+	}
+}
+
+func TestUpdateFieldsClears(t *testing.T) {
+	m := fullMetadata("dst", "1")
+	if err := m.UpdateFields(&Metadata{}, []string{"genres", "external_ids", "release_date"}); err != nil {
+		t.Fatalf("UpdateFields: %v", err)
+	}
+	if m.Genres != nil || m.ExternalIDs != nil || m.ReleaseDate != "" {
+		t.Fatalf("UpdateFields from empty metadata: got %+v, want cleared fields", m)
+	}
+}
+
+func TestUpdateFieldsCopies(t *testing.T) {
+	src := fullMetadata("src", "2")
+	m := fullMetadata("dst", "1")
+	if err := m.UpdateFields(src, []string{"*"}); err != nil {
+		t.Fatalf("UpdateFields: %v", err)
+	}
+	src.Genres[0] = "changed"
+	src.ExternalIDs[NamespaceIMDb] = "changed"
+	if m.Genres[0] == "changed" || m.ExternalIDs[NamespaceIMDb] == "changed" {
+		t.Fatal("UpdateFields shares lists or maps with the source")
+	}
+}
+
+func TestUpdateFieldsUnknownPaths(t *testing.T) {
+	for _, path := range []string{"id", "version", "updated_by", "tombstone", "releaseDate", "Title", "unknown", ""} {
+		m := fullMetadata("dst", "1")
+		if err := m.UpdateFields(fullMetadata("src", "2"), []string{"title", path}); err == nil {
+			t.Errorf("UpdateFields with path %q: got no error", path)
+		}
+	}
+}
+
+func TestFieldPath(t *testing.T) {
+	tests := []struct {
+		jsonName string
+		want     string
+		ok       bool
+	}{
+		{"title", "title", true},
+		{"releaseDate", "release_date", true},
+		{"runtimeMinutes", "runtime_minutes", true},
+		{"ageRating", "age_rating", true},
+		{"externalIds", "external_ids", true},
+		{"localizations", "localizations", true},
+		{"assets", "assets", true},
+		{"release_date", "", false},
+		{"id", "", false},
+		{"version", "", false},
+		{"updatedBy", "", false},
+		{"*", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := FieldPath(tt.jsonName)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("FieldPath(%q): got %q, %v, want %q, %v", tt.jsonName, got, ok, tt.want, tt.ok)
+		}
+	}
+	// Every editable field has a JSON name matching the JSON encoding of Metadata.
+	for _, f := range fields {
+		if path, ok := FieldPath(f.jsonName); !ok || path != f.name {
+			t.Errorf("FieldPath(%q): got %q, %v, want %q", f.jsonName, path, ok, f.name)
+		}
+		if _, ok := reflect.TypeFor[Metadata]().FieldByNameFunc(func(name string) bool {
+			sf, _ := reflect.TypeFor[Metadata]().FieldByName(name)
+			tag, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+			return tag == f.jsonName
+		}); !ok {
+			t.Errorf("field %q: no Metadata field with JSON name %q", f.name, f.jsonName)
+		}
+	}
+}
@@ -2,24 +2,101 @@ package model
 
 import (
 	"github.com/meirongdev/movie-microservice/gen"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // MetadataToProto converts a Metadata struct into a generated proto counterpart.
 func MetadataToProto(m *Metadata) *gen.Metadata {
-	return &gen.Metadata{
-		Id:          m.ID,
-		Title:       m.Title,
-		Description: m.Description,
-		Director:    m.Director,
+	res := &gen.Metadata{
+		Id:             m.ID,
+		Title:          m.Title,
+		Description:    m.Description,
+		Director:       m.Director,
+		Genres:         m.Genres,
+		ReleaseDate:    m.ReleaseDate,
+		RuntimeMinutes: m.RuntimeMinutes,
+		Language:       m.Language,
+		Country:        m.Country,
+		AgeRating:      m.AgeRating,
+		ExternalIds:    m.ExternalIDs,
+		Version:        m.Version,
+		UpdatedBy:      m.UpdatedBy,
 	}
+	if !m.UpdatedAt.IsZero() {
+		res.UpdatedAt = timestamppb.New(m.UpdatedAt)
+	}
+	if m.Tombstone != nil {
+		res.Tombstone = &gen.Tombstone{DeletedAt: timestamppb.New(m.Tombstone.DeletedAt), Reason: m.Tombstone.Reason}
+	}
+	for _, c := range m.Credits {
+		res.Credits = append(res.Credits, &gen.Credit{Name: c.Name, Role: string(c.Role), Character: c.Character})
+	}
+	if len(m.Localizations) > 0 {
+		res.Localizations = make(map[string]*gen.Localization, len(m.Localizations))
+		for locale, l := range m.Localizations {
+			res.Localizations[locale] = &gen.Localization{Title: l.Title, Description: l.Description}
+		}
+	}
+	for _, a := range m.Assets {
+		res.Assets = append(res.Assets, &gen.AssetRef{Kind: string(a.Kind), Hash: a.Hash})
+	}
+	return res
 }
 
 // MetadataFromProto converts a generated proto counterpart into a Metadata struct.
 func MetadataFromProto(m *gen.Metadata) *Metadata {
-	return &Metadata{
-		ID:          m.Id,
-		Title:       m.Title,
-		Description: m.Description,
-		Director:    m.Director,
+	res := &Metadata{
+		ID:             m.Id,
+		Title:          m.Title,
+		Description:    m.Description,
+		Director:       m.Director,
+		Genres:         m.Genres,
+		ReleaseDate:    m.ReleaseDate,
+		RuntimeMinutes: m.RuntimeMinutes,
+		Language:       m.Language,
+		Country:        m.Country,
+		AgeRating:      m.AgeRating,
+		ExternalIDs:    m.ExternalIds,
+		Version:        m.Version,
+		UpdatedBy:      m.UpdatedBy,
+	}
+	if m.UpdatedAt != nil {
+		res.UpdatedAt = m.UpdatedAt.AsTime()
+	}
+	if m.Tombstone != nil {
+		res.Tombstone = &Tombstone{DeletedAt: m.Tombstone.DeletedAt.AsTime(), Reason: m.Tombstone.Reason}
+	}
+	for _, c := range m.Credits {
+		res.Credits = append(res.Credits, Credit{Name: c.Name, Role: Role(c.Role), Character: c.Character})
+	}
+	if len(m.Localizations) > 0 {
+		res.Localizations = make(map[string]Localization, len(m.Localizations))
+		for locale, l := range m.Localizations {
+			res.Localizations[locale] = Localization{Title: l.GetTitle(), Description: l.GetDescription()}
+		}
+	}
+	for _, a := range m.Assets {
+		res.Assets = append(res.Assets, AssetRef{Kind: AssetKind(a.Kind), Hash: a.Hash})
+	}
+	return res
+}
+
+// AssetToProto converts an Asset struct into a generated proto counterpart.
+func AssetToProto(a *Asset) *gen.Asset {
+	return &gen.Asset{Hash: a.Hash, ContentType: a.ContentType, Size: a.Size, Width: int32(a.Width), Height: int32(a.Height)}
+}
+
+// RevisionToProto converts a Revision struct into a generated proto counterpart.
+func RevisionToProto(r *Revision) *gen.MetadataRevision {
+	res := &gen.MetadataRevision{
+		MovieId:  r.MovieID,
+		Version:  r.Version,
+		Editor:   r.Editor,
+		Time:     timestamppb.New(r.Time),
+		Metadata: MetadataToProto(&r.Metadata),
+	}
+	for _, c := range r.Changes {
+		res.Changes = append(res.Changes, &gen.FieldChange{Field: c.Field, OldValue: c.Old, NewValue: c.New})
 	}
+	return res
 }
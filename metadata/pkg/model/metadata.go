@@ -1,9 +1,114 @@
 package model
 
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"time"
+)
+
 // Metadata defines the movie metadata.
 type Metadata struct {
-	ID          string `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Director    string `json:"director"`
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Director    string   `json:"director"`
+	Genres      []string `json:"genres,omitempty"`
+	Credits     []Credit `json:"credits,omitempty"`
+	// ReleaseDate is formatted as YYYY-MM-DD, empty if unknown.
+	ReleaseDate    string `json:"releaseDate,omitempty"`
+	RuntimeMinutes int32  `json:"runtimeMinutes,omitempty"`
+	// Language is an ISO 639-1 code, e.g. "en".
+	Language string `json:"language,omitempty"`
+	// Country is an ISO 3166-1 alpha-2 code, e.g. "US".
+	Country string `json:"country,omitempty"`
+	// AgeRating is a content rating, e.g. "PG-13".
+	AgeRating string `json:"ageRating,omitempty"`
+	// ExternalIDs maps namespaces, e.g. NamespaceIMDb, to the id of the movie in the namespace. An
+	// external id identifies at most one movie in its namespace.
+	ExternalIDs map[string]string `json:"externalIds,omitempty"`
+	// Localizations maps BCP 47 locales, e.g. "pt-BR", to the title and description translated to
+	// the locale.
+	Localizations map[string]Localization `json:"localizations,omitempty"`
+	// Assets are the images of the movie. The first poster is the poster of the movie.
+	Assets []AssetRef `json:"assets,omitempty"`
+	// Version is incremented by every write, starting at 1.
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// UpdatedBy is the editor of the last write.
+	UpdatedBy string `json:"updatedBy,omitempty"`
+	// Tombstone is set if the movie is deleted.
+	Tombstone *Tombstone `json:"tombstone,omitempty"`
+}
+
+// Tombstone defines the deletion of a movie, which is kept until it is purged.
+type Tombstone struct {
+	DeletedAt time.Time `json:"deletedAt"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// Localization defines the title and description of a movie translated to a locale. Empty fields
+// fall back to the untranslated ones.
+type Localization struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Namespaces of well-known external ids.
+const (
+	NamespaceIMDb    = "imdb"
+	NamespaceTMDb    = "tmdb"
+	NamespaceCatalog = "catalog"
+)
+
+// Role defines the role of a person in a movie.
+type Role string
+
+// Roles of the cast and crew.
+const (
+	RoleActor    = Role("actor")
+	RoleDirector = Role("director")
+	RoleWriter   = Role("writer")
+	RoleProducer = Role("producer")
+	RoleComposer = Role("composer")
+)
+
+// Credit defines a member of the cast or crew of a movie.
+type Credit struct {
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+	// Character is the character played by an actor.
+	Character string `json:"character,omitempty"`
+}
+
+// Clone returns a copy of the metadata that shares no memory with it.
+func (m *Metadata) Clone() *Metadata {
+	c := *m
+	c.Genres = slices.Clone(m.Genres)
+	c.Credits = slices.Clone(m.Credits)
+	c.ExternalIDs = maps.Clone(m.ExternalIDs)
+	c.Localizations = maps.Clone(m.Localizations)
+	c.Assets = slices.Clone(m.Assets)
+	if m.Tombstone != nil {
+		t := *m.Tombstone
+		c.Tombstone = &t
+	}
+	return &c
+}
+
+// Localize returns the metadata with the title and description of the first locale of a fallback
+// chain (see locale.Chain) having a localization, and that locale. The untranslated metadata and
+// an empty locale are returned if no locale of the chain has a localization.
+func (m *Metadata) Localize(chain []string) (*Metadata, string) {
+	for _, locale := range chain {
+		l, ok := m.Localizations[locale]
+		if !ok {
+			continue
+		}
+		c := m.Clone()
+		c.Title = cmp.Or(l.Title, m.Title)
+		c.Description = cmp.Or(l.Description, m.Description)
+		return c, locale
+	}
+	return m, ""
 }
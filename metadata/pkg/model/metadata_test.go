@@ -0,0 +1,43 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLocalize(t *testing.T) {
+	m := &Metadata{ID: "1", Title: "City of God", Description: "Two boys grow up in Rio.", Localizations: map[string]Localization{
+		"pt":    {Title: "Cidade de Deus", Description: "Dois meninos crescem no Rio."},
+		"pt-BR": {Title: "Cidade de Deus (BR)"},
+		"fr":    {Description: "Deux garçons grandissent à Rio."},
+	}}
+	tests := []struct {
+		name        string
+		chain       []string
+		locale      string
+		title       string
+		description string
+	}{
+		{"no chain", nil, "", "City of God", "Two boys grow up in Rio."},
+		{"no localization", []string{"de-AT", "de"}, "", "City of God", "Two boys grow up in Rio."},
+		{"exact locale", []string{"pt", "en"}, "pt", "Cidade de Deus", "Dois meninos crescem no Rio."},
+		{"first of the chain", []string{"pt-BR", "pt"}, "pt-BR", "Cidade de Deus (BR)", "Two boys grow up in Rio."},
+		{"parent locale", []string{"pt-PT", "pt"}, "pt", "Cidade de Deus", "Dois meninos crescem no Rio."},
+		{"missing title", []string{"fr-CA", "fr"}, "fr", "City of God", "Deux garçons grandissent à Rio."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := m.Clone()
+			got, locale := m.Localize(tt.chain)
+			if locale != tt.locale || got.Title != tt.title || got.Description != tt.description {
+				t.Fatalf("Localize(%v): got %q, %q, %q, want %q, %q, %q", tt.chain, locale, got.Title, got.Description, tt.locale, tt.title, tt.description)
+			}
+			if got.ID != m.ID || !reflect.DeepEqual(got.Localizations, m.Localizations) {
+				t.Fatalf("Localize(%v): got %+v, want the other fields of %+v", tt.chain, got, m)
+			}
+			if !reflect.DeepEqual(m, before) {
+				t.Fatalf("Localize(%v) changed the metadata: got %+v, want %+v", tt.chain, m, before)
+			}
+		})
+	}
+}
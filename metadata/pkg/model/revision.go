@@ -0,0 +1,79 @@
+package model
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Revision defines a write of movie metadata in its append-only change history.
+type Revision struct {
+	MovieID string `json:"movieId"`
+	// Version is the version of the metadata written by the revision.
+	Version int64     `json:"version"`
+	Editor  string    `json:"editor"`
+	Time    time.Time `json:"time"`
+	// Changes are the fields changed from the previous revision.
+	Changes []FieldChange `json:"changes"`
+	// Metadata is the metadata as written.
+	Metadata Metadata `json:"metadata"`
+}
+
+// FieldChange defines the old and new value of a changed metadata field. Fields are named as in
+// the proto Metadata message, and lists and maps are formatted as JSON.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Diff returns the fields changed from old to new metadata, old being nil for new metadata.
+// Deletions are changes of the tombstone field.
+// The version and the time and editor of the last write are not compared.
+func Diff(old, new *Metadata) []FieldChange {
+	if old == nil {
+		old = &Metadata{}
+	}
+	var res []FieldChange
+	for _, f := range fields {
+		if o, n := f.value(old), f.value(new); o != n {
+			res = append(res, FieldChange{Field: f.name, Old: o, New: n})
+		}
+	}
+	if o, n := formatTombstone(old.Tombstone), formatTombstone(new.Tombstone); o != n {
+		res = append(res, FieldChange{Field: "tombstone", Old: o, New: n})
+	}
+	return res
+}
+
+func formatList[T any](list []T) string {
+	if len(list) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(list)
+	return string(b)
+}
+
+// formatMap formats a map as a JSON object with sorted keys.
+func formatMap[V any](m map[string]V) string {
+	if len(m) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+func formatRuntime(minutes int32) string {
+	if minutes == 0 {
+		return ""
+	}
+	return strconv.Itoa(int(minutes))
+}
+
+func formatTombstone(t *Tombstone) string {
+	if t == nil {
+		return ""
+	}
+	b, _ := json.Marshal(t)
+	return string(b)
+}
@@ -8,11 +8,27 @@ import (
 )
 
 type config struct {
-	API apiConfig `yaml:"api"`
+	API      apiConfig      `yaml:"api"`
+	Gateways gatewaysConfig `yaml:"gateways"`
+	Assets   assetsConfig   `yaml:"assets"`
 }
 
 type apiConfig struct {
-	Port int `yaml:"port"`
+	Port     int `yaml:"port"`
+	HTTPPort int `yaml:"http_port"`
+}
+
+// gatewaysConfig selects the transport (grpc or http) used to call each dependency.
+type gatewaysConfig struct {
+	Metadata string `yaml:"metadata"`
+	Rating   string `yaml:"rating"`
+}
+
+// assetsConfig defines where the images of movies are served.
+type assetsConfig struct {
+	// BaseURL is the URL under which the metadata service serves images, empty to leave out the
+	// poster URLs of movies.
+	BaseURL string `yaml:"base_url"`
 }
 
 func loadConfig(path string) (config, error) {
@@ -6,16 +6,27 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/meirongdev/movie-microservice/gen"
+	"github.com/meirongdev/movie-microservice/internal/grpcutil"
+	metadatamodel "github.com/meirongdev/movie-microservice/metadata/pkg/model"
 	"github.com/meirongdev/movie-microservice/movie/internal/controller/movie"
-	metadatagateway "github.com/meirongdev/movie-microservice/movie/internal/gateway/metadata/grpc"
-	ratinggateway "github.com/meirongdev/movie-microservice/movie/internal/gateway/rating/grpc"
+	metadatagrpcgateway "github.com/meirongdev/movie-microservice/movie/internal/gateway/metadata/grpc"
+	metadatahttpgateway "github.com/meirongdev/movie-microservice/movie/internal/gateway/metadata/http"
+	ratinggrpcgateway "github.com/meirongdev/movie-microservice/movie/internal/gateway/rating/grpc"
+	ratinghttpgateway "github.com/meirongdev/movie-microservice/movie/internal/gateway/rating/http"
 	grpchandler "github.com/meirongdev/movie-microservice/movie/internal/handler/grpc"
+	httphandler "github.com/meirongdev/movie-microservice/movie/internal/handler/http"
 	"github.com/meirongdev/movie-microservice/pkg/discovery"
 	"github.com/meirongdev/movie-microservice/pkg/discovery/consul"
+	ratingmodel "github.com/meirongdev/movie-microservice/rating/pkg/model"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -30,42 +41,106 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	port := config.API.Port
+	if err := run(config); err != nil {
+		log.Fatalf("The movie service failed: %v", err)
+	}
+}
+
+func run(cfg config) error {
+	// The service stops on SIGINT or SIGTERM.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", cfg.API.Port))
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	httpLis, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", cfg.API.HTTPPort))
+	if err != nil {
+		lis.Close()
+		return fmt.Errorf("failed to listen: %w", err)
+	}
 
 	// Register with Consul start
 	registry, err := consul.NewRegistry("localhost:8500")
 	if err != nil {
-		panic(err)
+		return err
 	}
-	ctx := context.Background()
 	instanceID := discovery.GenerateInstanceID((serviceName))
-	if err := registry.Register(ctx, instanceID, serviceName, "localhost:"+strconv.Itoa(port)); err != nil {
-		panic(err)
+	// The ports are those of the listeners, which the system chooses if port 0 is configured.
+	endpoints := map[string]int{discovery.ProtocolGRPC: grpcutil.Port(lis), discovery.ProtocolHTTP: grpcutil.Port(httpLis)}
+	for protocol, p := range endpoints {
+		id := discovery.ProtocolInstanceID(instanceID, protocol)
+		if err := registry.Register(ctx, id, serviceName, "localhost:"+strconv.Itoa(p), protocol); err != nil {
+			return err
+		}
+		defer registry.Deregister(context.Background(), id, serviceName)
 	}
 
 	go func() {
 		for {
-			if err := registry.ReportHealthyState(instanceID, serviceName); err != nil {
-				log.Println("failed to report healthy state:", err)
+			for protocol := range endpoints {
+				if err := registry.ReportHealthyState(discovery.ProtocolInstanceID(instanceID, protocol), serviceName); err != nil {
+					log.Println("failed to report healthy state:", err)
+				}
 			}
 			time.Sleep(2 * time.Second)
 		}
 	}()
-	defer registry.Deregister(ctx, instanceID, serviceName)
 	// Register with Consul end
 
-	metadataGateway := metadatagateway.New(registry)
-	ratingGateway := ratinggateway.New(registry)
-	ctrl := movie.New(ratingGateway, metadataGateway)
-	h := grpchandler.New(ctrl)
-	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+	metadataGateway, err := newMetadataGateway(cfg.Gateways.Metadata, registry)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		return err
 	}
+	ratingGateway, err := newRatingGateway(cfg.Gateways.Rating, registry)
+	if err != nil {
+		return err
+	}
+	ctrl := movie.New(ratingGateway, metadataGateway, movie.WithAssetBaseURL(cfg.Assets.BaseURL))
+	h := grpchandler.New(ctrl)
+
+	// The REST/JSON gateway generated from api/movie.proto calls the gRPC handler in-process.
+	gwmux := runtime.NewServeMux()
+	if err := gen.RegisterMovieServiceHandlerServer(ctx, gwmux, h); err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/movie", http.HandlerFunc(httphandler.New(ctrl).GetMovieDetails))
+	mux.Handle("/v1/", gwmux)
+
 	srv := grpc.NewServer()
 	reflection.Register(srv)
 	gen.RegisterMovieServiceServer(srv, h)
-	if err := srv.Serve(lis); err != nil {
-		panic(err)
+	log.Printf("Starting the movie service on %s (gRPC) and %s (HTTP)", lis.Addr(), httpLis.Addr())
+	return grpcutil.Serve(ctx, srv, lis, &http.Server{Handler: mux}, httpLis)
+}
+
+type metadataService interface {
+	Get(ctx context.Context, id string) (*metadatamodel.Metadata, error)
+	Resolve(ctx context.Context, namespace, externalID string) (string, error)
+	Search(ctx context.Context, query string, pageSize int, pageToken string) ([]*metadatamodel.Metadata, string, error)
+}
+
+type ratingService interface {
+	GetAggregatedRating(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType) (float64, error)
+}
+
+func newMetadataGateway(protocol string, registry discovery.Registry) (metadataService, error) {
+	switch protocol {
+	case "", discovery.ProtocolGRPC:
+		return metadatagrpcgateway.New(registry), nil
+	case discovery.ProtocolHTTP:
+		return metadatahttpgateway.New(registry), nil
+	}
+	return nil, fmt.Errorf("unsupported metadata gateway protocol %q", protocol)
+}
+
+func newRatingGateway(protocol string, registry discovery.Registry) (ratingService, error) {
+	switch protocol {
+	case "", discovery.ProtocolGRPC:
+		return ratinggrpcgateway.New(registry), nil
+	case discovery.ProtocolHTTP:
+		return ratinghttpgateway.New(registry), nil
 	}
+	return nil, fmt.Errorf("unsupported rating gateway protocol %q", protocol)
 }
@@ -3,6 +3,10 @@ package movie
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
 
 	metadatamodel "github.com/meirongdev/movie-microservice/metadata/pkg/model"
 	"github.com/meirongdev/movie-microservice/movie/internal/gateway"
@@ -13,6 +17,9 @@ import (
 // ErrNotFound is returned when the movie metadata is not found.
 var ErrNotFound = errors.New("movie metadata not found")
 
+// ErrInvalidArgument is returned when a search is malformed.
+var ErrInvalidArgument = errors.New("invalid argument")
+
 type ratingGateway interface {
 	GetAggregatedRating(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType) (float64, error)
 	// PutRating(ctx context.Context, recordID ratingmodel.RecordID, recordType ratingmodel.RecordType, rating *ratingmodel.Rating) error
@@ -20,35 +27,127 @@ type ratingGateway interface {
 
 type metadataGateway interface {
 	Get(ctx context.Context, id string) (*metadatamodel.Metadata, error)
+	Resolve(ctx context.Context, namespace, externalID string) (string, error)
+	Search(ctx context.Context, query string, pageSize int, pageToken string) ([]*metadatamodel.Metadata, string, error)
 }
 
 // Controller defines a movie service controller.
 type Controller struct {
 	ratingGateway   ratingGateway
 	metadataGateway metadataGateway
+	config
+}
+
+type config struct {
+	assetBaseURL string
+}
+
+// Option configures a movie controller.
+type Option func(*config)
+
+// WithAssetBaseURL sets the URL under which the metadata service serves images, e.g.
+// "http://localhost:8091/assets", used to return the poster URLs of movies.
+func WithAssetBaseURL(url string) Option {
+	return func(c *config) {
+		c.assetBaseURL = strings.TrimSuffix(url, "/")
+	}
 }
 
 // New creates a new movie service controller.
-func New(ratingGateway ratingGateway, metadataGateway metadataGateway) *Controller {
-	return &Controller{ratingGateway, metadataGateway}
+func New(ratingGateway ratingGateway, metadataGateway metadataGateway, options ...Option) *Controller {
+	c := &Controller{ratingGateway: ratingGateway, metadataGateway: metadataGateway}
+	for _, o := range options {
+		o(&c.config)
+	}
+	return c
 }
 
-// Get returns the movie details including the aggregated rating and movie metadata.
-func (c *Controller) Get(ctx context.Context, id string) (*model.MovieDetails, error) {
+// Get returns the movie details including the aggregated rating and movie metadata, with the title
+// and description in the first locale of a fallback chain (see locale.Chain) they are translated to.
+func (c *Controller) Get(ctx context.Context, id string, locales []string) (*model.MovieDetails, error) {
 	metadata, err := c.metadataGateway.Get(ctx, id)
 	if err != nil && errors.Is(err, gateway.ErrNotFound) {
 		return nil, ErrNotFound
 	} else if err != nil {
 		return nil, err
 	}
-	details := &model.MovieDetails{Metadata: *metadata}
-	rating, err := c.ratingGateway.GetAggregatedRating(ctx, ratingmodel.RecordID(id), ratingmodel.RecordTypeMovie)
-	if err != nil && !errors.Is(err, gateway.ErrNotFound) {
-		// Just proceed in this case, it's ok not to have ratings yet.
-	} else if err != nil {
+	// Deleted movies are not found, even if the metadata service returns their tombstones.
+	if metadata.Tombstone != nil {
+		return nil, ErrNotFound
+	}
+	metadata, served := metadata.Localize(locales)
+	details := &model.MovieDetails{Metadata: *metadata, Locale: served, Poster: c.poster(metadata)}
+	if err := c.attachRating(ctx, details); err != nil {
 		return nil, err
-	} else {
-		details.Rating = &rating
 	}
 	return details, nil
 }
+
+// GetByExternalID returns the movie details of the movie with an external id in a namespace, such
+// as an IMDb id, localized as by Get.
+func (c *Controller) GetByExternalID(ctx context.Context, namespace, externalID string, locales []string) (*model.MovieDetails, error) {
+	id, err := c.metadataGateway.Resolve(ctx, namespace, externalID)
+	if err != nil && errors.Is(err, gateway.ErrNotFound) {
+		return nil, ErrNotFound
+	} else if err != nil && errors.Is(err, gateway.ErrInvalidArgument) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	} else if err != nil {
+		return nil, err
+	}
+	return c.Get(ctx, id, locales)
+}
+
+// Search returns a page of movies whose metadata matches a query, with their aggregated ratings,
+// and the token of the next page.
+func (c *Controller) Search(ctx context.Context, query string, pageSize int, pageToken string) ([]*model.MovieDetails, string, error) {
+	metadata, next, err := c.metadataGateway.Search(ctx, query, pageSize, pageToken)
+	if err != nil && errors.Is(err, gateway.ErrInvalidArgument) {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	} else if err != nil {
+		return nil, "", err
+	}
+	metadata = slices.DeleteFunc(metadata, func(m *metadatamodel.Metadata) bool { return m.Tombstone != nil })
+	res := make([]*model.MovieDetails, len(metadata))
+	errs := make([]error, len(metadata))
+	var wg sync.WaitGroup
+	for i, m := range metadata {
+		res[i] = &model.MovieDetails{Metadata: *m}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = c.attachRating(ctx, res[i])
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, "", err
+	}
+	return res, next, nil
+}
+
+// poster returns the URLs of the poster of a movie, nil if it has none or asset URLs are not
+// configured.
+func (c *Controller) poster(m *metadatamodel.Metadata) *model.Poster {
+	ref, ok := m.Poster()
+	if !ok || c.assetBaseURL == "" {
+		return nil
+	}
+	p := &model.Poster{URL: metadatamodel.AssetURL(c.assetBaseURL, ref.Hash, ""), ThumbnailURLs: map[string]string{}}
+	for _, size := range metadatamodel.ThumbnailSizes {
+		p.ThumbnailURLs[size.Name] = metadatamodel.AssetURL(c.assetBaseURL, ref.Hash, size.Name)
+	}
+	return p
+}
+
+// attachRating sets the aggregated rating of a movie, if it has ratings.
+func (c *Controller) attachRating(ctx context.Context, details *model.MovieDetails) error {
+	rating, err := c.ratingGateway.GetAggregatedRating(ctx, ratingmodel.RecordID(details.Metadata.ID), ratingmodel.RecordTypeMovie)
+	if err != nil && errors.Is(err, gateway.ErrNotFound) {
+		// Just proceed in this case, it's ok not to have ratings yet.
+		return nil
+	} else if err != nil {
+		return err
+	}
+	details.Rating = &rating
+	return nil
+}
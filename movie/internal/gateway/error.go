@@ -4,3 +4,6 @@ import "errors"
 
 // ErrNotFound is returned when the data is not found.
 var ErrNotFound = errors.New("not found")
+
+// ErrInvalidArgument is returned when the called service rejects the request as malformed.
+var ErrInvalidArgument = errors.New("invalid argument")
@@ -2,11 +2,15 @@ package grpc
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/meirongdev/movie-microservice/gen"
 	"github.com/meirongdev/movie-microservice/internal/grpcutil"
 	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
+	"github.com/meirongdev/movie-microservice/movie/internal/gateway"
 	"github.com/meirongdev/movie-microservice/pkg/discovery"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Gateway defines a movie metadata gRPC gateway.
@@ -29,7 +33,52 @@ func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
 	client := gen.NewMetadataServiceClient(conn)
 	resp, err := client.GetMetadata(ctx, &gen.GetMetadataRequest{MovieId: id})
 	if err != nil {
-		return nil, err
+		return nil, gatewayError(err)
 	}
 	return model.MetadataFromProto(resp.Metadata), nil
 }
+
+// Resolve returns the id of the movie with an external id in a namespace.
+func (g *Gateway) Resolve(ctx context.Context, namespace, externalID string) (string, error) {
+	conn, err := grpcutil.ServiceConnection(ctx, "metadata", g.registry)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	client := gen.NewMetadataServiceClient(conn)
+	resp, err := client.ResolveMovieID(ctx, &gen.ResolveMovieIDRequest{Namespace: namespace, ExternalId: externalID})
+	if err != nil {
+		return "", gatewayError(err)
+	}
+	return resp.MovieId, nil
+}
+
+// Search returns a page of movie metadata matching a query and the token of the next page.
+func (g *Gateway) Search(ctx context.Context, query string, pageSize int, pageToken string) ([]*model.Metadata, string, error) {
+	conn, err := grpcutil.ServiceConnection(ctx, "metadata", g.registry)
+	if err != nil {
+		return nil, "", err
+	}
+	defer conn.Close()
+	client := gen.NewMetadataServiceClient(conn)
+	resp, err := client.SearchMetadata(ctx, &gen.SearchMetadataRequest{Query: query, PageSize: int32(pageSize), PageToken: pageToken})
+	if err != nil {
+		return nil, "", gatewayError(err)
+	}
+	res := make([]*model.Metadata, 0, len(resp.Metadata))
+	for _, m := range resp.Metadata {
+		res = append(res, model.MetadataFromProto(m))
+	}
+	return res, resp.NextPageToken, nil
+}
+
+// gatewayError maps gRPC status codes to gateway errors.
+func gatewayError(err error) error {
+	switch status.Code(err) {
+	case codes.NotFound:
+		return gateway.ErrNotFound
+	case codes.InvalidArgument:
+		return fmt.Errorf("%w: %v", gateway.ErrInvalidArgument, status.Convert(err).Message())
+	}
+	return err
+}
@@ -4,13 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"math/rand"
 	"net/http"
+	"strconv"
 
+	"github.com/meirongdev/movie-microservice/gen"
 	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
 	"github.com/meirongdev/movie-microservice/movie/internal/gateway"
 	"github.com/meirongdev/movie-microservice/pkg/discovery"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
 // Gateway defines a movie metadata HTTP gateway.
@@ -25,7 +29,7 @@ func New(registry discovery.Registry) *Gateway {
 
 // Get gets movie metadata by a movie id.
 func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
-	addrs, err := g.registry.ServiceAddresses(ctx, "metadata")
+	addrs, err := g.registry.ServiceAddresses(ctx, "metadata", discovery.ProtocolHTTP)
 	if err != nil {
 		return nil, err
 	}
@@ -55,3 +59,86 @@ func (g *Gateway) Get(ctx context.Context, id string) (*model.Metadata, error) {
 	}
 	return v, nil
 }
+
+// Resolve returns the id of the movie with an external id in a namespace, using the REST/JSON
+// gateway of the metadata service.
+func (g *Gateway) Resolve(ctx context.Context, namespace, externalID string) (string, error) {
+	addrs, err := g.registry.ServiceAddresses(ctx, "metadata", discovery.ProtocolHTTP)
+	if err != nil {
+		return "", err
+	}
+	url := fmt.Sprintf("http://%s/v1/metadata:resolve", addrs[rand.Intn(len(addrs))])
+	log.Printf("Calling metadata service, Request: GET %s", url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	values := req.URL.Query()
+	values.Add("namespace", namespace)
+	values.Add("externalId", externalID)
+	req.URL.RawQuery = values.Encode()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", gateway.ErrNotFound
+	} else if resp.StatusCode == http.StatusBadRequest {
+		return "", gateway.ErrInvalidArgument
+	} else if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("non-2xx response: %v", resp)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var v gen.ResolveMovieIDResponse
+	if err := protojson.Unmarshal(body, &v); err != nil {
+		return "", err
+	}
+	return v.MovieId, nil
+}
+
+// Search returns a page of movie metadata matching a query and the token of the next page,
+// using the REST/JSON gateway of the metadata service.
+func (g *Gateway) Search(ctx context.Context, query string, pageSize int, pageToken string) ([]*model.Metadata, string, error) {
+	addrs, err := g.registry.ServiceAddresses(ctx, "metadata", discovery.ProtocolHTTP)
+	if err != nil {
+		return nil, "", err
+	}
+	url := fmt.Sprintf("http://%s/v1/metadata:search", addrs[rand.Intn(len(addrs))])
+	log.Printf("Calling metadata service, Request: GET %s", url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	values := req.URL.Query()
+	values.Add("query", query)
+	values.Add("pageSize", strconv.Itoa(pageSize))
+	values.Add("pageToken", pageToken)
+	req.URL.RawQuery = values.Encode()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusBadRequest {
+		return nil, "", gateway.ErrInvalidArgument
+	} else if resp.StatusCode/100 != 2 {
+		return nil, "", fmt.Errorf("non-2xx response: %v", resp)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	var v gen.SearchMetadataResponse
+	if err := protojson.Unmarshal(body, &v); err != nil {
+		return nil, "", err
+	}
+	res := make([]*model.Metadata, 0, len(v.Metadata))
+	for _, m := range v.Metadata {
+		res = append(res, model.MetadataFromProto(m))
+	}
+	return res, v.NextPageToken, nil
+}
@@ -5,8 +5,11 @@ import (
 
 	"github.com/meirongdev/movie-microservice/gen"
 	"github.com/meirongdev/movie-microservice/internal/grpcutil"
+	"github.com/meirongdev/movie-microservice/movie/internal/gateway"
 	"github.com/meirongdev/movie-microservice/pkg/discovery"
 	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Gateway defines an gRPC gateway for a rating service.
@@ -28,7 +31,9 @@ func (g *Gateway) GetAggregatedRating(ctx context.Context, recordID model.Record
 	defer conn.Close()
 	client := gen.NewRatingServiceClient(conn)
 	resp, err := client.GetAggregatedRating(ctx, &gen.GetAggregatedRatingRequest{RecordId: string(recordID), RecordType: string(recordType)})
-	if err != nil {
+	if err != nil && status.Code(err) == codes.NotFound {
+		return 0, gateway.ErrNotFound
+	} else if err != nil {
 		return 0, err
 	}
 	return resp.RatingValue, nil
@@ -25,7 +25,7 @@ func New(registry discovery.Registry) *Gateway {
 
 // GetAggregatedRating returns the aggregated rating for a record or ErrNotFound if there are no ratings for it.
 func (g *Gateway) GetAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType) (float64, error) {
-	addrs, err := g.registry.ServiceAddresses(ctx, "rating")
+	addrs, err := g.registry.ServiceAddresses(ctx, "rating", discovery.ProtocolHTTP)
 	if err != nil {
 		return 0, err
 	}
@@ -59,7 +59,7 @@ func (g *Gateway) GetAggregatedRating(ctx context.Context, recordID model.Record
 
 // PutRating writes a rating.
 func (g *Gateway) PutRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
-	addrs, err := g.registry.ServiceAddresses(ctx, "rating")
+	addrs, err := g.registry.ServiceAddresses(ctx, "rating", discovery.ProtocolHTTP)
 	if err != nil {
 		return err
 	}
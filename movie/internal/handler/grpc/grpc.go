@@ -5,8 +5,10 @@ import (
 	"errors"
 
 	"github.com/meirongdev/movie-microservice/gen"
+	"github.com/meirongdev/movie-microservice/internal/grpcutil"
 	"github.com/meirongdev/movie-microservice/metadata/pkg/model"
 	"github.com/meirongdev/movie-microservice/movie/internal/controller/movie"
+	moviemodel "github.com/meirongdev/movie-microservice/movie/pkg/model"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -22,21 +24,57 @@ func New(ctrl *movie.Controller) *Handler {
 	return &Handler{ctrl: ctrl}
 }
 
-// GetMovieDetails returns moviie details by id.
+// GetMovieDetails returns moviie details by id, or by an external id in a namespace, with the
+// title and description in the requested locale if translated.
 func (h *Handler) GetMovieDetails(ctx context.Context, req *gen.GetMovieDetailsRequest) (*gen.GetMovieDetailsResponse, error) {
-	if req == nil || req.MovieId == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
+	if req == nil || (req.MovieId == "") == (req.Namespace == "" || req.ExternalId == "") {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or not exactly one of id and namespace with external id")
+	}
+	locales, err := grpcutil.Locales(ctx, req.Locale)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+	var m *moviemodel.MovieDetails
+	if req.MovieId != "" {
+		m, err = h.ctrl.Get(ctx, req.MovieId, locales)
+	} else {
+		m, err = h.ctrl.GetByExternalID(ctx, req.Namespace, req.ExternalId, locales)
 	}
-	m, err := h.ctrl.Get(ctx, req.MovieId)
 	if err != nil && errors.Is(err, movie.ErrNotFound) {
 		return nil, status.Errorf(codes.NotFound, err.Error())
+	} else if err != nil && errors.Is(err, movie.ErrInvalidArgument) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
 	} else if err != nil {
 		return nil, status.Errorf(codes.Internal, err.Error())
 	}
-	return &gen.GetMovieDetailsResponse{
-		MovieDetails: &gen.MovieDetails{
-			Metadata: model.MetadataToProto(&m.Metadata),
-			Rating:   *m.Rating,
-		},
-	}, nil
+	details := &gen.MovieDetails{Metadata: model.MetadataToProto(&m.Metadata)}
+	if m.Rating != nil {
+		details.Rating = *m.Rating
+	}
+	if m.Poster != nil {
+		details.Poster = &gen.Poster{Url: m.Poster.URL, ThumbnailUrls: m.Poster.ThumbnailURLs}
+	}
+	return &gen.GetMovieDetailsResponse{MovieDetails: details, ServedLocale: m.Locale}, nil
+}
+
+// SearchMovies returns a page of movies matching a query, with their aggregated ratings.
+func (h *Handler) SearchMovies(ctx context.Context, req *gen.SearchMoviesRequest) (*gen.SearchMoviesResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+	movies, next, err := h.ctrl.Search(ctx, req.Query, int(req.PageSize), req.PageToken)
+	if err != nil && errors.Is(err, movie.ErrInvalidArgument) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	res := &gen.SearchMoviesResponse{NextPageToken: next}
+	for _, m := range movies {
+		details := &gen.MovieDetails{Metadata: model.MetadataToProto(&m.Metadata)}
+		if m.Rating != nil {
+			details.Rating = *m.Rating
+		}
+		res.Movies = append(res.Movies, details)
+	}
+	return res, nil
 }
@@ -7,6 +7,8 @@ import (
 	"net/http"
 
 	"github.com/meirongdev/movie-microservice/movie/internal/controller/movie"
+	"github.com/meirongdev/movie-microservice/movie/pkg/model"
+	"github.com/meirongdev/movie-microservice/pkg/locale"
 )
 
 // Handler defines a movie handler.
@@ -19,18 +21,41 @@ func New(ctrl *movie.Controller) *Handler {
 	return &Handler{ctrl}
 }
 
-// GetMovieDetails handles GET /movie requests.
+// GetMovieDetails handles GET /movie requests, by id or by namespace and externalId. The title and
+// description are served in the locale parameter or a locale of the Accept-Language header, if
+// translated, which is given as Content-Language.
 func (h *Handler) GetMovieDetails(w http.ResponseWriter, req *http.Request) {
-	id := req.FormValue("id")
-	details, err := h.ctrl.Get(req.Context(), id)
+	id, namespace, externalID := req.FormValue("id"), req.FormValue("namespace"), req.FormValue("externalId")
+	if (id == "") == (namespace == "" || externalID == "") {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	locales, err := locale.FromRequest(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var details *model.MovieDetails
+	if id != "" {
+		details, err = h.ctrl.Get(req.Context(), id, locales)
+	} else {
+		details, err = h.ctrl.GetByExternalID(req.Context(), namespace, externalID, locales)
+	}
 	if err != nil && errors.Is(err, movie.ErrNotFound) {
 		w.WriteHeader(http.StatusNotFound)
 		return
+	} else if err != nil && errors.Is(err, movie.ErrInvalidArgument) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	} else if err != nil {
 		log.Printf("Get error: %v\n", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("Vary", "Accept-Language")
+	if details.Locale != "" {
+		w.Header().Set("Content-Language", details.Locale)
+	}
 	if err := json.NewEncoder(w).Encode(details); err != nil {
 		log.Printf("Encode error: %v\n", err)
 	}
@@ -6,4 +6,15 @@ import "github.com/meirongdev/movie-microservice/metadata/pkg/model"
 type MovieDetails struct {
 	Rating   *float64       `json:"rating,omitempty"`
 	Metadata model.Metadata `json:"metadata"`
+	// Locale is the locale of the title and description, empty if they are not translated.
+	Locale string `json:"locale,omitempty"`
+	// Poster is set if the movie has a poster and asset URLs are configured.
+	Poster *Poster `json:"poster,omitempty"`
+}
+
+// Poster defines the URLs of the poster of a movie.
+type Poster struct {
+	URL string `json:"url"`
+	// ThumbnailURLs maps thumbnail sizes, e.g. "w185", to the URLs of the thumbnails.
+	ThumbnailURLs map[string]string `json:"thumbnailUrls"`
 }
@@ -1,15 +1,127 @@
 package config
 
-import "fmt"
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
 
 type MySQLConfig struct {
 	Host     string `yaml:"host"`
 	Username string `yaml:"username"`
 	Password string `yaml:"password"`
 	Database string `yaml:"database"`
+	// AutoMigrate applies pending schema migrations when the service starts.
+	AutoMigrate bool `yaml:"auto_migrate"`
+	// ParseTime scans DATE and DATETIME values into time.Time.
+	ParseTime bool `yaml:"parse_time"`
+	// Pool configures the connection pool. Zero values keep the database/sql defaults.
+	Pool MySQLPoolConfig `yaml:"pool"`
+	// Timeouts of dialing, reading and writing. Zero values mean no timeout.
+	ConnectTimeout time.Duration  `yaml:"connect_timeout"`
+	ReadTimeout    time.Duration  `yaml:"read_timeout"`
+	WriteTimeout   time.Duration  `yaml:"write_timeout"`
+	TLS            MySQLTLSConfig `yaml:"tls"`
+	// Retry configures how long services wait for the database on startup.
+	Retry MySQLRetryConfig `yaml:"retry"`
+	// Replicas are the DSNs of read replicas, which use the timeouts and TLS above unless
+	// their DSN sets them. Reads are spread over healthy replicas and writes go to the primary.
+	Replicas []string `yaml:"replicas"`
+	// ReplicaHealthInterval is the interval of replica health checks, 5s if zero.
+	ReplicaHealthInterval time.Duration `yaml:"replica_health_interval"`
+	// ReadYourWrites routes the reads of a request to the primary once the request has written.
+	ReadYourWrites bool `yaml:"read_your_writes"`
+}
+
+// MySQLPoolConfig configures a MySQL connection pool.
+type MySQLPoolConfig struct {
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+}
+
+// MySQLTLSConfig configures TLS for MySQL connections. Server certificates are verified against
+// CAFile, or the system roots if it is empty.
+type MySQLTLSConfig struct {
+	Enabled            bool   `yaml:"enabled"`
+	CAFile             string `yaml:"ca_file"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// MySQLRetryConfig configures retries of the initial connection. Attempts is the number of
+// connection attempts, and the backoff between them doubles from InitialBackoff up to MaxBackoff.
+type MySQLRetryConfig struct {
+	Attempts       int           `yaml:"attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+}
+
+// DriverConfig returns the MySQL driver configuration, loading the TLS CA file if configured.
+func (c MySQLConfig) DriverConfig() (*mysql.Config, error) {
+	cfg := mysql.NewConfig()
+	cfg.Net = "tcp"
+	cfg.Addr = c.Host
+	cfg.User = c.Username
+	cfg.Passwd = c.Password
+	cfg.DBName = c.Database
+	cfg.ParseTime = c.ParseTime
+	cfg.Timeout = c.ConnectTimeout
+	cfg.ReadTimeout = c.ReadTimeout
+	cfg.WriteTimeout = c.WriteTimeout
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.TLS = tlsConfig
+	return cfg, nil
+}
+
+// ReplicaDriverConfig returns the MySQL driver configuration of a replica DSN.
+func (c MySQLConfig) ReplicaDriverConfig(dsn string) (*mysql.Config, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ParseTime = cfg.ParseTime || c.ParseTime
+	if cfg.Timeout == 0 {
+		cfg.Timeout = c.ConnectTimeout
+	}
+	if cfg.ReadTimeout == 0 {
+		cfg.ReadTimeout = c.ReadTimeout
+	}
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = c.WriteTimeout
+	}
+	if cfg.TLSConfig == "" {
+		tlsConfig, err := c.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		cfg.TLS = tlsConfig
+	}
+	return cfg, nil
 }
 
-func (c MySQLConfig) FormatDSN() string {
-	// Format DSN string
-	return fmt.Sprintf("%s:%s@tcp(%s)/%s", c.Username, c.Password, c.Host, c.Database)
+func (c MySQLConfig) tlsConfig() (*tls.Config, error) {
+	if !c.TLS.Enabled {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{ServerName: c.TLS.ServerName, InsecureSkipVerify: c.TLS.InsecureSkipVerify}
+	if c.TLS.CAFile != "" {
+		pem, err := os.ReadFile(c.TLS.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in MySQL CA file " + c.TLS.CAFile)
+		}
+	}
+	return tlsConfig, nil
 }
@@ -0,0 +1,20 @@
+package config
+
+// StorageConfig selects the repository backend of a service: "mysql" (the default), "sqlite" or "memory".
+type StorageConfig struct {
+	Driver string       `yaml:"driver"`
+	SQLite SQLiteConfig `yaml:"sqlite"`
+}
+
+// SQLiteConfig configures a SQLite database.
+type SQLiteConfig struct {
+	// Path is the database file, or ":memory:" for an in-memory database.
+	Path string `yaml:"path"`
+	// AutoMigrate applies pending schema migrations when the service starts.
+	AutoMigrate bool `yaml:"auto_migrate"`
+}
+
+// FormatDSN returns the data source name of the SQLite database.
+func (c SQLiteConfig) FormatDSN() string {
+	return "file:" + c.Path + "?_busy_timeout=5000&_journal_mode=WAL"
+}
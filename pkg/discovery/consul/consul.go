@@ -28,7 +28,7 @@ func NewRegistry(addr string) (*Registry, error) {
 }
 
 // Register creates a service record in the registry.
-func (r *Registry) Register(ctx context.Context, instanceID string, serviceName string, hostPort string) error {
+func (r *Registry) Register(ctx context.Context, instanceID string, serviceName string, hostPort string, tags ...string) error {
 	parts := strings.Split(hostPort, ":")
 	if len(parts) != 2 {
 		return errors.New("hostPort must be in a form of <host>:<port>, example: localhost:8081")
@@ -42,6 +42,7 @@ func (r *Registry) Register(ctx context.Context, instanceID string, serviceName
 		ID:      instanceID,
 		Name:    serviceName,
 		Port:    port,
+		Tags:    tags,
 		Check:   &consul.AgentServiceCheck{CheckID: instanceID, TTL: "5s"},
 	})
 }
@@ -52,8 +53,8 @@ func (r *Registry) Deregister(ctx context.Context, instanceID string, _ string)
 }
 
 // ServiceAddresses returns the list of addresses of active instances of the given service.
-func (r *Registry) ServiceAddresses(ctx context.Context, serviceName string) ([]string, error) {
-	entries, _, err := r.client.Health().Service(serviceName, "", true, nil)
+func (r *Registry) ServiceAddresses(ctx context.Context, serviceName string, tags ...string) ([]string, error) {
+	entries, _, err := r.client.Health().ServiceMultipleTags(serviceName, tags, true, nil)
 	if err != nil {
 		return nil, err
 	} else if len(entries) == 0 {
@@ -10,16 +10,24 @@ import (
 
 // Registry defines a service registry.
 type Registry interface {
-	// Register creates a service instance record in the registry.
-	Register(ctx context.Context, instanceID string, serviceName string, hostPort string) error
+	// Register creates a service instance record in the registry. Optional tags are attached to the record
+	// and can be used to filter instances in ServiceAddresses.
+	Register(ctx context.Context, instanceID string, serviceName string, hostPort string, tags ...string) error
 	// Deregister removes a service insttance record from the registry.
 	Deregister(ctx context.Context, instanceID string, serviceName string) error
 	// ServiceAddresses returns the list of addresses of active instances of the given service.
-	ServiceAddresses(ctx context.Context, serviceID string) ([]string, error)
+	// If tags are provided, only instances registered with all of them are returned.
+	ServiceAddresses(ctx context.Context, serviceID string, tags ...string) ([]string, error)
 	// ReportHealthyState is a push mechanism for reporting healthy state to the registry.
 	ReportHealthyState(instanceID string, serviceName string) error
 }
 
+// Protocol tags used to distinguish endpoints of the same service.
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
 // ErrNotFound is returned when no service addresses are found.
 var ErrNotFound = errors.New("no service addresses found")
 
@@ -28,3 +36,8 @@ var ErrNotFound = errors.New("no service addresses found")
 func GenerateInstanceID(serviceName string) string {
 	return fmt.Sprintf("%s-%d", serviceName, rand.New(rand.NewSource(time.Now().UnixNano())).Int())
 }
+
+// ProtocolInstanceID returns the instance identifier of the endpoint serving the given protocol.
+func ProtocolInstanceID(instanceID string, protocol string) string {
+	return instanceID + "-" + protocol
+}
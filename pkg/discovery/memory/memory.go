@@ -3,6 +3,7 @@ package memory
 import (
 	"context"
 	"errors"
+	"slices"
 	"sync"
 	"time"
 
@@ -18,22 +19,32 @@ type Registry struct {
 
 type serviceInstance struct {
 	hostPort   string
+	tags       []string
 	lastActive time.Time
 }
 
+func (i *serviceInstance) hasTags(tags []string) bool {
+	for _, t := range tags {
+		if !slices.Contains(i.tags, t) {
+			return false
+		}
+	}
+	return true
+}
+
 // NewRegistry creates a new in-memory service registry instance.
 func NewRegistry() *Registry {
 	return &Registry{serviceAddrs: map[string]map[string]*serviceInstance{}}
 }
 
 // Register creates a service record in the registry.
-func (r *Registry) Register(ctx context.Context, instanceID string, serviceName string, hostPort string) error {
+func (r *Registry) Register(ctx context.Context, instanceID string, serviceName string, hostPort string, tags ...string) error {
 	r.Lock()
 	defer r.Unlock()
 	if _, ok := r.serviceAddrs[serviceName]; !ok {
 		r.serviceAddrs[serviceName] = map[string]*serviceInstance{}
 	}
-	r.serviceAddrs[serviceName][instanceID] = &serviceInstance{hostPort: hostPort, lastActive: time.Now()}
+	r.serviceAddrs[serviceName][instanceID] = &serviceInstance{hostPort: hostPort, tags: tags, lastActive: time.Now()}
 	return nil
 }
 
@@ -63,7 +74,7 @@ func (r *Registry) ReportHealthyState(instanceID string, serviceName string) err
 }
 
 // ServiceAddresses returns the list of addresses of active instances of the given service.
-func (r *Registry) ServiceAddresses(ctx context.Context, serviceName string) ([]string, error) {
+func (r *Registry) ServiceAddresses(ctx context.Context, serviceName string, tags ...string) ([]string, error) {
 	r.RLock()
 	defer r.RUnlock()
 	if len(r.serviceAddrs[serviceName]) == 0 {
@@ -71,10 +82,13 @@ func (r *Registry) ServiceAddresses(ctx context.Context, serviceName string) ([]
 	}
 	var res []string
 	for _, i := range r.serviceAddrs[serviceName] {
-		if i.lastActive.Before(time.Now().Add(-5 * time.Second)) {
+		if i.lastActive.Before(time.Now().Add(-5*time.Second)) || !i.hasTags(tags) {
 			continue
 		}
 		res = append(res, i.hostPort)
 	}
+	if len(res) == 0 {
+		return nil, discovery.ErrNotFound
+	}
 	return res, nil
 }
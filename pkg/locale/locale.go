@@ -0,0 +1,98 @@
+// Package locale negotiates the locales of localized content from BCP 47 language tags and
+// Accept-Language headers.
+package locale
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Canonical returns the canonical form of a BCP 47 locale, e.g. "pt-BR" for "pt_br".
+func Canonical(locale string) (string, error) {
+	tag, err := language.Parse(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	if err != nil || tag == language.Und {
+		return "", fmt.Errorf("invalid locale %q", locale)
+	}
+	return tag.String(), nil
+}
+
+// ParseAcceptLanguage returns the locales of an Accept-Language header, the most preferred first.
+// Wildcards and malformed entries are left out.
+func ParseAcceptLanguage(header string) []string {
+	type entry struct {
+		locale string
+		q      float32
+	}
+	var entries []entry
+	// Entries are parsed one at a time, so that a malformed entry does not discard the others.
+	for _, e := range strings.Split(header, ",") {
+		tags, q, err := language.ParseAcceptLanguage(e)
+		if err != nil || len(tags) == 0 {
+			continue
+		}
+		// Wildcards are parsed as "mul", multiple languages.
+		if s := tags[0].String(); tags[0] != language.Und && s != "mul" {
+			entries = append(entries, entry{s, q[0]})
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b entry) int { return cmp.Compare(b.q, a.q) })
+	var res []string
+	for _, e := range entries {
+		if !slices.Contains(res, e.locale) {
+			res = append(res, e.locale)
+		}
+	}
+	return res
+}
+
+// Chain returns the fallback chain of preferred locales, the most preferred first: each locale is
+// followed by its parents and its base language, e.g. pt-BR by pt and zh-TW by zh-Hant and zh.
+// Invalid locales are left out.
+func Chain(preferred ...string) []string {
+	var res []string
+	add := func(s string) {
+		if !slices.Contains(res, s) {
+			res = append(res, s)
+		}
+	}
+	for _, p := range preferred {
+		tag, err := language.Parse(strings.ReplaceAll(strings.TrimSpace(p), "_", "-"))
+		if err != nil || tag == language.Und {
+			continue
+		}
+		// The parent of a locale with a script other than the default one of its language, such
+		// as sr-Latn, is the root, so the base language is added after the parents.
+		base, confidence := tag.Base()
+		for ; tag != language.Und; tag = tag.Parent() {
+			add(tag.String())
+		}
+		if confidence == language.Exact {
+			add(base.String())
+		}
+	}
+	return res
+}
+
+// Negotiate returns the fallback chain of an explicitly requested locale or, if none is requested,
+// of the locales of an Accept-Language header.
+func Negotiate(requested, acceptLanguage string) ([]string, error) {
+	if requested == "" {
+		return Chain(ParseAcceptLanguage(acceptLanguage)...), nil
+	}
+	canonical, err := Canonical(requested)
+	if err != nil {
+		return nil, err
+	}
+	return Chain(canonical), nil
+}
+
+// FromRequest returns the fallback chain of the locales preferred by an HTTP request: its locale
+// parameter, or else its Accept-Language header.
+func FromRequest(r *http.Request) ([]string, error) {
+	return Negotiate(r.FormValue("locale"), r.Header.Get("Accept-Language"))
+}
@@ -0,0 +1,115 @@
+package locale
+
+import (
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		locale  string
+		want    string
+		wantErr bool
+	}{
+		{"pt-BR", "pt-BR", false},
+		{"pt_br", "pt-BR", false},
+		{" EN ", "en", false},
+		{"zh-hant-tw", "zh-Hant-TW", false},
+		{"", "", true},
+		{"und", "", true},
+		{"x_bad!!", "", true},
+	}
+	for _, tt := range tests {
+		got, err := Canonical(tt.locale)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("Canonical(%q): got %q, error %v, want %q", tt.locale, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", nil},
+		{"pt-BR", []string{"pt-BR"}},
+		{"de;q=0.5, fr, en;q=0.8", []string{"fr", "en", "de"}},
+		{"en, de;q=0.9, en-GB;q=0.9", []string{"en", "de", "en-GB"}},
+		{"*, de;q=0.5", []string{"de"}},
+		{"es;q=0, de", []string{"de"}},
+		{"en, en;q=0.5", []string{"en"}},
+		// Malformed entries are left out.
+		{"pt-BR, x_bad!!, en", []string{"pt-BR", "en"}},
+		{"en;q=abc, de", []string{"de"}},
+		{",,de,", []string{"de"}},
+	}
+	for _, tt := range tests {
+		if got := ParseAcceptLanguage(tt.header); !slices.Equal(got, tt.want) {
+			t.Errorf("ParseAcceptLanguage(%q): got %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	tests := []struct {
+		preferred []string
+		want      []string
+	}{
+		{nil, nil},
+		{[]string{"pt-BR"}, []string{"pt-BR", "pt"}},
+		{[]string{"pt_br", "en"}, []string{"pt-BR", "pt", "en"}},
+		{[]string{"en-GB", "en"}, []string{"en-GB", "en-001", "en"}},
+		{[]string{"zh-TW"}, []string{"zh-TW", "zh-Hant", "zh"}},
+		{[]string{"sr-Latn-RS"}, []string{"sr-Latn-RS", "sr-Latn", "sr"}},
+		{[]string{"x_bad!!", "und", "de"}, []string{"de"}},
+	}
+	for _, tt := range tests {
+		if got := Chain(tt.preferred...); !slices.Equal(got, tt.want) {
+			t.Errorf("Chain(%q): got %v, want %v", tt.preferred, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		requested, acceptLanguage string
+		want                      []string
+		wantErr                   bool
+	}{
+		{"", "", nil, false},
+		{"", "fr;q=0.5, pt-BR", []string{"pt-BR", "pt", "fr"}, false},
+		{"de_at", "fr", []string{"de-AT", "de"}, false},
+		{"x_bad!!", "fr", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := Negotiate(tt.requested, tt.acceptLanguage)
+		if !slices.Equal(got, tt.want) || (err != nil) != tt.wantErr {
+			t.Errorf("Negotiate(%q, %q): got %v, error %v, want %v", tt.requested, tt.acceptLanguage, got, err, tt.want)
+		}
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	tests := []struct {
+		target, acceptLanguage string
+		want                   []string
+		wantErr                bool
+	}{
+		{"/metadata?id=1", "", nil, false},
+		{"/metadata?id=1", "zh-TW, en;q=0.5", []string{"zh-TW", "zh-Hant", "zh", "en"}, false},
+		{"/metadata?id=1&locale=pt-BR", "en", []string{"pt-BR", "pt"}, false},
+		{"/metadata?id=1&locale=x_bad!!", "en", nil, true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.target, nil)
+		if tt.acceptLanguage != "" {
+			r.Header.Set("Accept-Language", tt.acceptLanguage)
+		}
+		got, err := FromRequest(r)
+		if !slices.Equal(got, tt.want) || (err != nil) != tt.wantErr {
+			t.Errorf("FromRequest(%s, %q): got %v, error %v, want %v", tt.target, tt.acceptLanguage, got, err, tt.want)
+		}
+	}
+}
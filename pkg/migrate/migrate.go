@@ -0,0 +1,324 @@
+// Package migrate applies versioned SQL schema migrations to MySQL and SQLite databases.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrLocked is returned when another runner holds the migration lock.
+var ErrLocked = errors.New("migrations are locked by another runner")
+
+// Migration defines a versioned schema change with the SQL statements to apply and revert it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status defines the state of a migration in a database.
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Load reads migrations from the files of a directory, named <version>_<name>.up.sql and
+// <version>_<name>.down.sql, and returns them ordered by version.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		match := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+	res := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		res = append(res, *m)
+	}
+	slices.SortFunc(res, func(a, b Migration) int { return a.Version - b.Version })
+	return res, nil
+}
+
+// Migrator applies and reverts migrations, recording the applied versions in a migrations table.
+// Runners are serialized with a database lock, so services can migrate on startup.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	config
+}
+
+type config struct {
+	table       string
+	lockTimeout time.Duration
+	locker      Locker
+}
+
+// Locker serializes migration runners on a database connection.
+type Locker interface {
+	Lock(ctx context.Context, conn *sql.Conn, name string, timeout time.Duration) error
+	// Unlock releases the lock after running migrations, which failed with err if it is not nil.
+	Unlock(ctx context.Context, conn *sql.Conn, name string, err error) error
+}
+
+// MySQLLocker locks migrations with a MySQL named lock. It is the default locker.
+type MySQLLocker struct{}
+
+// Lock acquires the named lock, waiting up to timeout.
+func (MySQLLocker) Lock(ctx context.Context, conn *sql.Conn, name string, timeout time.Duration) error {
+	var ok sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, int(timeout.Seconds())).Scan(&ok); err != nil {
+		return err
+	}
+	if ok.Int64 != 1 {
+		return ErrLocked
+	}
+	return nil
+}
+
+// Unlock releases the named lock. MySQL commits DDL statements implicitly, so the statements of a
+// failed migration which ran are not rolled back.
+func (MySQLLocker) Unlock(ctx context.Context, conn *sql.Conn, name string, _ error) error {
+	_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", name)
+	return err
+}
+
+// SQLiteLocker locks migrations by holding a write transaction, in which the migrations run, so a
+// failed run applies or reverts no migration. The wait for the lock is bounded by the busy timeout
+// of the connection.
+type SQLiteLocker struct{}
+
+// Lock begins an immediate transaction.
+func (SQLiteLocker) Lock(ctx context.Context, conn *sql.Conn, _ string, _ time.Duration) error {
+	_, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE")
+	return err
+}
+
+// Unlock commits the transaction, or rolls it back if the migrations failed, so that a failed
+// migration leaves no partial changes.
+func (SQLiteLocker) Unlock(ctx context.Context, conn *sql.Conn, _ string, err error) error {
+	if err != nil {
+		_, err = conn.ExecContext(ctx, "ROLLBACK")
+		return err
+	}
+	_, err = conn.ExecContext(ctx, "COMMIT")
+	return err
+}
+
+// Option configures a Migrator.
+type Option func(*config)
+
+// WithTable sets the name of the migrations table, schema_migrations by default.
+func WithTable(table string) Option {
+	return func(c *config) {
+		c.table = table
+	}
+}
+
+// WithLockTimeout sets how long to wait for the migration lock, 1 minute by default.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.lockTimeout = timeout
+	}
+}
+
+// WithLocker sets how migration runners are serialized, MySQLLocker by default.
+func WithLocker(locker Locker) Option {
+	return func(c *config) {
+		c.locker = locker
+	}
+}
+
+// New creates a new migrator for a database.
+func New(db *sql.DB, migrations []Migration, opts ...Option) *Migrator {
+	m := &Migrator{db: db, migrations: migrations, config: config{table: "schema_migrations", lockTimeout: time.Minute, locker: MySQLLocker{}}}
+	for _, opt := range opts {
+		opt(&m.config)
+	}
+	return m
+}
+
+// Close closes the database of the migrator.
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// Up applies all pending migrations in version order and returns the number applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	n := 0
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			log.Printf("Applying migration %d_%s\n", mig.Version, mig.Name)
+			record := fmt.Sprintf("INSERT INTO %s (version, name, applied_at) VALUES (?, ?, ?)", m.table)
+			if err := m.run(ctx, conn, mig, mig.Up, record, mig.Version, mig.Name, time.Now().Unix()); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Down reverts up to steps of the most recently applied migrations and returns the number reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	n := 0
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && n < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %d_%s cannot be reverted", mig.Version, mig.Name)
+			}
+			log.Printf("Reverting migration %d_%s\n", mig.Version, mig.Name)
+			record := fmt.Sprintf("DELETE FROM %s WHERE version = ?", m.table)
+			if err := m.run(ctx, conn, mig, mig.Down, record, mig.Version); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Status returns the state of all known migrations in version order.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var res []Status
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			at, ok := applied[mig.Version]
+			res = append(res, Status{Version: mig.Version, Name: mig.Name, Applied: ok, AppliedAt: at})
+		}
+		return nil
+	})
+	return res, err
+}
+
+// run executes the statements of a migration and records the change in the migrations table.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, mig Migration, statements string, record string, args ...any) error {
+	for _, stmt := range split(statements) {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+		}
+	}
+	_, err := conn.ExecContext(ctx, record, args...)
+	return err
+}
+
+// applied creates the migrations table if needed and returns the applied versions with their time.
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version BIGINT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at BIGINT NOT NULL)", m.table)); err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT version, applied_at FROM %s", m.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at int64
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		res[version] = time.Unix(at, 0)
+	}
+	return res, rows.Err()
+}
+
+// locked runs fn on a connection holding the migration lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := m.locker.Lock(ctx, conn, m.table, m.lockTimeout); err != nil {
+		return err
+	}
+	err = fn(conn)
+	return errors.Join(err, m.locker.Unlock(context.Background(), conn, m.table, err))
+}
+
+// split splits a migration file into statements terminated by a semicolon at the end of a line.
+func split(statements string) []string {
+	var res []string
+	var stmt strings.Builder
+	for _, line := range strings.Split(statements, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		stmt.WriteString(line)
+		stmt.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			res = append(res, strings.TrimSuffix(strings.TrimSpace(stmt.String()), ";"))
+			stmt.Reset()
+		}
+	}
+	if s := strings.TrimSpace(stmt.String()); s != "" {
+		res = append(res, s)
+	}
+	return res
+}
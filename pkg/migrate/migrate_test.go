@@ -0,0 +1,47 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSQLiteRollsBackFailedMigrations(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", t.TempDir()+"/test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ms := []Migration{
+		{Version: 1, Name: "create_movies", Up: "CREATE TABLE movies (id TEXT);", Down: "DROP TABLE movies;"},
+		{Version: 2, Name: "add_title", Up: "CREATE TABLE genres (name TEXT);\nALTER TABLE missing ADD COLUMN title TEXT;"},
+	}
+	m := New(db, ms, WithLocker(SQLiteLocker{}))
+	if _, err := m.Up(ctx); err == nil {
+		t.Fatal("Up with a failing migration: got no error")
+	}
+	for _, table := range []string{"movies", "genres"} {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = ?", table).Scan(&n); err != nil || n != 0 {
+			t.Errorf("table %s: got %d tables, error %v, want it rolled back", table, n, err)
+		}
+	}
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, s := range status {
+		if s.Applied {
+			t.Errorf("Status: got migration %d applied, want none", s.Version)
+		}
+	}
+
+	// The lock is released, so fixed migrations can be applied.
+	ms[1].Up = "CREATE TABLE genres (name TEXT);\nALTER TABLE movies ADD COLUMN title TEXT;"
+	if n, err := New(db, ms, WithLocker(SQLiteLocker{})).Up(ctx); err != nil || n != 2 {
+		t.Fatalf("Up: got %d, error %v, want 2", n, err)
+	}
+}
@@ -3,8 +3,11 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	commonConfig "github.com/meirongdev/movie-microservice/pkg/config"
+	"github.com/meirongdev/movie-microservice/rating/internal/controller/rating"
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
 	"gopkg.in/yaml.v3"
 )
 
@@ -13,15 +16,76 @@ type config struct {
 }
 
 type apiConfig struct {
-	Port        int                      `yaml:"port"`
-	MysqlConfig commonConfig.MySQLConfig `yaml:"mysql"`
-	KafkaConfig kafkaConfig              `yaml:"kafka"`
+	Port        int                        `yaml:"port"`
+	HTTPPort    int                        `yaml:"http_port"`
+	Storage     commonConfig.StorageConfig `yaml:"storage"`
+	MysqlConfig commonConfig.MySQLConfig   `yaml:"mysql"`
+	Ingester    ingesterConfig             `yaml:"ingester"`
+	KafkaConfig kafkaConfig                `yaml:"kafka"`
+	DeadLetter  deadLetterConfig           `yaml:"dead_letter"`
+	Providers   map[string]providerConfig  `yaml:"providers"`
+}
+
+// ingesterConfig selects the source of rating events: "kafka" (the default), or "file" to read
+// JSON-lines events from Path ("-" for stdin). The in-process memory ingester is test-only and rejected here.
+// Events are handled in batches of up to BatchSize events by Concurrency workers.
+type ingesterConfig struct {
+	Type          string        `yaml:"type"`
+	Path          string        `yaml:"path"`
+	BatchSize     int           `yaml:"batch_size"`
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	Concurrency   int           `yaml:"concurrency"`
 }
 
 type kafkaConfig struct {
-	Address string `yaml:"address"`
-	GroupID string `yaml:"group_id"`
-	Topic   string `yaml:"topic"`
+	Address     string        `yaml:"address"`
+	GroupID     string        `yaml:"group_id"`
+	Topic       string        `yaml:"topic"`
+	PollTimeout time.Duration `yaml:"poll_timeout"`
+}
+
+// deadLetterConfig configures where rating events that cannot be ingested are sent.
+// Type is "kafka", "file", or "drop" to log and drop them. If it is empty, failed writes are
+// retried until they succeed and invalid events stop the ingestion. MaxAttempts bounds the
+// attempts to write an event before it is dead-lettered or dropped.
+type deadLetterConfig struct {
+	Type        string `yaml:"type"`
+	Topic       string `yaml:"topic"`
+	Path        string `yaml:"path"`
+	MaxAttempts int    `yaml:"max_attempts"`
+}
+
+// providerConfig configures how ratings from a rating provider are accepted and weighted.
+// Providers are enabled unless Enabled is set to false. RecordTypes restricts the record types
+// the provider may rate, and Scale maps the provider's rating scale to the 1-5 scale.
+type providerConfig struct {
+	Enabled     *bool       `yaml:"enabled"`
+	Weight      float64     `yaml:"weight"`
+	RecordTypes []string    `yaml:"record_types"`
+	Scale       scaleConfig `yaml:"scale"`
+}
+
+type scaleConfig struct {
+	Min int `yaml:"min"`
+	Max int `yaml:"max"`
+}
+
+// providers converts the provider configuration to the rating controller's.
+func (c apiConfig) providers() map[string]rating.Provider {
+	res := make(map[string]rating.Provider, len(c.Providers))
+	for id, p := range c.Providers {
+		provider := rating.Provider{
+			Enabled:  p.Enabled == nil || *p.Enabled,
+			Weight:   p.Weight,
+			ScaleMin: model.RatingValue(p.Scale.Min),
+			ScaleMax: model.RatingValue(p.Scale.Max),
+		}
+		for _, t := range p.RecordTypes {
+			provider.RecordTypes = append(provider.RecordTypes, model.RecordType(t))
+		}
+		res[id] = provider
+	}
+	return res
 }
 
 func locaConfig(path string) (config, error) {
@@ -6,15 +6,27 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/meirongdev/movie-microservice/gen"
+	"github.com/meirongdev/movie-microservice/internal/grpcutil"
+	"github.com/meirongdev/movie-microservice/internal/mysqlutil"
 	"github.com/meirongdev/movie-microservice/pkg/discovery"
 	"github.com/meirongdev/movie-microservice/pkg/discovery/consul"
 	"github.com/meirongdev/movie-microservice/rating/internal/controller/rating"
+	"github.com/meirongdev/movie-microservice/rating/internal/deadletter"
+	deadletterfile "github.com/meirongdev/movie-microservice/rating/internal/deadletter/file"
+	deadletterkafka "github.com/meirongdev/movie-microservice/rating/internal/deadletter/kafka"
 	grpchandler "github.com/meirongdev/movie-microservice/rating/internal/handler/grpc"
+	httphandler "github.com/meirongdev/movie-microservice/rating/internal/handler/http"
+	"github.com/meirongdev/movie-microservice/rating/internal/ingester"
+	"github.com/meirongdev/movie-microservice/rating/internal/ingester/file"
 	"github.com/meirongdev/movie-microservice/rating/internal/ingester/kafka"
-	"github.com/meirongdev/movie-microservice/rating/internal/repository/mysql"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -29,59 +41,157 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	port := config.API.Port
-	log.Printf("Starting the rating service on port %d", port)
+	switch cmd := flag.Arg(0); cmd {
+	case "":
+	case "replay":
+		if err := replay(context.Background(), config, flag.Args()[1:]); err != nil {
+			log.Fatalf("Replay failed: %v", err)
+		}
+		return
+	case "migrate":
+		if err := runMigrations(context.Background(), config, flag.Args()[1:]); err != nil {
+			log.Fatalf("Migrate failed: %v", err)
+		}
+		return
+	case "publish":
+		if err := publish(context.Background(), config, flag.Args()[1:]); err != nil {
+			log.Fatalf("Publish failed: %v", err)
+		}
+		return
+	default:
+		log.Fatalf("Unknown command %q", cmd)
+	}
+	if err := run(config); err != nil {
+		log.Fatalf("The rating service failed: %v", err)
+	}
+}
+
+func run(cfg config) error {
+	// The service stops on SIGINT or SIGTERM, or if a server fails, once the ingestion stopped and
+	// the dead-letter sink was flushed.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%v", cfg.API.Port))
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	httpLis, err := net.Listen("tcp", fmt.Sprintf("localhost:%v", cfg.API.HTTPPort))
+	if err != nil {
+		lis.Close()
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	log.Printf("Starting the rating service on %s (gRPC) and %s (HTTP)", lis.Addr(), httpLis.Addr())
 	registry, err := consul.NewRegistry("localhost:8500")
 	if err != nil {
-		panic(err)
+		return err
 	}
-	ctx := context.Background()
 	instanceID := discovery.GenerateInstanceID(serviceName)
-	if err := registry.Register(ctx, instanceID, serviceName, fmt.Sprintf("localhost:%d", port)); err != nil {
-		panic(err)
+	// The ports are those of the listeners, which the system chooses if port 0 is configured.
+	endpoints := map[string]int{discovery.ProtocolGRPC: grpcutil.Port(lis), discovery.ProtocolHTTP: grpcutil.Port(httpLis)}
+	for protocol, p := range endpoints {
+		id := discovery.ProtocolInstanceID(instanceID, protocol)
+		if err := registry.Register(ctx, id, serviceName, fmt.Sprintf("localhost:%d", p), protocol); err != nil {
+			return err
+		}
+		defer registry.Deregister(context.Background(), id, serviceName)
 	}
 	go func() {
 		for {
-			if err := registry.ReportHealthyState(instanceID, serviceName); err != nil {
-				log.Println("Failed to report healthy state: " + err.Error())
+			for protocol := range endpoints {
+				if err := registry.ReportHealthyState(discovery.ProtocolInstanceID(instanceID, protocol), serviceName); err != nil {
+					log.Println("Failed to report healthy state: " + err.Error())
+				}
 			}
 			time.Sleep(1 * time.Second)
 		}
 	}()
-	defer registry.Deregister(ctx, instanceID, serviceName)
 
-	mysqlConfig := config.API.MysqlConfig
-	dsn := mysqlConfig.FormatDSN()
-	repo, err := mysql.New(dsn)
+	repo, err := newRepository(ctx, cfg.API)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	kafkaConfig := config.API.KafkaConfig
-	ing, err := kafka.NewIngester(kafkaConfig.Address, kafkaConfig.GroupID, kafkaConfig.Topic)
+	ing, err := newIngester(cfg.API)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	ctrl := rating.New(repo, rating.WithIngester(ing))
+	options := []rating.Option{rating.WithIngester(ing), rating.WithConcurrency(cfg.API.Ingester.Concurrency), rating.WithProviders(cfg.API.providers())}
+	switch cfg.API.DeadLetter.Type {
+	case "":
+	case "drop":
+		options = append(options, rating.WithDropRejected(cfg.API.DeadLetter.MaxAttempts))
+	default:
+		sink, err := newDeadLetterSink(cfg.API)
+		if err != nil {
+			return err
+		}
+		defer sink.Close()
+		options = append(options, rating.WithDeadLetterSink(sink, cfg.API.DeadLetter.MaxAttempts))
+	}
+	ctrl := rating.New(repo, options...)
+	ingestionDone := make(chan struct{})
 	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Println("recovered from panic: ", r)
-			}
-		}()
-		ingestErr := ctrl.StartIngestion(ctx)
-		if ingestErr != nil {
-			panic(ingestErr)
+		defer close(ingestionDone)
+		if err := ctrl.StartIngestion(ctx); err != nil {
+			log.Println("Ingestion stopped: " + err.Error())
 		}
 	}()
+	defer func() {
+		stop()
+		<-ingestionDone
+	}()
 	h := grpchandler.New(ctrl)
-	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%v", port))
-	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+
+	// The REST/JSON gateway generated from api/movie.proto calls the gRPC handler in-process.
+	gwmux := runtime.NewServeMux()
+	if err := gen.RegisterRatingServiceHandlerServer(ctx, gwmux, h); err != nil {
+		return err
 	}
-	srv := grpc.NewServer()
+	mux := http.NewServeMux()
+	httpHandler := httphandler.New(ctrl)
+	mux.Handle("/rating", http.HandlerFunc(httpHandler.Handle))
+	mux.Handle("/ingestion/stats", http.HandlerFunc(httpHandler.Stats))
+	mux.Handle("/v1/", gwmux)
+
+	// Requests run in sessions so that reads can follow their own writes with read replicas.
+	srv := grpc.NewServer(grpc.UnaryInterceptor(mysqlutil.UnarySessionInterceptor), grpc.StreamInterceptor(mysqlutil.StreamSessionInterceptor))
 	reflection.Register(srv)
 	gen.RegisterRatingServiceServer(srv, h)
-	if err := srv.Serve(lis); err != nil {
-		panic(err)
+	err = grpcutil.Serve(ctx, srv, lis, &http.Server{Handler: mysqlutil.SessionHandler(mux)}, httpLis)
+	log.Println("Shutting down the rating service")
+	return err
+}
+
+type ratingIngester interface {
+	Ingest(ctx context.Context, handler ingester.Handler) error
+}
+
+func newIngester(cfg apiConfig) (ratingIngester, error) {
+	switch cfg.Ingester.Type {
+	case "", "kafka":
+		kafkaConfig := cfg.KafkaConfig
+		return kafka.NewIngester(kafkaConfig.Address, kafkaConfig.GroupID, kafkaConfig.Topic,
+			kafka.WithBatchSize(cfg.Ingester.BatchSize), kafka.WithPollTimeout(kafkaConfig.PollTimeout),
+			kafka.WithFlushInterval(cfg.Ingester.FlushInterval))
+	case "file":
+		return file.NewIngester(cfg.Ingester.Path, cfg.Ingester.BatchSize), nil
+	case "memory":
+		// Nothing in the service publishes to the in-process ingester, which is meant for tests.
+		return nil, fmt.Errorf("ingester type %q is only available in tests", cfg.Ingester.Type)
+	}
+	return nil, fmt.Errorf("unsupported ingester type %q", cfg.Ingester.Type)
+}
+
+type deadLetterSink interface {
+	Write(ctx context.Context, record deadletter.Record) error
+	Close() error
+}
+
+func newDeadLetterSink(cfg apiConfig) (deadLetterSink, error) {
+	switch cfg.DeadLetter.Type {
+	case "kafka":
+		return deadletterkafka.NewSink(cfg.KafkaConfig.Address, cfg.DeadLetter.Topic)
+	case "file":
+		return deadletterfile.NewSink(cfg.DeadLetter.Path)
 	}
+	return nil, fmt.Errorf("unsupported dead-letter type %q", cfg.DeadLetter.Type)
 }
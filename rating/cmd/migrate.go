@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/meirongdev/movie-microservice/pkg/migrate"
+	"github.com/meirongdev/movie-microservice/rating/internal/repository/mysql"
+	"github.com/meirongdev/movie-microservice/rating/internal/repository/sqlite"
+)
+
+// runMigrations runs the migrate command (up, down or status) against the rating database
+// selected by storage.driver.
+func runMigrations(ctx context.Context, cfg config, args []string) error {
+	var m *migrate.Migrator
+	var err error
+	switch driver := cfg.API.Storage.Driver; driver {
+	case "", "mysql":
+		m, err = mysql.NewMigrator(ctx, cfg.API.MysqlConfig)
+	case "sqlite":
+		m, err = sqlite.NewMigrator(cfg.API.Storage.SQLite.FormatDSN())
+	default:
+		return fmt.Errorf("storage driver %q has no migrations", driver)
+	}
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	return migrate.Run(ctx, m, args)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+	"github.com/meirongdev/movie-microservice/rating/pkg/producer"
+)
+
+// publish publishes the JSON-lines rating events of the -file file ("-" for stdin) to the configured
+// rating topic. Lines that cannot be decoded or published are reported and skipped.
+func publish(ctx context.Context, cfg config, args []string) error {
+	fs := flag.NewFlagSet("publish", flag.ExitOnError)
+	path := fs.String("file", "-", "JSON-lines file of rating events to publish, - for stdin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var r io.Reader = os.Stdin
+	if *path != "-" {
+		f, err := os.Open(*path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	p, err := producer.New(cfg.API.KafkaConfig.Address, cfg.API.KafkaConfig.Topic)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	scanner := bufio.NewScanner(r)
+	var published, failed int
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		event, err := decodeEvent(scanner.Bytes())
+		if err == nil {
+			err = p.Publish(ctx, event)
+		}
+		if err != nil {
+			log.Printf("Line %d: %v\n", line, err)
+			failed++
+			continue
+		}
+		published++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	log.Printf("Published %d rating events, %d failed\n", published, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d rating events could not be published", failed)
+	}
+	return nil
+}
+
+// decodeEvent decodes and validates a JSON rating event, so that invalid lines are reported
+// without being sent to the broker.
+func decodeEvent(line []byte) (model.RatingEvent, error) {
+	var event model.RatingEvent
+	if err := json.Unmarshal(line, &event); err != nil {
+		return event, err
+	}
+	return event, event.Validate()
+}
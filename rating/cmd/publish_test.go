@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+)
+
+func TestDecodeEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want error
+	}{
+		{"valid", `{"userId":"u1","recordId":"m1","recordType":"movie","value":4}`, nil},
+		{"empty user id", `{"recordId":"m1","recordType":"movie","value":4}`, model.ErrInvalidEvent},
+		{"empty record id", `{"userId":"u1","recordType":"movie","value":4}`, model.ErrInvalidEvent},
+		{"empty record type", `{"userId":"u1","recordId":"m1","value":4}`, model.ErrInvalidEvent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeEvent([]byte(tt.line)); !errors.Is(err, tt.want) {
+				t.Fatalf("decodeEvent: got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+	for _, line := range []string{`not json`, `{"userId":1}`, `{"value":"4"}`} {
+		if _, err := decodeEvent([]byte(line)); err == nil || errors.Is(err, model.ErrInvalidEvent) {
+			t.Errorf("decodeEvent of %s: got error %v, want a decoding error", line, err)
+		}
+	}
+}
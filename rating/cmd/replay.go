@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+
+	"github.com/meirongdev/movie-microservice/rating/internal/controller/rating"
+	deadletterfile "github.com/meirongdev/movie-microservice/rating/internal/deadletter/file"
+	deadletterkafka "github.com/meirongdev/movie-microservice/rating/internal/deadletter/kafka"
+)
+
+// replay re-feeds dead-lettered rating events into the controller. It replays the dead-letter file
+// given with -file, or the configured dead-letter file or topic otherwise. Events failing again
+// are dead-lettered to the -failed file if given, or else to the configured dead-letter sink,
+// unless it is the replayed file, in which case they go to <file>.failed.
+func replay(ctx context.Context, cfg config, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	path := fs.String("file", "", "dead-letter file to replay (default the configured dead-letter file or topic)")
+	failedPath := fs.String("failed", "", "file receiving events that fail again (default the configured dead-letter sink, or <file>.failed)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	repo, err := newRepository(ctx, cfg.API)
+	if err != nil {
+		return err
+	}
+	deadLetter := cfg.API.DeadLetter
+	options := []rating.Option{rating.WithProviders(cfg.API.providers())}
+	switch {
+	case *path != "":
+		options = append(options, rating.WithIngester(deadletterfile.NewReader(*path)))
+	case deadLetter.Type == "file":
+		*path = deadLetter.Path
+		options = append(options, rating.WithIngester(deadletterfile.NewReader(*path)))
+	case deadLetter.Type == "kafka":
+		kafkaConfig := cfg.API.KafkaConfig
+		reader, err := deadletterkafka.NewReader(kafkaConfig.Address, kafkaConfig.GroupID+"-replay", deadLetter.Topic)
+		if err != nil {
+			return err
+		}
+		options = append(options, rating.WithIngester(reader))
+	default:
+		return errors.New("no dead-letter file given with -file and no dead-letter file or topic configured")
+	}
+	var sink deadLetterSink
+	switch {
+	case *failedPath != "":
+		sink, err = deadletterfile.NewSink(*failedPath)
+	case deadLetter.Type == "kafka" || (deadLetter.Type == "file" && deadLetter.Path != *path):
+		sink, err = newDeadLetterSink(cfg.API)
+	default:
+		sink, err = deadletterfile.NewSink(*path + ".failed")
+	}
+	if err != nil {
+		return err
+	}
+	defer sink.Close()
+	options = append(options, rating.WithDeadLetterSink(sink, deadLetter.MaxAttempts))
+	log.Println("Replaying dead-lettered rating events")
+	return rating.New(repo, options...).StartIngestion(ctx)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/meirongdev/movie-microservice/rating/internal/repository/memory"
+	"github.com/meirongdev/movie-microservice/rating/internal/repository/mysql"
+	"github.com/meirongdev/movie-microservice/rating/internal/repository/sqlite"
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+)
+
+// ratingRepository is the repository interface of the rating controller.
+type ratingRepository interface {
+	Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error)
+	Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error
+	PutBatch(ctx context.Context, ratings []model.Rating) error
+	ForEach(ctx context.Context, recordType model.RecordType, fn func(rating model.Rating) error) error
+}
+
+// newRepository creates the repository selected by storage.driver, applying pending
+// migrations first if enabled.
+func newRepository(ctx context.Context, cfg apiConfig) (ratingRepository, error) {
+	switch cfg.Storage.Driver {
+	case "", "mysql":
+		if cfg.MysqlConfig.AutoMigrate {
+			m, err := mysql.NewMigrator(ctx, cfg.MysqlConfig)
+			if err != nil {
+				return nil, err
+			}
+			defer m.Close()
+			if _, err := m.Up(ctx); err != nil {
+				return nil, err
+			}
+		}
+		return mysql.New(ctx, cfg.MysqlConfig)
+	case "sqlite":
+		repo, err := sqlite.New(cfg.Storage.SQLite.FormatDSN())
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Storage.SQLite.AutoMigrate {
+			if err := repo.Migrate(ctx); err != nil {
+				return nil, err
+			}
+		}
+		return repo, nil
+	case "memory":
+		return memory.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+}
@@ -3,8 +3,10 @@ package rating
 import (
 	"context"
 	"errors"
-	"log"
+	"time"
 
+	"github.com/meirongdev/movie-microservice/rating/internal/deadletter"
+	"github.com/meirongdev/movie-microservice/rating/internal/ingester"
 	"github.com/meirongdev/movie-microservice/rating/internal/repository"
 	"github.com/meirongdev/movie-microservice/rating/pkg/model"
 )
@@ -15,20 +17,34 @@ var ErrNotFound = errors.New("ratings not found for a record")
 type ratingRepository interface {
 	Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error)
 	Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error
+	PutBatch(ctx context.Context, ratings []model.Rating) error
+	ForEach(ctx context.Context, recordType model.RecordType, fn func(rating model.Rating) error) error
 }
 
 type ratingIngester interface {
-	Ingest(ctx context.Context) (chan model.RatingEvent, error)
+	Ingest(ctx context.Context, handler ingester.Handler) error
+}
+
+type deadLetterSink interface {
+	Write(ctx context.Context, record deadletter.Record) error
 }
 
 // Controller defines a rating service controller.
 type Controller struct {
 	repo ratingRepository
 	config
+	stats ingestionStats
 }
 
 type config struct {
-	ingester ratingIngester
+	ingester          ratingIngester
+	retryInitialDelay time.Duration
+	retryMaxDelay     time.Duration
+	deadLetter        deadLetterSink
+	drop              bool
+	maxAttempts       int
+	concurrency       int
+	providers         map[string]Provider
 }
 
 type Option func(*config)
@@ -39,9 +55,50 @@ func WithIngester(ingester ratingIngester) Option {
 	}
 }
 
+// WithRetryBackoff sets the exponential backoff used to retry failed writes of ingested events.
+func WithRetryBackoff(initial time.Duration, maxDelay time.Duration) Option {
+	return func(c *config) {
+		c.retryInitialDelay = initial
+		c.retryMaxDelay = maxDelay
+	}
+}
+
+// WithDeadLetterSink sets the sink receiving ingested events that cannot be decoded or validated,
+// or whose write still fails after maxAttempts attempts. Without a sink or WithDropRejected,
+// failed writes are retried until the ingestion is cancelled, and invalid events stop the
+// ingestion, so that their offsets are not committed.
+func WithDeadLetterSink(sink deadLetterSink, maxAttempts int) Option {
+	return func(c *config) {
+		c.deadLetter = sink
+		c.maxAttempts = max(maxAttempts, 1)
+	}
+}
+
+// WithDropRejected logs and drops ingested events that cannot be decoded or validated, or whose
+// write still fails after maxAttempts attempts, instead of dead-lettering them. Dropped events
+// are lost.
+func WithDropRejected(maxAttempts int) Option {
+	return func(c *config) {
+		c.drop = true
+		c.maxAttempts = max(maxAttempts, 1)
+	}
+}
+
+// WithConcurrency sets the number of workers writing ingested events. Events are assigned to
+// workers by record, so the events of a record are still written in order.
+func WithConcurrency(workers int) Option {
+	return func(c *config) {
+		c.concurrency = max(workers, 1)
+	}
+}
+
 // New creates a rating service controller.
 func New(repo ratingRepository, options ...Option) *Controller {
-	c := &Controller{repo, config{}}
+	c := &Controller{repo: repo, config: config{
+		retryInitialDelay: 100 * time.Millisecond,
+		retryMaxDelay:     10 * time.Second,
+		concurrency:       1,
+	}}
 	for _, o := range options {
 		o(&c.config)
 	}
@@ -49,37 +106,66 @@ func New(repo ratingRepository, options ...Option) *Controller {
 }
 
 // GetAggregatedRating returns the aggregated rating for a record or ErrNotFound if there are no ratings for it.
-func (c *Controller) GetAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType) (float64, error) {
+// Ratings are weighted by the weight of their provider. If providerID is not empty, only the ratings from
+// that provider are aggregated.
+func (c *Controller) GetAggregatedRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, providerID string) (float64, error) {
 	ratings, err := c.repo.Get(ctx, recordID, recordType)
 	if err != nil && err == repository.ErrNotFound {
 		return 0, ErrNotFound
 	} else if err != nil {
 		return 0, err
 	}
-	sum := float64(0)
+	var sum, weights float64
 	for _, r := range ratings {
-		sum += float64(r.Value)
+		if providerID != "" && r.ProviderID != providerID {
+			continue
+		}
+		w := c.provider(r.ProviderID).weight()
+		sum += w * float64(r.Value)
+		weights += w
 	}
-	return sum / float64(len(ratings)), nil
+	if weights == 0 {
+		return 0, ErrNotFound
+	}
+	return sum / weights, nil
 }
 
-// PutRating writes a rating for a given record.
+// PutRating writes a rating for a given record. The rating is checked and its value mapped according
+// to the configuration of its provider, and ErrProviderRejected is returned if it is not accepted.
 func (c *Controller) PutRating(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
+	if err := c.provider(rating.ProviderID).apply(recordType, rating); err != nil {
+		return err
+	}
 	return c.repo.Put(ctx, recordID, recordType, rating)
 }
 
-// StartIngestion starts the ingestion of rating events.
-func (s *Controller) StartIngestion(ctx context.Context) error {
-	ch, err := s.ingester.Ingest(ctx)
-	if err != nil {
-		return err
-	}
-	log.Println("Started ingestion")
-	for e := range ch {
-		if err := s.PutRating(ctx, e.RecordID, e.RecordType, &model.Rating{UserID: e.UserID, Value: e.Value}); err != nil {
-			return err
+// BatchFailure defines a rating of a batch which was not written.
+type BatchFailure struct {
+	// Index is the index of the rating in the batch.
+	Index int
+	Err   error
+}
+
+// PutRatings writes a batch of ratings, each for the record given by its RecordID and RecordType, in
+// a single transaction. Ratings are checked and mapped as by PutRating, and rejected ratings are
+// left out of the transaction and returned as failures in batch order.
+func (c *Controller) PutRatings(ctx context.Context, ratings []model.Rating) ([]BatchFailure, error) {
+	var failures []BatchFailure
+	batch := make([]model.Rating, 0, len(ratings))
+	for i, rating := range ratings {
+		if err := c.provider(rating.ProviderID).apply(model.RecordType(rating.RecordType), &rating); err != nil {
+			failures = append(failures, BatchFailure{i, err})
+			continue
 		}
+		batch = append(batch, rating)
 	}
-	log.Println("Stopped ingestion")
-	return nil
+	if err := c.repo.PutBatch(ctx, batch); err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+// ExportRatings calls fn for every rating of a record type and stops at the first error of fn.
+func (c *Controller) ExportRatings(ctx context.Context, recordType model.RecordType, fn func(rating model.Rating) error) error {
+	return c.repo.ForEach(ctx, recordType, fn)
 }
@@ -0,0 +1,95 @@
+package rating
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+)
+
+var testProviders = map[string]Provider{
+	"imdb":     {Enabled: true, Weight: 3, ScaleMin: 1, ScaleMax: 10},
+	"disabled": {Enabled: false},
+	"movies":   {Enabled: true, RecordTypes: []model.RecordType{"movie"}},
+}
+
+func TestGetAggregatedRating(t *testing.T) {
+	repo := &fakeRepository{ratings: []model.Rating{
+		{RecordID: "m1", RecordType: "movie", UserID: "u1", Value: 5},
+		{RecordID: "m1", RecordType: "movie", UserID: "u2", Value: 1, ProviderID: "imdb"},
+		{RecordID: "m1", RecordType: "movie", UserID: "u3", Value: 5, ProviderID: "disabled"},
+		{RecordID: "m2", RecordType: "movie", UserID: "u1", Value: 4, ProviderID: "disabled"},
+	}}
+	c := New(repo, WithProviders(testProviders))
+	tests := []struct {
+		name       string
+		recordID   model.RecordID
+		providerID string
+		want       float64
+		wantErr    error
+	}{
+		// (1*5 + 3*1) / (1 + 3); ratings of disabled providers are left out.
+		{"weighted", "m1", "", 2, nil},
+		{"provider", "m1", "imdb", 1, nil},
+		{"unknown provider", "m1", "other", 0, ErrNotFound},
+		{"disabled provider", "m1", "disabled", 0, ErrNotFound},
+		{"zero weight", "m2", "", 0, ErrNotFound},
+		{"no ratings", "m3", "", 0, ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.GetAggregatedRating(context.Background(), tt.recordID, "movie", tt.providerID)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Fatalf("GetAggregatedRating: got error %v, want %v", err, tt.wantErr)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("GetAggregatedRating: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPutRating(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeRepository{}
+	c := New(repo, WithProviders(testProviders))
+	if err := c.PutRating(ctx, "m1", "movie", &model.Rating{UserID: "u1", Value: 10, ProviderID: "imdb"}); err != nil {
+		t.Fatalf("PutRating: %v", err)
+	}
+	if len(repo.ratings) != 1 || repo.ratings[0].Value != 5 {
+		t.Fatalf("PutRating: got ratings %+v, want a value mapped to 5", repo.ratings)
+	}
+	for _, r := range []model.Rating{{UserID: "u1", Value: 3, ProviderID: "disabled"}, {UserID: "u1", Value: 11, ProviderID: "imdb"}} {
+		if err := c.PutRating(ctx, "m1", "movie", &r); !errors.Is(err, ErrProviderRejected) {
+			t.Errorf("PutRating of %+v: got error %v, want %v", r, err, ErrProviderRejected)
+		}
+	}
+	if err := c.PutRating(ctx, "s1", "series", &model.Rating{UserID: "u1", Value: 3, ProviderID: "movies"}); !errors.Is(err, ErrProviderRejected) {
+		t.Errorf("PutRating of a record type not allowed: got error %v, want %v", err, ErrProviderRejected)
+	}
+	if len(repo.ratings) != 1 {
+		t.Fatalf("got %d ratings written, want 1", len(repo.ratings))
+	}
+}
+
+func TestPutRatings(t *testing.T) {
+	repo := &fakeRepository{}
+	c := New(repo, WithProviders(testProviders))
+	failures, err := c.PutRatings(context.Background(), []model.Rating{
+		{RecordID: "m1", RecordType: "movie", UserID: "u1", Value: 1, ProviderID: "imdb"},
+		{RecordID: "m1", RecordType: "movie", UserID: "u2", Value: 3, ProviderID: "disabled"},
+		{RecordID: "s1", RecordType: "series", UserID: "u3", Value: 3, ProviderID: "movies"},
+		{RecordID: "m1", RecordType: "movie", UserID: "u4", Value: 2},
+	})
+	if err != nil {
+		t.Fatalf("PutRatings: %v", err)
+	}
+	if len(failures) != 2 || failures[0].Index != 1 || failures[1].Index != 2 {
+		t.Fatalf("PutRatings: got failures %+v, want ratings 1 and 2", failures)
+	}
+	if len(repo.ratings) != 2 || repo.ratings[0].Value != 1 || repo.ratings[1].UserID != "u4" {
+		t.Fatalf("PutRatings: got ratings %+v, want those of u1 and u4", repo.ratings)
+	}
+}
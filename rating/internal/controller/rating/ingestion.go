@@ -0,0 +1,213 @@
+package rating
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/meirongdev/movie-microservice/rating/internal/deadletter"
+	"github.com/meirongdev/movie-microservice/rating/internal/ingester"
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+)
+
+// IngestionStats defines statistics of the rating ingestion.
+type IngestionStats struct {
+	// Processed is the number of events written.
+	Processed uint64 `json:"processed"`
+	// Rejected is the number of events that could not be ingested, dead-lettered or dropped.
+	Rejected uint64 `json:"rejected"`
+	// Batches is the number of batches received from the ingester.
+	Batches uint64 `json:"batches"`
+	// Throughput is the number of events written per second since the ingestion started.
+	Throughput float64 `json:"throughput"`
+	// Lag is the time between the production and the write of the latest written event.
+	Lag time.Duration `json:"lag"`
+}
+
+type ingestionStats struct {
+	started   atomic.Int64
+	processed atomic.Uint64
+	rejected  atomic.Uint64
+	batches   atomic.Uint64
+	lag       atomic.Int64
+}
+
+// IngestionStats returns statistics of the rating ingestion.
+func (s *Controller) IngestionStats() IngestionStats {
+	res := IngestionStats{
+		Processed: s.stats.processed.Load(),
+		Rejected:  s.stats.rejected.Load(),
+		Batches:   s.stats.batches.Load(),
+		Lag:       time.Duration(s.stats.lag.Load()),
+	}
+	if started := s.stats.started.Load(); started != 0 {
+		res.Throughput = float64(res.Processed) / time.Since(time.Unix(0, started)).Seconds()
+	}
+	return res
+}
+
+// StartIngestion starts the ingestion of rating events. It blocks until the context is cancelled
+// or the ingester stops.
+func (s *Controller) StartIngestion(ctx context.Context) error {
+	log.Println("Started ingestion")
+	s.stats.started.Store(time.Now().UnixNano())
+	err := s.ingester.Ingest(ctx, s.handleEvents)
+	log.Println("Stopped ingestion")
+	return err
+}
+
+// handleEvents writes the ratings of a batch of ingested events. Events are spread over the
+// workers by record, and each worker writes its events as one batch. Events that cannot be
+// ingested are dead-lettered or dropped as configured, or fail the batch otherwise.
+func (s *Controller) handleEvents(ctx context.Context, msgs []ingester.Message) error {
+	s.stats.batches.Add(1)
+	partitions := make([][]ingester.Message, s.concurrency)
+	for _, m := range msgs {
+		err := m.Err
+		if err == nil {
+			err = m.Event.Validate()
+		}
+		if err == nil {
+			err = s.applyProvider(&m.Event)
+		}
+		if err != nil {
+			if err := s.reject(ctx, m, err, 1); err != nil {
+				return err
+			}
+			continue
+		}
+		p := partition(m.Event, s.concurrency)
+		partitions[p] = append(partitions[p], m)
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(partitions))
+	for i, msgs := range partitions {
+		if len(msgs) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.writeMessages(ctx, msgs)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// writeMessages writes the ratings of messages with a single batch write, retried with backoff.
+// If the batch keeps failing, the messages are written one at a time so that only the failing
+// events are dead-lettered.
+func (s *Controller) writeMessages(ctx context.Context, msgs []ingester.Message) error {
+	ratings := make([]model.Rating, len(msgs))
+	for i, m := range msgs {
+		ratings[i] = ratingFromEvent(m.Event)
+	}
+	_, err := s.retry(ctx, "batch of rating events", func() error {
+		return s.repo.PutBatch(ctx, ratings)
+	})
+	if err == nil {
+		s.recordWritten(msgs...)
+		return nil
+	} else if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	for i, m := range msgs {
+		attempts, err := s.retry(ctx, fmt.Sprintf("rating event %q", m.Event.ID), func() error {
+			return s.repo.Put(ctx, m.Event.RecordID, m.Event.RecordType, &ratings[i])
+		})
+		if err == nil {
+			s.recordWritten(m)
+			continue
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := s.reject(ctx, m, err, attempts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retry calls fn with exponential backoff until it succeeds, the context is cancelled or
+// maxAttempts is reached, if set. It returns the number of attempts made.
+func (s *Controller) retry(ctx context.Context, what string, fn func() error) (int, error) {
+	delay := s.retryInitialDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return attempt, nil
+		}
+		if s.maxAttempts > 0 && attempt >= s.maxAttempts {
+			return attempt, err
+		}
+		log.Printf("Failed to write %s (attempt %d), retrying in %v: %v\n", what, attempt, delay, err)
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, s.retryMaxDelay)
+	}
+}
+
+// reject dead-letters or drops a message that could not be ingested. Without a sink and unless
+// rejected events are dropped, it returns an error, so that the offset of the message is not
+// committed.
+func (s *Controller) reject(ctx context.Context, m ingester.Message, err error, attempts int) error {
+	switch {
+	case s.deadLetter != nil:
+		s.stats.rejected.Add(1)
+		log.Printf("Dead-lettering rating event at partition %d offset %d after %d attempt(s): %v\n", m.Partition, m.Offset, m.Attempts+attempts, err)
+		return s.deadLetter.Write(ctx, deadletter.NewRecord(m, err, attempts))
+	case s.drop:
+		s.stats.rejected.Add(1)
+		log.Printf("Dropping rating event at partition %d offset %d after %d attempt(s): %v\n", m.Partition, m.Offset, m.Attempts+attempts, err)
+		return nil
+	default:
+		return fmt.Errorf("rating event at partition %d offset %d cannot be ingested: %w", m.Partition, m.Offset, err)
+	}
+}
+
+func (s *Controller) recordWritten(msgs ...ingester.Message) {
+	s.stats.processed.Add(uint64(len(msgs)))
+	if last := msgs[len(msgs)-1]; !last.Timestamp.IsZero() {
+		s.stats.lag.Store(int64(time.Since(last.Timestamp)))
+	}
+}
+
+// applyProvider checks that an event is accepted from its provider and maps its value to the
+// canonical rating scale.
+func (s *Controller) applyProvider(e *model.RatingEvent) error {
+	r := ratingFromEvent(*e)
+	if err := s.provider(e.ProviderID).apply(e.RecordType, &r); err != nil {
+		return err
+	}
+	e.Value = r.Value
+	return nil
+}
+
+// partition returns the worker handling the events of the record the event is about.
+func partition(e model.RatingEvent, workers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(e.RecordType))
+	h.Write([]byte{0})
+	h.Write([]byte(e.RecordID))
+	return int(h.Sum32() % uint32(workers))
+}
+
+func ratingFromEvent(e model.RatingEvent) model.Rating {
+	return model.Rating{
+		RecordID:   string(e.RecordID),
+		RecordType: string(e.RecordType),
+		UserID:     e.UserID,
+		Value:      e.Value,
+		ProviderID: e.ProviderID,
+		EventID:    e.ID,
+	}
+}
@@ -0,0 +1,241 @@
+package rating
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/meirongdev/movie-microservice/rating/internal/deadletter"
+	"github.com/meirongdev/movie-microservice/rating/internal/ingester"
+	"github.com/meirongdev/movie-microservice/rating/internal/repository"
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+)
+
+var errWrite = errors.New("write failed")
+
+// fakeRepository records the ratings written to it. Batch writes fail if failBatch is set, and
+// writes of the events in failEvents always fail.
+type fakeRepository struct {
+	mu         sync.Mutex
+	ratings    []model.Rating
+	batches    int
+	failBatch  bool
+	failEvents map[string]bool
+}
+
+func (r *fakeRepository) Get(_ context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var res []model.Rating
+	for _, rating := range r.ratings {
+		if rating.RecordID == string(recordID) && rating.RecordType == string(recordType) {
+			res = append(res, rating)
+		}
+	}
+	if len(res) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return res, nil
+}
+
+func (r *fakeRepository) Put(_ context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failEvents[rating.EventID] {
+		return errWrite
+	}
+	rating.RecordID, rating.RecordType = string(recordID), string(recordType)
+	r.ratings = append(r.ratings, *rating)
+	return nil
+}
+
+func (r *fakeRepository) PutBatch(_ context.Context, ratings []model.Rating) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches++
+	if r.failBatch || slices.ContainsFunc(ratings, func(rating model.Rating) bool { return r.failEvents[rating.EventID] }) {
+		return errWrite
+	}
+	r.ratings = append(r.ratings, ratings...)
+	return nil
+}
+
+func (r *fakeRepository) ForEach(_ context.Context, _ model.RecordType, _ func(rating model.Rating) error) error {
+	return nil
+}
+
+// fakeIngester passes its batches to the handler in order.
+type fakeIngester [][]model.RatingEvent
+
+func (i fakeIngester) Ingest(ctx context.Context, handler ingester.Handler) error {
+	var offset int64
+	for _, events := range i {
+		msgs := make([]ingester.Message, len(events))
+		for j, e := range events {
+			msgs[j] = ingester.Message{Event: e, Offset: offset}
+			offset++
+		}
+		if err := handler(ctx, msgs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type fakeSink struct {
+	mu      sync.Mutex
+	records []deadletter.Record
+}
+
+func (s *fakeSink) Write(_ context.Context, record deadletter.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+func newEvent(id string, recordID model.RecordID, value model.RatingValue) model.RatingEvent {
+	return model.RatingEvent{ID: id, UserID: "u-" + model.UserID(id), RecordID: recordID, RecordType: "movie", Value: value, EventType: "put"}
+}
+
+func TestIngestionKeepsRecordOrder(t *testing.T) {
+	var batches fakeIngester
+	for b := range 5 {
+		var batch []model.RatingEvent
+		for i := range 20 {
+			n := b*20 + i
+			batch = append(batch, newEvent(fmt.Sprint(n), model.RecordID(fmt.Sprint(n%7)), model.RatingValue(n)))
+		}
+		batches = append(batches, batch)
+	}
+	repo := &fakeRepository{}
+	c := New(repo, WithIngester(batches), WithConcurrency(4))
+	if err := c.StartIngestion(context.Background()); err != nil {
+		t.Fatalf("StartIngestion: %v", err)
+	}
+	if len(repo.ratings) != 100 {
+		t.Fatalf("got %d ratings written, want 100", len(repo.ratings))
+	}
+	last := map[string]model.RatingValue{}
+	for _, r := range repo.ratings {
+		if v, ok := last[r.RecordID]; ok && r.Value < v {
+			t.Fatalf("record %s: rating %d written after %d", r.RecordID, r.Value, v)
+		}
+		last[r.RecordID] = r.Value
+	}
+	if stats := c.IngestionStats(); stats.Processed != 100 || stats.Rejected != 0 || stats.Batches != 5 {
+		t.Fatalf("IngestionStats: got %+v, want 100 processed in 5 batches", stats)
+	}
+}
+
+func TestIngestionFallsBackToItemWrites(t *testing.T) {
+	for _, failBatch := range []bool{true, false} {
+		t.Run(fmt.Sprintf("failBatch=%v", failBatch), func(t *testing.T) {
+			repo := &fakeRepository{failBatch: failBatch, failEvents: map[string]bool{"2": true}}
+			sink := &fakeSink{}
+			events := fakeIngester{{newEvent("1", "m1", 3), newEvent("2", "m1", 4), newEvent("3", "m1", 5)}}
+			c := New(repo, WithIngester(events), WithRetryBackoff(time.Millisecond, time.Millisecond), WithDeadLetterSink(sink, 2))
+			if err := c.StartIngestion(context.Background()); err != nil {
+				t.Fatalf("StartIngestion: %v", err)
+			}
+			var written []string
+			for _, r := range repo.ratings {
+				written = append(written, r.EventID)
+			}
+			if !slices.Equal(written, []string{"1", "3"}) {
+				t.Fatalf("got events %v written, want [1 3]", written)
+			}
+			// The batch is retried twice before each event is written on its own.
+			if repo.batches != 2 {
+				t.Errorf("got %d batch writes, want 2", repo.batches)
+			}
+			if len(sink.records) != 1 || sink.records[0].Offset != 1 || sink.records[0].Attempts != 2 {
+				t.Fatalf("got dead-letter records %+v, want event 2 after 2 attempts", sink.records)
+			}
+			if stats := c.IngestionStats(); stats.Processed != 2 || stats.Rejected != 1 {
+				t.Fatalf("IngestionStats: got %+v, want 2 processed and 1 rejected", stats)
+			}
+		})
+	}
+}
+
+func TestIngestionDropsRejected(t *testing.T) {
+	repo := &fakeRepository{failEvents: map[string]bool{"1": true}}
+	events := fakeIngester{{newEvent("1", "m1", 3), newEvent("2", "", 3)}, {newEvent("3", "m1", 4)}}
+	c := New(repo, WithIngester(events), WithRetryBackoff(time.Millisecond, time.Millisecond), WithDropRejected(3))
+	if err := c.StartIngestion(context.Background()); err != nil {
+		t.Fatalf("StartIngestion: %v", err)
+	}
+	if len(repo.ratings) != 1 || repo.ratings[0].EventID != "3" {
+		t.Fatalf("got ratings %+v, want event 3 only", repo.ratings)
+	}
+	if stats := c.IngestionStats(); stats.Processed != 1 || stats.Rejected != 2 {
+		t.Fatalf("IngestionStats: got %+v, want 1 processed and 2 rejected", stats)
+	}
+}
+
+func TestIngestionWithoutSink(t *testing.T) {
+	t.Run("failed writes are retried", func(t *testing.T) {
+		repo := &fakeRepository{failEvents: map[string]bool{"1": true}}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		c := New(repo, WithIngester(fakeIngester{{newEvent("1", "m1", 3)}, {newEvent("2", "m1", 4)}}), WithRetryBackoff(time.Millisecond, time.Millisecond))
+		if err := c.StartIngestion(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("StartIngestion: got error %v, want %v", err, context.DeadlineExceeded)
+		}
+		if repo.batches < 5 || len(repo.ratings) != 0 {
+			t.Fatalf("got %d batch writes and ratings %+v, want the failing batch retried until cancellation", repo.batches, repo.ratings)
+		}
+		if stats := c.IngestionStats(); stats.Rejected != 0 {
+			t.Fatalf("IngestionStats: got %+v, want nothing rejected", stats)
+		}
+	})
+	t.Run("invalid events stop the ingestion", func(t *testing.T) {
+		repo := &fakeRepository{}
+		c := New(repo, WithIngester(fakeIngester{{newEvent("1", "m1", 3), newEvent("2", "", 3)}, {newEvent("3", "m1", 4)}}))
+		if err := c.StartIngestion(context.Background()); err == nil {
+			t.Fatal("StartIngestion: got no error, want the invalid event reported")
+		}
+		if len(repo.ratings) > 1 || c.IngestionStats().Batches != 1 {
+			t.Fatalf("got ratings %+v after %d batches, want the ingestion stopped at the first batch", repo.ratings, c.IngestionStats().Batches)
+		}
+	})
+}
+
+func TestIngestionRejectsInvalidEvents(t *testing.T) {
+	repo := &fakeRepository{}
+	sink := &fakeSink{}
+	invalid := newEvent("2", "", 4)
+	disabled := newEvent("3", "m1", 4)
+	disabled.ProviderID = "disabled"
+	events := fakeIngester{{newEvent("1", "m1", 3), invalid, disabled}}
+	c := New(repo, WithIngester(events), WithDeadLetterSink(sink, 5),
+		WithProviders(map[string]Provider{"disabled": {Enabled: false}}))
+	if err := c.StartIngestion(context.Background()); err != nil {
+		t.Fatalf("StartIngestion: %v", err)
+	}
+	if len(repo.ratings) != 1 || repo.ratings[0].EventID != "1" {
+		t.Fatalf("got ratings %+v, want event 1 only", repo.ratings)
+	}
+	if len(sink.records) != 2 || sink.records[0].Attempts != 1 || sink.records[1].Attempts != 1 {
+		t.Fatalf("got dead-letter records %+v, want 2 records after 1 attempt", sink.records)
+	}
+}
+
+func TestIngestionCancelled(t *testing.T) {
+	repo := &fakeRepository{failBatch: true, failEvents: map[string]bool{"1": true}}
+	sink := &fakeSink{}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	c := New(repo, WithIngester(fakeIngester{{newEvent("1", "m1", 3)}}), WithRetryBackoff(time.Hour, time.Hour), WithDeadLetterSink(sink, 5))
+	if err := c.StartIngestion(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("StartIngestion: got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if len(sink.records) != 0 {
+		t.Fatalf("got dead-letter records %+v after cancellation, want none", sink.records)
+	}
+}
@@ -0,0 +1,76 @@
+package rating
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+)
+
+// ErrProviderRejected is returned when a rating is not accepted from its provider.
+var ErrProviderRejected = errors.New("rating rejected for provider")
+
+// Provider defines how ratings from a rating provider are accepted and weighted.
+// Ratings from providers without a configuration, including ratings without a provider,
+// are accepted for all record types with a weight of 1.
+type Provider struct {
+	// Enabled reports whether ratings from the provider are accepted and aggregated.
+	Enabled bool
+	// Weight is the weight of the provider's ratings in aggregated ratings, 1 if zero.
+	Weight float64
+	// RecordTypes restricts the record types the provider may rate. Empty means all types.
+	RecordTypes []model.RecordType
+	// ScaleMin and ScaleMax define the scale of the provider's rating values, which are mapped to the
+	// model.MinRatingValue-model.MaxRatingValue scale on ingestion. Zero values mean no mapping.
+	ScaleMin model.RatingValue
+	ScaleMax model.RatingValue
+}
+
+var defaultProvider = Provider{Enabled: true, Weight: 1}
+
+// WithProviders sets the configuration of rating providers, keyed by provider id.
+func WithProviders(providers map[string]Provider) Option {
+	return func(c *config) {
+		c.providers = providers
+	}
+}
+
+func (c *config) provider(id string) Provider {
+	if p, ok := c.providers[id]; ok {
+		return p
+	}
+	return defaultProvider
+}
+
+// weight returns the weight of a provider's ratings in aggregated ratings.
+func (p Provider) weight() float64 {
+	switch {
+	case !p.Enabled:
+		return 0
+	case p.Weight == 0:
+		return 1
+	}
+	return p.Weight
+}
+
+// apply checks that a rating of the given record type is accepted from the provider, and maps
+// its value to the canonical rating scale.
+func (p Provider) apply(recordType model.RecordType, rating *model.Rating) error {
+	if !p.Enabled {
+		return fmt.Errorf("%w %q: provider disabled", ErrProviderRejected, rating.ProviderID)
+	}
+	if len(p.RecordTypes) > 0 && !slices.Contains(p.RecordTypes, recordType) {
+		return fmt.Errorf("%w %q: record type %q not allowed", ErrProviderRejected, rating.ProviderID, recordType)
+	}
+	if p.ScaleMin == 0 && p.ScaleMax == 0 {
+		return nil
+	}
+	if p.ScaleMax <= p.ScaleMin || rating.Value < p.ScaleMin || rating.Value > p.ScaleMax {
+		return fmt.Errorf("%w %q: value %d out of scale %d-%d", ErrProviderRejected, rating.ProviderID, rating.Value, p.ScaleMin, p.ScaleMax)
+	}
+	ratio := float64(rating.Value-p.ScaleMin) / float64(p.ScaleMax-p.ScaleMin)
+	rating.Value = model.MinRatingValue + model.RatingValue(math.Round(ratio*float64(model.MaxRatingValue-model.MinRatingValue)))
+	return nil
+}
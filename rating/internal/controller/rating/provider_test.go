@@ -0,0 +1,68 @@
+package rating
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+)
+
+func TestProviderApply(t *testing.T) {
+	imdb := Provider{Enabled: true, RecordTypes: []model.RecordType{"movie"}, ScaleMin: 1, ScaleMax: 10}
+	tests := []struct {
+		name       string
+		provider   Provider
+		recordType model.RecordType
+		value      model.RatingValue
+		want       model.RatingValue
+		wantErr    bool
+	}{
+		{"default", defaultProvider, "series", 3, 3, false},
+		{"disabled", Provider{}, "movie", 3, 0, true},
+		{"allowed record type", imdb, "movie", 1, 1, false},
+		{"record type not allowed", imdb, "series", 1, 0, true},
+		{"scale minimum", imdb, "movie", 1, 1, false},
+		{"scale maximum", imdb, "movie", 10, 5, false},
+		{"scale rounding", imdb, "movie", 5, 3, false},
+		{"scale rounding up", imdb, "movie", 7, 4, false},
+		{"below scale", imdb, "movie", 0, 0, true},
+		{"above scale", imdb, "movie", 11, 0, true},
+		{"empty scale", Provider{Enabled: true, ScaleMin: 5, ScaleMax: 5}, "movie", 5, 0, true},
+		{"percentages", Provider{Enabled: true, ScaleMin: 0, ScaleMax: 100}, "movie", 50, 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := model.Rating{ProviderID: "p", Value: tt.value}
+			err := tt.provider.apply(tt.recordType, &r)
+			if tt.wantErr {
+				if !errors.Is(err, ErrProviderRejected) {
+					t.Fatalf("apply: got error %v, want %v", err, ErrProviderRejected)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+			if r.Value != tt.want {
+				t.Fatalf("apply: got value %d, want %d", r.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestProviderWeight(t *testing.T) {
+	tests := []struct {
+		provider Provider
+		want     float64
+	}{
+		{defaultProvider, 1},
+		{Provider{Enabled: true}, 1},
+		{Provider{Enabled: true, Weight: 2.5}, 2.5},
+		{Provider{Enabled: false, Weight: 2.5}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.provider.weight(); got != tt.want {
+			t.Errorf("weight of %+v: got %v, want %v", tt.provider, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package deadletter
+
+import (
+	"time"
+
+	"github.com/meirongdev/movie-microservice/rating/internal/ingester"
+)
+
+// Record defines a rating event that could not be ingested.
+type Record struct {
+	// Payload is the raw event as read from the ingestion source, and ContentType its encoding.
+	Payload     []byte    `json:"payload"`
+	ContentType string    `json:"contentType,omitempty"`
+	Partition   int32     `json:"partition"`
+	Offset      int64     `json:"offset"`
+	Error       string    `json:"error"`
+	Attempts    int       `json:"attempts"`
+	Time        time.Time `json:"time"`
+}
+
+// NewRecord creates a dead-letter record for a message that failed after the given number of attempts,
+// which add to the attempts made before the message was dead-lettered, if it was replayed.
+func NewRecord(msg ingester.Message, err error, attempts int) Record {
+	return Record{
+		Payload:     msg.Payload,
+		ContentType: msg.ContentType,
+		Partition:   msg.Partition,
+		Offset:      msg.Offset,
+		Error:       err.Error(),
+		Attempts:    msg.Attempts + attempts,
+		Time:        time.Now().UTC(),
+	}
+}
+
+// Message converts a dead-letter record back into an ingestion message, decoding its payload again.
+// The message keeps the attempts of the record.
+func (r Record) Message() ingester.Message {
+	m := ingester.NewMessage(r.Payload, r.ContentType, r.Partition, r.Offset, r.Time)
+	m.Attempts = r.Attempts
+	return m
+}
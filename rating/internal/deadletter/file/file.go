@@ -0,0 +1,78 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/meirongdev/movie-microservice/rating/internal/deadletter"
+	"github.com/meirongdev/movie-microservice/rating/internal/ingester"
+)
+
+// Sink defines a dead-letter sink appending records as JSON lines to a local file.
+type Sink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewSink creates a new file dead-letter sink, creating the file if it does not exist.
+func NewSink(path string) (*Sink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Sink{file: f}, nil
+}
+
+// Write appends a record to the file.
+func (s *Sink) Write(_ context.Context, record deadletter.Record) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Close closes the underlying file.
+func (s *Sink) Close() error {
+	return s.file.Close()
+}
+
+// Reader defines an ingester replaying the records of a file written by Sink.
+type Reader struct {
+	path string
+}
+
+// NewReader creates a new reader of a dead-letter file.
+func NewReader(path string) *Reader {
+	return &Reader{path}
+}
+
+// Ingest passes the records of the file to the handler one at a time and returns once the
+// whole file was handled.
+func (r *Reader) Ingest(ctx context.Context, handler ingester.Handler) error {
+	f, err := os.Open(r.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() && ctx.Err() == nil {
+		var record deadletter.Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return err
+		}
+		if err := handler(ctx, []ingester.Message{record.Message()}); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
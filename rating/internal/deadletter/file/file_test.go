@@ -0,0 +1,102 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/meirongdev/movie-microservice/rating/internal/deadletter"
+	"github.com/meirongdev/movie-microservice/rating/internal/ingester"
+	"github.com/meirongdev/movie-microservice/rating/pkg/event"
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+)
+
+func TestSinkReaderRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+	e := model.RatingEvent{ID: "e1", UserID: "u1", RecordID: "m1", RecordType: "movie", Value: 4, EventType: "put"}
+	payload, err := event.Encode(event.NewEnvelope(e, "test", time.Time{}), event.ContentTypeProtobuf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []ingester.Message{
+		ingester.NewMessage(payload, event.ContentTypeProtobuf, 3, 42, time.Now()),
+		ingester.NewMessage([]byte("not json"), "", 1, 7, time.Now()),
+	}
+	// Records are appended to the file across sinks.
+	for i, m := range msgs {
+		sink, err := NewSink(path)
+		if err != nil {
+			t.Fatalf("NewSink: %v", err)
+		}
+		if err := sink.Write(ctx, deadletter.NewRecord(m, errors.New("write failed"), i+1)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	}
+
+	var got []ingester.Message
+	err = NewReader(path).Ingest(ctx, func(_ context.Context, batch []ingester.Message) error {
+		got = append(got, batch...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	if len(got) != len(msgs) {
+		t.Fatalf("Ingest: got %d messages, want %d", len(got), len(msgs))
+	}
+	for i, m := range got {
+		if string(m.Payload) != string(msgs[i].Payload) || m.ContentType != msgs[i].ContentType ||
+			m.Partition != msgs[i].Partition || m.Offset != msgs[i].Offset {
+			t.Errorf("message %d: got %+v, want %+v", i, m, msgs[i])
+		}
+		if m.Attempts != i+1 {
+			t.Errorf("message %d: got %d attempts, want %d", i, m.Attempts, i+1)
+		}
+	}
+	if got[0].Err != nil || got[0].Event != e {
+		t.Errorf("replayed event: got %+v, error %v, want %+v", got[0].Event, got[0].Err, e)
+	}
+	if got[1].Err == nil {
+		t.Error("replayed malformed payload: got no error")
+	}
+}
+
+func TestReplayedAttemptsAccumulate(t *testing.T) {
+	r := deadletter.Record{Payload: []byte("{}"), Attempts: 5}
+	if got := deadletter.NewRecord(r.Message(), errors.New("write failed"), 3).Attempts; got != 8 {
+		t.Fatalf("attempts of a replayed record dead-lettered again: got %d, want 8", got)
+	}
+}
+
+func TestReaderStopsOnHandlerError(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+	sink, err := NewSink(path)
+	if err != nil {
+		t.Fatalf("NewSink: %v", err)
+	}
+	for range 3 {
+		if err := sink.Write(ctx, deadletter.Record{Payload: []byte("{}")}); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	sink.Close()
+	want := errors.New("handler failed")
+	calls := 0
+	err = NewReader(path).Ingest(ctx, func(context.Context, []ingester.Message) error {
+		calls++
+		return want
+	})
+	if !errors.Is(err, want) || calls != 1 {
+		t.Fatalf("Ingest: got error %v after %d call(s), want %v after 1", err, calls, want)
+	}
+	if err := NewReader(filepath.Join(t.TempDir(), "missing.jsonl")).Ingest(ctx, nil); err == nil {
+		t.Fatal("Ingest of a missing file: got no error")
+	}
+}
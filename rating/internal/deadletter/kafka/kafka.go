@@ -0,0 +1,150 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/meirongdev/movie-microservice/rating/internal/deadletter"
+	"github.com/meirongdev/movie-microservice/rating/internal/ingester"
+)
+
+// timeoutMs bounds metadata queries and producer flushes.
+const timeoutMs = 10000
+
+// Sink defines a dead-letter sink producing records as JSON to a Kafka topic.
+type Sink struct {
+	producer *kafka.Producer
+	topic    string
+}
+
+// NewSink creates a new Kafka dead-letter sink.
+func NewSink(addr string, topic string) (*Sink, error) {
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": addr})
+	if err != nil {
+		return nil, err
+	}
+	return &Sink{producer, topic}, nil
+}
+
+// Write produces a record to the topic and waits for its delivery.
+func (s *Sink) Write(ctx context.Context, record deadletter.Record) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	delivery := make(chan kafka.Event, 1)
+	if err := s.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &s.topic, Partition: kafka.PartitionAny},
+		Value:          b,
+	}, delivery); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case e := <-delivery:
+		if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+			return m.TopicPartition.Error
+		}
+		return nil
+	}
+}
+
+// Close flushes pending records and closes the producer.
+func (s *Sink) Close() error {
+	s.producer.Flush(timeoutMs)
+	s.producer.Close()
+	return nil
+}
+
+// Reader defines an ingester replaying the records of a dead-letter topic.
+type Reader struct {
+	consumer *kafka.Consumer
+	topic    string
+}
+
+// NewReader creates a new reader of a dead-letter topic. The reader commits its progress
+// under the given consumer group, so replayed records are not replayed again.
+func NewReader(addr string, groupID string, topic string) (*Reader, error) {
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":  addr,
+		"group.id":           groupID,
+		"enable.auto.commit": false,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{consumer, topic}, nil
+}
+
+// Ingest passes the records of the topic to the handler one at a time. Only records present
+// when the replay started are read, so records dead-lettered again by the replay itself are
+// left for the next one.
+func (r *Reader) Ingest(ctx context.Context, handler ingester.Handler) error {
+	defer r.consumer.Close()
+	end, err := r.assign()
+	if err != nil {
+		return err
+	}
+	for len(end) > 0 && ctx.Err() == nil {
+		msg, err := r.consumer.ReadMessage(time.Second)
+		var kafkaErr kafka.Error
+		if errors.As(err, &kafkaErr) && kafkaErr.IsTimeout() {
+			continue
+		} else if err != nil {
+			return err
+		}
+		tp := msg.TopicPartition
+		var record deadletter.Record
+		if err := json.Unmarshal(msg.Value, &record); err != nil {
+			return fmt.Errorf("decode dead-letter record at partition %d offset %d: %w", tp.Partition, tp.Offset, err)
+		}
+		if err := handler(ctx, []ingester.Message{record.Message()}); err != nil {
+			return err
+		}
+		if _, err := r.consumer.CommitMessage(msg); err != nil {
+			return err
+		}
+		if int64(tp.Offset)+1 >= end[tp.Partition] {
+			delete(end, tp.Partition)
+		}
+	}
+	return nil
+}
+
+// assign assigns all partitions of the topic to the consumer, starting from the committed
+// offsets, and returns the end offset of each partition that has records left to replay.
+func (r *Reader) assign() (map[int32]int64, error) {
+	md, err := r.consumer.GetMetadata(&r.topic, false, timeoutMs)
+	if err != nil {
+		return nil, err
+	}
+	var partitions []kafka.TopicPartition
+	for _, p := range md.Topics[r.topic].Partitions {
+		partitions = append(partitions, kafka.TopicPartition{Topic: &r.topic, Partition: p.ID})
+	}
+	committed, err := r.consumer.Committed(partitions, timeoutMs)
+	if err != nil {
+		return nil, err
+	}
+	end := map[int32]int64{}
+	for i, tp := range committed {
+		low, high, err := r.consumer.QueryWatermarkOffsets(r.topic, tp.Partition, timeoutMs)
+		if err != nil {
+			return nil, err
+		}
+		start := int64(tp.Offset)
+		if tp.Offset < 0 {
+			start = low
+			committed[i].Offset = kafka.OffsetBeginning
+		}
+		if start < high {
+			end[tp.Partition] = high
+		}
+	}
+	return end, r.consumer.Assign(committed)
+}
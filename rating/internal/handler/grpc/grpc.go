@@ -1,10 +1,14 @@
 package grpc
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"io"
+	"slices"
 
 	"github.com/meirongdev/movie-microservice/gen"
+	"github.com/meirongdev/movie-microservice/internal/grpcutil"
 	"github.com/meirongdev/movie-microservice/rating/internal/controller/rating"
 	"github.com/meirongdev/movie-microservice/rating/pkg/model"
 	"google.golang.org/grpc/codes"
@@ -27,7 +31,7 @@ func (h *Handler) GetAggregatedRating(ctx context.Context, req *gen.GetAggregate
 	if req == nil || req.RecordId == "" || req.RecordType == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
 	}
-	v, err := h.ctrl.GetAggregatedRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType))
+	v, err := h.ctrl.GetAggregatedRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType), req.ProviderId)
 	if err != nil && errors.Is(err, rating.ErrNotFound) {
 		return nil, status.Errorf(codes.NotFound, err.Error())
 	} else if err != nil {
@@ -41,8 +45,93 @@ func (h *Handler) PutRating(ctx context.Context, req *gen.PutRatingRequest) (*ge
 	if req == nil || req.RecordId == "" || req.UserId == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty user id or record id")
 	}
-	if err := h.ctrl.PutRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType), &model.Rating{UserID: model.UserID(req.UserId), Value: model.RatingValue(req.RatingValue)}); err != nil {
+	r := &model.Rating{UserID: model.UserID(req.UserId), Value: model.RatingValue(req.RatingValue), ProviderID: req.ProviderId, EventID: req.EventId}
+	err := h.ctrl.PutRating(ctx, model.RecordID(req.RecordId), model.RecordType(req.RecordType), r)
+	if err != nil && errors.Is(err, rating.ErrProviderRejected) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	} else if err != nil {
 		return nil, err
 	}
 	return &gen.PutRatingResponse{}, nil
 }
+
+// bulkBatchSize is the number of ratings of bulk writes written per transaction.
+const bulkBatchSize = 500
+
+// BulkPutRatings writes a stream of ratings in transactional batches. Ratings that fail are reported
+// in the response; other errors abort the stream, keeping the batches written before. Ratings with
+// an event id are written once, so that streams can be retried.
+func (h *Handler) BulkPutRatings(stream gen.RatingService_BulkPutRatingsServer) error {
+	ctx := stream.Context()
+	res := &gen.BulkPutRatingsResponse{}
+	var batch []model.Rating
+	// indexes are the positions of the ratings of the batch in the stream.
+	var indexes []int64
+	flush := func() error {
+		failures, err := h.ctrl.PutRatings(ctx, batch)
+		if err != nil {
+			return status.Errorf(codes.Internal, err.Error())
+		}
+		for _, f := range failures {
+			res.Failures = append(res.Failures, grpcutil.BulkPutFailure(indexes[f.Index], batch[f.Index].RecordID, status.Errorf(codes.InvalidArgument, f.Err.Error())))
+		}
+		res.Written += int64(len(batch) - len(failures))
+		batch, indexes = batch[:0], indexes[:0]
+		return nil
+	}
+	for index := int64(0); ; index++ {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if req.RecordId == "" || req.RecordType == "" || req.UserId == "" {
+			res.Failures = append(res.Failures, grpcutil.BulkPutFailure(index, req.RecordId, status.Errorf(codes.InvalidArgument, "empty user id, record id or record type")))
+			continue
+		}
+		batch = append(batch, model.Rating{
+			RecordID:   req.RecordId,
+			RecordType: req.RecordType,
+			UserID:     model.UserID(req.UserId),
+			Value:      model.RatingValue(req.RatingValue),
+			ProviderID: req.ProviderId,
+			EventID:    req.EventId,
+		})
+		indexes = append(indexes, index)
+		if len(batch) < bulkBatchSize {
+			continue
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	slices.SortFunc(res.Failures, func(a, b *gen.BulkPutFailure) int { return cmp.Compare(a.Index, b.Index) })
+	return stream.SendAndClose(res)
+}
+
+// ExportRatings streams all ratings of a record type.
+func (h *Handler) ExportRatings(req *gen.ExportRatingsRequest, stream gen.RatingService_ExportRatingsServer) error {
+	if req == nil || req.RecordType == "" {
+		return status.Errorf(codes.InvalidArgument, "nil req or empty record type")
+	}
+	err := h.ctrl.ExportRatings(stream.Context(), model.RecordType(req.RecordType), func(r model.Rating) error {
+		return stream.Send(&gen.Rating{
+			RecordId:    r.RecordID,
+			RecordType:  r.RecordType,
+			UserId:      string(r.UserID),
+			RatingValue: int32(r.Value),
+			ProviderId:  r.ProviderID,
+			EventId:     r.EventID,
+		})
+	})
+	if _, ok := status.FromError(err); err != nil && !ok {
+		return status.Errorf(codes.Internal, err.Error())
+	}
+	return err
+}
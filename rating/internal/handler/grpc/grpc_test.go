@@ -0,0 +1,66 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/meirongdev/movie-microservice/gen"
+	"github.com/meirongdev/movie-microservice/rating/internal/controller/rating"
+	"github.com/meirongdev/movie-microservice/rating/internal/repository/memory"
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+	"google.golang.org/grpc"
+)
+
+// bulkPutStream defines a stream of bulk writes receiving a list of requests.
+type bulkPutStream struct {
+	grpc.ServerStream
+	reqs []*gen.PutRatingRequest
+	res  *gen.BulkPutRatingsResponse
+}
+
+func (s *bulkPutStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *bulkPutStream) Recv() (*gen.PutRatingRequest, error) {
+	if len(s.reqs) == 0 {
+		return nil, io.EOF
+	}
+	req := s.reqs[0]
+	s.reqs = s.reqs[1:]
+	return req, nil
+}
+
+func (s *bulkPutStream) SendAndClose(res *gen.BulkPutRatingsResponse) error {
+	s.res = res
+	return nil
+}
+
+func TestBulkPutRatingsRetried(t *testing.T) {
+	repo := memory.New()
+	h := New(rating.New(repo))
+	reqs := []*gen.PutRatingRequest{
+		{RecordId: "1", RecordType: string(model.RecordTypeMovie), UserId: "alice", RatingValue: 5, EventId: "event-1"},
+		{RecordId: "1", RecordType: string(model.RecordTypeMovie), UserId: "bob", RatingValue: 1, EventId: "event-2"},
+		{RecordId: "1", RecordType: string(model.RecordTypeMovie), UserId: "carol", RatingValue: 3},
+	}
+	// The stream is sent twice, as by a client retrying after a lost response.
+	for range 2 {
+		stream := &bulkPutStream{reqs: reqs}
+		if err := h.BulkPutRatings(stream); err != nil {
+			t.Fatalf("BulkPutRatings: %v", err)
+		}
+		if len(stream.res.Failures) != 0 {
+			t.Fatalf("BulkPutRatings: got failures %v", stream.res.Failures)
+		}
+	}
+	ratings, err := repo.Get(context.Background(), "1", model.RecordTypeMovie)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the rating without an event id is written twice.
+	if len(ratings) != 4 {
+		t.Fatalf("got %d ratings, want 4: %+v", len(ratings), ratings)
+	}
+}
@@ -35,22 +35,35 @@ func (h *Handler) Handle(w http.ResponseWriter, req *http.Request) {
 	}
 	switch req.Method {
 	case http.MethodGet:
-		v, err := h.ctrl.GetAggregatedRating(req.Context(), recordID, recordType)
+		v, err := h.ctrl.GetAggregatedRating(req.Context(), recordID, recordType, req.FormValue("provider"))
 		if err != nil && errors.Is(err, rating.ErrNotFound) {
 			w.WriteHeader(http.StatusNotFound)
 			return
+		} else if err != nil {
+			log.Printf("Repository get error: %v\n", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
 		}
 		if err := json.NewEncoder(w).Encode(v); err != nil {
 			log.Printf("Response encode error: %v\n", err)
 		}
 	case http.MethodPut:
 		userID := model.UserID(req.FormValue("userId"))
-		v, err := strconv.ParseFloat(req.FormValue("value"), 64)
+		if userID == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// Rating values are integers, matching the rating_value field of PutRatingRequest.
+		v, err := strconv.ParseInt(req.FormValue("value"), 10, 32)
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
-		if err := h.ctrl.PutRating(req.Context(), recordID, recordType, &model.Rating{UserID: userID, Value: model.RatingValue(v)}); err != nil {
+		r := &model.Rating{UserID: userID, Value: model.RatingValue(v), ProviderID: req.FormValue("provider")}
+		err = h.ctrl.PutRating(req.Context(), recordID, recordType, r)
+		if err != nil && errors.Is(err, rating.ErrProviderRejected) {
+			w.WriteHeader(http.StatusBadRequest)
+		} else if err != nil {
 			log.Printf("Repository put error: %v\n", err)
 			w.WriteHeader(http.StatusInternalServerError)
 		}
@@ -58,3 +71,14 @@ func (h *Handler) Handle(w http.ResponseWriter, req *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 	}
 }
+
+// Stats handles GET /ingestion/stats requests.
+func (h *Handler) Stats(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(h.ctrl.IngestionStats()); err != nil {
+		log.Printf("Response encode error: %v\n", err)
+	}
+}
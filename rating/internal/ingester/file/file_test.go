@@ -0,0 +1,92 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/meirongdev/movie-microservice/rating/internal/ingester"
+)
+
+// writeFile writes content to a file in a temporary directory and returns its path.
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestIngest(t *testing.T) {
+	// The blank line is skipped but counted, and the last line has no newline.
+	path := writeFile(t, `{"id":"e1","userId":"u1","recordId":"m1","recordType":"movie","value":4}
+{"schemaVersion":"2.0","id":"e2","payload":{"userId":"u2","recordId":"m1","recordType":"movie","value":5}}
+
+not json
+{"id":"e4","userId":"u4","recordId":"m2","recordType":"movie","value":1}`)
+	var batches [][]ingester.Message
+	err := NewIngester(path, 2).Ingest(context.Background(), func(_ context.Context, msgs []ingester.Message) error {
+		batches = append(batches, msgs)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 2 {
+		t.Fatalf("Ingest: got batches %v, want 2 batches of 2 messages", batches)
+	}
+	msgs := append(batches[0], batches[1]...)
+	for i, want := range []struct {
+		offset int64
+		id     string
+		err    bool
+	}{{0, "e1", false}, {1, "e2", false}, {3, "", true}, {4, "e4", false}} {
+		m := msgs[i]
+		if m.Offset != want.offset || m.Event.ID != want.id || (m.Err != nil) != want.err {
+			t.Errorf("message %d: got offset %d, id %q, error %v, want offset %d, id %q, error %v",
+				i, m.Offset, m.Event.ID, m.Err, want.offset, want.id, want.err)
+		}
+	}
+	if string(msgs[3].Payload) != `{"id":"e4","userId":"u4","recordId":"m2","recordType":"movie","value":1}` {
+		t.Errorf("last line without a newline: got payload %q", msgs[3].Payload)
+	}
+}
+
+func TestIngestStopsOnHandlerError(t *testing.T) {
+	path := writeFile(t, "{}\n{}\n{}\n")
+	want := errors.New("handler failed")
+	var offsets []int64
+	err := NewIngester(path, 1).Ingest(context.Background(), func(_ context.Context, msgs []ingester.Message) error {
+		offsets = append(offsets, msgs[0].Offset)
+		if len(offsets) == 2 {
+			return want
+		}
+		return nil
+	})
+	if !errors.Is(err, want) {
+		t.Fatalf("Ingest: got error %v, want %v", err, want)
+	}
+	if len(offsets) != 2 || offsets[1] != 1 {
+		t.Fatalf("Ingest: got offsets %v, want [0 1]", offsets)
+	}
+}
+
+func TestIngestCancelled(t *testing.T) {
+	path := writeFile(t, "{}\n{}\n{}\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := NewIngester(path, 1).Ingest(ctx, func(context.Context, []ingester.Message) error {
+		calls++
+		cancel()
+		return nil
+	})
+	if err != nil || calls != 1 {
+		t.Fatalf("Ingest: got error %v after %d call(s), want no error after 1", err, calls)
+	}
+	if err := NewIngester(filepath.Join(t.TempDir(), "missing.jsonl"), 1).Ingest(context.Background(), nil); err == nil {
+		t.Fatal("Ingest of a missing file: got no error")
+	}
+}
@@ -0,0 +1,67 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/meirongdev/movie-microservice/rating/internal/ingester"
+	"github.com/meirongdev/movie-microservice/rating/pkg/event"
+)
+
+const defaultBatchSize = 100
+
+// Ingester defines an ingester reading JSON-lines rating events from a file or stdin. Each line is
+// either a JSON rating event envelope or a bare JSON rating event of schema version 1.
+type Ingester struct {
+	path      string
+	batchSize int
+}
+
+// NewIngester creates a new file ingester. A path of "-" reads from stdin.
+func NewIngester(path string, batchSize int) *Ingester {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	return &Ingester{path, batchSize}
+}
+
+// Ingest reads the file line by line and passes the events to the handler in batches. It returns
+// once the whole file was handled. Message offsets are the zero-based line numbers in the file.
+func (i *Ingester) Ingest(ctx context.Context, handler ingester.Handler) error {
+	var r io.Reader = os.Stdin
+	if i.path != "-" {
+		f, err := os.Open(i.path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var batch []ingester.Message
+	for line := int64(0); scanner.Scan() && ctx.Err() == nil; line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		payload := append([]byte(nil), scanner.Bytes()...)
+		batch = append(batch, ingester.NewMessage(payload, event.ContentTypeJSON, 0, line, time.Now()))
+		if len(batch) < i.batchSize {
+			continue
+		}
+		if err := handler(ctx, batch); err != nil {
+			return err
+		}
+		batch = nil
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 && ctx.Err() == nil {
+		return handler(ctx, batch)
+	}
+	return nil
+}
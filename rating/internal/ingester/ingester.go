@@ -0,0 +1,43 @@
+package ingester
+
+import (
+	"context"
+	"time"
+
+	"github.com/meirongdev/movie-microservice/rating/pkg/event"
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+)
+
+// Message defines a rating event read from an ingestion source.
+type Message struct {
+	Event model.RatingEvent
+	// Payload is the raw message as read from the source, and ContentType its encoding.
+	Payload     []byte
+	ContentType string
+	// Partition and Offset locate the message in its source, if the source supports it.
+	Partition int32
+	Offset    int64
+	// Timestamp is the time the message was produced, or read if the source does not record it.
+	Timestamp time.Time
+	// Err is set if the payload could not be decoded into Event.
+	Err error
+	// Attempts is the number of earlier attempts to ingest a replayed message.
+	Attempts int
+}
+
+// NewMessage creates a message by decoding a raw rating event envelope of the given content type.
+// Envelopes of older schema versions are upcast, and unknown major versions set Err.
+func NewMessage(payload []byte, contentType string, partition int32, offset int64, timestamp time.Time) Message {
+	m := Message{Payload: payload, ContentType: contentType, Partition: partition, Offset: offset, Timestamp: timestamp}
+	env, err := event.Decode(payload, contentType)
+	if err != nil {
+		m.Err = err
+		return m
+	}
+	m.Event = event.RatingEvent(env)
+	return m
+}
+
+// Handler processes a batch of messages. Ingesters only acknowledge a batch to their source
+// after the handler returned without an error, so a failed batch is delivered again.
+type Handler func(ctx context.Context, msgs []Message) error
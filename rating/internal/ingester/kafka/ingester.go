@@ -2,61 +2,154 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"log"
+	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
-	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+	"github.com/meirongdev/movie-microservice/rating/internal/ingester"
+	"github.com/meirongdev/movie-microservice/rating/pkg/event"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultPollTimeout   = time.Second
+	defaultFlushInterval = time.Second
 )
 
 // Ingester defines a Kafka ingester.
 type Ingester struct {
 	consumer *kafka.Consumer
 	topic    string
+	config
+}
+
+type config struct {
+	batchSize     int
+	pollTimeout   time.Duration
+	flushInterval time.Duration
+}
+
+// Option configures a Kafka ingester.
+type Option func(*config)
+
+// WithBatchSize sets the maximum number of messages passed to the handler at once.
+func WithBatchSize(size int) Option {
+	return func(c *config) {
+		if size > 0 {
+			c.batchSize = size
+		}
+	}
+}
+
+// WithPollTimeout sets how long a single poll of the consumer blocks waiting for a message.
+func WithPollTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		if timeout > 0 {
+			c.pollTimeout = timeout
+		}
+	}
+}
+
+// WithFlushInterval sets how long a partially filled batch waits for more messages before
+// it is passed to the handler.
+func WithFlushInterval(interval time.Duration) Option {
+	return func(c *config) {
+		if interval > 0 {
+			c.flushInterval = interval
+		}
+	}
 }
 
-// NewIngester creates a new Kafka ingester.
-func NewIngester(addr string, groupID string, topic string) (*Ingester, error) {
+// NewIngester creates a new Kafka ingester. Offsets are committed manually, only after
+// a batch has been handled successfully, which gives at-least-once delivery.
+func NewIngester(addr string, groupID string, topic string, options ...Option) (*Ingester, error) {
 	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
-		"bootstrap.servers": addr,
-		"group.id":          groupID,
-		"auto.offset.reset": "earliest",
+		"bootstrap.servers":  addr,
+		"group.id":           groupID,
+		"auto.offset.reset":  "earliest",
+		"enable.auto.commit": false,
 	})
 	if err != nil {
 		return nil, err
 	}
-	return &Ingester{consumer, topic}, nil
+	i := &Ingester{consumer, topic, config{batchSize: defaultBatchSize, pollTimeout: defaultPollTimeout, flushInterval: defaultFlushInterval}}
+	for _, o := range options {
+		o(&i.config)
+	}
+	return i, nil
 }
 
-// Ingest starts ingestion from Kafka and returns a channel containing rating events
-// representing the data consumed from the topic.
-func (i *Ingester) Ingest(ctx context.Context) (chan model.RatingEvent, error) {
+// Ingest consumes rating events from the topic and passes them to the handler in batches.
+// It blocks until the context is cancelled or the handler fails, in which case the failed
+// batch is left uncommitted and will be consumed again.
+func (i *Ingester) Ingest(ctx context.Context, handler ingester.Handler) error {
 	if err := i.consumer.SubscribeTopics([]string{i.topic}, nil); err != nil {
-		return nil, err
+		return err
 	}
-
-	ch := make(chan model.RatingEvent, 1)
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				close(ch)
-				i.consumer.Close()
-				return
-			default:
+	defer i.consumer.Close()
+	for ctx.Err() == nil {
+		msgs, offsets := i.poll(ctx)
+		if len(msgs) > 0 {
+			if err := handler(ctx, msgs); err != nil {
+				return err
 			}
-			msg, err := i.consumer.ReadMessage(-1)
-			if err != nil {
-				log.Println("ReadMessage error: " + err.Error())
-				continue
+		}
+		if len(offsets) == 0 {
+			continue
+		}
+		if _, err := i.consumer.CommitOffsets(offsets); err != nil {
+			log.Println("CommitOffsets error: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// poll reads up to batchSize messages. It returns early if a poll times out before the first
+// message arrived, or once flushInterval elapsed since then. It returns the messages and the
+// offsets to commit once they are handled.
+func (i *Ingester) poll(ctx context.Context) ([]ingester.Message, []kafka.TopicPartition) {
+	var msgs []ingester.Message
+	next := map[int32]kafka.TopicPartition{}
+	var deadline time.Time
+	for len(msgs) < i.batchSize && ctx.Err() == nil {
+		timeout := i.pollTimeout
+		if !deadline.IsZero() {
+			timeout = min(timeout, time.Until(deadline))
+			if timeout <= 0 {
+				break
 			}
-			var event model.RatingEvent
-			if err := json.Unmarshal(msg.Value, &event); err != nil {
-				log.Println("Unmarshal error: " + err.Error())
-				continue
+		}
+		msg, err := i.consumer.ReadMessage(timeout)
+		var kafkaErr kafka.Error
+		if errors.As(err, &kafkaErr) && kafkaErr.IsTimeout() {
+			if deadline.IsZero() {
+				break
 			}
-			ch <- event
+			continue
+		} else if err != nil {
+			log.Println("ReadMessage error: " + err.Error())
+			continue
+		}
+		if deadline.IsZero() {
+			deadline = time.Now().Add(i.flushInterval)
+		}
+		tp := msg.TopicPartition
+		next[tp.Partition] = kafka.TopicPartition{Topic: tp.Topic, Partition: tp.Partition, Offset: tp.Offset + 1}
+		msgs = append(msgs, ingester.NewMessage(msg.Value, contentType(msg), tp.Partition, int64(tp.Offset), msg.Timestamp))
+	}
+	offsets := make([]kafka.TopicPartition, 0, len(next))
+	for _, tp := range next {
+		offsets = append(offsets, tp)
+	}
+	return msgs, offsets
+}
+
+func contentType(msg *kafka.Message) string {
+	for _, h := range msg.Headers {
+		if h.Key == event.ContentTypeHeader {
+			return string(h.Value)
 		}
-	}()
-	return ch, nil
+	}
+	return event.ContentTypeJSON
 }
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/meirongdev/movie-microservice/rating/internal/ingester"
+	"github.com/meirongdev/movie-microservice/rating/pkg/event"
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+)
+
+// Ingester defines an in-process ingester fed through a channel, mostly useful in tests.
+type Ingester struct {
+	ch chan model.RatingEvent
+}
+
+// NewIngester creates a new in-process ingester buffering up to size events.
+func NewIngester(size int) *Ingester {
+	return &Ingester{make(chan model.RatingEvent, size)}
+}
+
+// Publish sends an event to the ingester, blocking while its buffer is full.
+func (i *Ingester) Publish(ctx context.Context, event model.RatingEvent) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case i.ch <- event:
+		return nil
+	}
+}
+
+// Close stops the ingestion once all published events are handled.
+func (i *Ingester) Close() {
+	close(i.ch)
+}
+
+// Ingest passes published events to the handler, batching those already buffered. It returns
+// when the ingester is closed or the context is cancelled.
+func (i *Ingester) Ingest(ctx context.Context, handler ingester.Handler) error {
+	var offset int64
+	for {
+		var batch []ingester.Message
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-i.ch:
+			if !ok {
+				return nil
+			}
+			batch = append(batch, newMessage(e, offset))
+			offset++
+		}
+	drain:
+		for len(batch) < cap(i.ch)+1 {
+			select {
+			case e, ok := <-i.ch:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, newMessage(e, offset))
+				offset++
+			default:
+				break drain
+			}
+		}
+		if err := handler(ctx, batch); err != nil {
+			return err
+		}
+	}
+}
+
+func newMessage(e model.RatingEvent, offset int64) ingester.Message {
+	now := time.Now()
+	payload, _ := event.Encode(event.NewEnvelope(e, "", now), event.ContentTypeJSON)
+	return ingester.Message{Event: e, Payload: payload, ContentType: event.ContentTypeJSON, Offset: offset, Timestamp: now}
+}
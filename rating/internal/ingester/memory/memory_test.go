@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/meirongdev/movie-microservice/rating/internal/ingester"
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+)
+
+func TestIngest(t *testing.T) {
+	ctx := context.Background()
+	i := NewIngester(2)
+	events := []model.RatingEvent{
+		{ID: "e1", UserID: "u1", RecordID: "m1", RecordType: "movie", Value: 4},
+		{ID: "e2", UserID: "u2", RecordID: "m1", RecordType: "movie", Value: 5},
+		{ID: "e3", UserID: "u3", RecordID: "m2", RecordType: "movie", Value: 1},
+	}
+	go func() {
+		for _, e := range events {
+			if err := i.Publish(ctx, e); err != nil {
+				t.Error(err)
+			}
+		}
+		i.Close()
+	}()
+	var got []ingester.Message
+	err := i.Ingest(ctx, func(_ context.Context, msgs []ingester.Message) error {
+		if len(msgs) > 3 {
+			t.Errorf("got a batch of %d messages, want at most 3", len(msgs))
+		}
+		got = append(got, msgs...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	if len(got) != len(events) {
+		t.Fatalf("Ingest: got %d messages, want %d", len(got), len(events))
+	}
+	for j, m := range got {
+		if m.Event != events[j] || m.Offset != int64(j) || m.Err != nil {
+			t.Errorf("message %d: got %+v at offset %d, error %v, want %+v at offset %d", j, m.Event, m.Offset, m.Err, events[j], j)
+		}
+		// The payload decodes to the published event, e.g. when it is dead-lettered and replayed.
+		if replayed := ingester.NewMessage(m.Payload, m.ContentType, 0, 0, time.Time{}); replayed.Err != nil || replayed.Event != events[j] {
+			t.Errorf("message %d: payload decodes to %+v, error %v, want %+v", j, replayed.Event, replayed.Err, events[j])
+		}
+	}
+}
+
+func TestIngestStopsOnHandlerError(t *testing.T) {
+	ctx := context.Background()
+	i := NewIngester(1)
+	if err := i.Publish(ctx, model.RatingEvent{ID: "e1"}); err != nil {
+		t.Fatal(err)
+	}
+	want := errors.New("handler failed")
+	if err := i.Ingest(ctx, func(context.Context, []ingester.Message) error { return want }); !errors.Is(err, want) {
+		t.Fatalf("Ingest: got error %v, want %v", err, want)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	i := NewIngester(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := i.Publish(ctx, model.RatingEvent{ID: "e1"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Publish with a full buffer: got error %v, want %v", err, context.Canceled)
+	}
+	if err := i.Ingest(ctx, nil); err != nil {
+		t.Fatalf("Ingest: got error %v after cancellation", err)
+	}
+}
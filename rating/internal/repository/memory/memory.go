@@ -2,6 +2,9 @@ package memory
 
 import (
 	"context"
+	"maps"
+	"slices"
+	"sync"
 
 	"github.com/meirongdev/movie-microservice/rating/internal/repository"
 	"github.com/meirongdev/movie-microservice/rating/pkg/model"
@@ -9,30 +12,86 @@ import (
 
 // Repository defines a rating repository.
 type Repository struct {
-	data map[model.RecordType]map[model.RecordID][]model.Rating
+	sync.RWMutex
+	data   map[model.RecordType]map[model.RecordID][]model.Rating
+	events map[string]struct{}
 }
 
 // New creates a new memory repository.
 func New() *Repository {
-	return &Repository{map[model.RecordType]map[model.RecordID][]model.Rating{}}
+	return &Repository{data: map[model.RecordType]map[model.RecordID][]model.Rating{}, events: map[string]struct{}{}}
 }
 
 // Get retrieves all ratings for a given record.
 func (r *Repository) Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error) {
-	if _, ok := r.data[recordType]; !ok {
-		return nil, repository.ErrNotFound
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	if ratings, ok := r.data[recordType][recordID]; !ok || len(ratings) == 0 {
+	r.RLock()
+	defer r.RUnlock()
+	ratings := r.data[recordType][recordID]
+	if len(ratings) == 0 {
 		return nil, repository.ErrNotFound
 	}
-	return r.data[recordType][recordID], nil
+	return slices.Clone(ratings), nil
 }
 
 // Put adds a rating for a given record.
 func (r *Repository) Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.put(recordID, recordType, rating)
+	return nil
+}
+
+// PutBatch adds multiple ratings, each for the record given by its RecordID and RecordType.
+func (r *Repository) PutBatch(ctx context.Context, ratings []model.Rating) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.Lock()
+	defer r.Unlock()
+	for i := range ratings {
+		r.put(model.RecordID(ratings[i].RecordID), model.RecordType(ratings[i].RecordType), &ratings[i])
+	}
+	return nil
+}
+
+// ForEach calls fn for every rating of a record type, ordered by record id, and stops at the first
+// error of fn.
+func (r *Repository) ForEach(ctx context.Context, recordType model.RecordType, fn func(rating model.Rating) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.RLock()
+	recordIDs := slices.Sorted(maps.Keys(r.data[recordType]))
+	var ratings []model.Rating
+	for _, id := range recordIDs {
+		ratings = append(ratings, r.data[recordType][id]...)
+	}
+	r.RUnlock()
+	for _, rating := range ratings {
+		if err := fn(rating); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) put(recordID model.RecordID, recordType model.RecordType, rating *model.Rating) {
+	if rating.EventID != "" {
+		if _, ok := r.events[rating.EventID]; ok {
+			return
+		}
+		r.events[rating.EventID] = struct{}{}
+	}
 	if _, ok := r.data[recordType]; !ok {
 		r.data[recordType] = map[model.RecordID][]model.Rating{}
 	}
-	r.data[recordType][recordID] = append(r.data[recordType][recordID], *rating)
-	return nil
+	stored := *rating
+	stored.RecordID, stored.RecordType = string(recordID), string(recordType)
+	r.data[recordType][recordID] = append(r.data[recordType][recordID], stored)
 }
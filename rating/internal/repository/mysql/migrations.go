@@ -0,0 +1,30 @@
+package mysql
+
+import (
+	"context"
+	"embed"
+
+	"github.com/meirongdev/movie-microservice/internal/mysqlutil"
+	"github.com/meirongdev/movie-microservice/pkg/config"
+	"github.com/meirongdev/movie-microservice/pkg/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationsTable records the applied migrations. It is owned by the rating service, so
+// services sharing a database do not see each other's versions.
+const migrationsTable = "rating_schema_migrations"
+
+// NewMigrator creates a migrator applying the rating schema migrations to a MySQL database.
+func NewMigrator(ctx context.Context, cfg config.MySQLConfig) (*migrate.Migrator, error) {
+	ms, err := migrate.Load(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	db, err := mysqlutil.Open(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(db, ms, migrate.WithTable(migrationsTable)), nil
+}
@@ -0,0 +1,10 @@
+ALTER TABLE ratings
+    DROP INDEX ratings_record,
+    DROP INDEX ratings_event_id,
+    DROP COLUMN event_id,
+    DROP COLUMN provider_id,
+    DROP COLUMN id,
+    MODIFY record_id VARCHAR(255) NULL,
+    MODIFY record_type VARCHAR(255) NULL,
+    MODIFY user_id VARCHAR(255) NULL,
+    MODIFY value INT NULL;
@@ -0,0 +1,12 @@
+-- Databases created before migrations have the ratings table of the first migration, without
+-- providers and event ids.
+ALTER TABLE ratings
+    ADD id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY FIRST,
+    MODIFY record_id VARCHAR(255) NOT NULL,
+    MODIFY record_type VARCHAR(255) NOT NULL,
+    MODIFY user_id VARCHAR(255) NOT NULL,
+    MODIFY value INT NOT NULL,
+    ADD COLUMN provider_id VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN event_id VARCHAR(255) NULL,
+    ADD UNIQUE KEY ratings_event_id (event_id),
+    ADD INDEX ratings_record (record_type, record_id, provider_id);
@@ -0,0 +1,44 @@
+package mysql
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/meirongdev/movie-microservice/pkg/config"
+	"github.com/meirongdev/movie-microservice/rating/internal/repository/repositorytest"
+)
+
+// TestRepository runs against the MySQL database in MYSQL_TEST_DSN, e.g. the one started by
+// make compose/up: MYSQL_TEST_DSN='test:test@tcp(127.0.0.1:3306)/moviedb'. It clears the ratings table.
+func TestRepository(t *testing.T) {
+	dsn := os.Getenv("MYSQL_TEST_DSN")
+	if dsn == "" {
+		t.Skip("MYSQL_TEST_DSN not set")
+	}
+	c, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.MySQLConfig{Host: c.Addr, Username: c.User, Password: c.Passwd, Database: c.DBName}
+	m, err := NewMigrator(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repository {
+		r, err := New(context.Background(), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { r.cluster.Close() })
+		if _, err := r.cluster.Primary().Exec("DELETE FROM ratings"); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	})
+}
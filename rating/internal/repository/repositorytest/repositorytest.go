@@ -0,0 +1,242 @@
+// Package repositorytest implements a conformance suite for rating repositories.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/meirongdev/movie-microservice/rating/internal/repository"
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+)
+
+// Repository defines the rating repository contract.
+type Repository interface {
+	Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error)
+	Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error
+	PutBatch(ctx context.Context, ratings []model.Rating) error
+	ForEach(ctx context.Context, recordType model.RecordType, fn func(rating model.Rating) error) error
+}
+
+const (
+	recordID   = model.RecordID("1")
+	recordType = model.RecordTypeMovie
+)
+
+// Run runs the conformance suite. newRepository must return an empty repository for every call.
+func Run(t *testing.T, newRepository func(t *testing.T) Repository) {
+	t.Run("NotFound", func(t *testing.T) {
+		r := newRepository(t)
+		if _, err := r.Get(context.Background(), recordID, recordType); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("Get of a record without ratings: got error %v, want %v", err, repository.ErrNotFound)
+		}
+	})
+	t.Run("PutGet", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+		want := []model.Rating{rating("alice", 4, ""), rating("bob", 2, "e2")}
+		want[1].ProviderID = "imdb"
+		for i := range want {
+			// The record of a rating is given by the arguments of Put.
+			put := want[i]
+			put.RecordID, put.RecordType = "", ""
+			if err := r.Put(ctx, recordID, recordType, &put); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+		}
+		assertRatings(t, mustGet(t, r, recordID, recordType), want)
+		if _, err := r.Get(ctx, "2", recordType); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Get of another record: got error %v, want %v", err, repository.ErrNotFound)
+		}
+		if _, err := r.Get(ctx, recordID, "other"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Get of another record type: got error %v, want %v", err, repository.ErrNotFound)
+		}
+	})
+	t.Run("PutAppends", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+		want := []model.Rating{rating("alice", 4, ""), rating("alice", 5, "")}
+		for i := range want {
+			if err := r.Put(ctx, recordID, recordType, &want[i]); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+		}
+		assertRatings(t, mustGet(t, r, recordID, recordType), want)
+	})
+	t.Run("DuplicateEvent", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+		first := rating("alice", 4, "e1")
+		if err := r.Put(ctx, recordID, recordType, &first); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		duplicate := rating("alice", 1, "e1")
+		if err := r.Put(ctx, recordID, recordType, &duplicate); err != nil {
+			t.Fatalf("Put of a duplicate event: %v", err)
+		}
+		if err := r.PutBatch(ctx, []model.Rating{duplicate, rating("bob", 3, "e2"), rating("bob", 3, "e2")}); err != nil {
+			t.Fatalf("PutBatch with duplicate events: %v", err)
+		}
+		assertRatings(t, mustGet(t, r, recordID, recordType), []model.Rating{first, rating("bob", 3, "e2")})
+	})
+	t.Run("PutBatch", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+		var want []model.Rating
+		for i := range 1200 {
+			want = append(want, rating(fmt.Sprint("user", i), model.RatingValue(i%5+1), fmt.Sprint("e", i)))
+		}
+		other := rating("alice", 3, "")
+		other.RecordID = "2"
+		if err := r.PutBatch(ctx, append(slices.Clone(want), other)); err != nil {
+			t.Fatalf("PutBatch: %v", err)
+		}
+		assertRatings(t, mustGet(t, r, recordID, recordType), want)
+		assertRatings(t, mustGet(t, r, "2", recordType), []model.Rating{other})
+		if err := r.PutBatch(ctx, nil); err != nil {
+			t.Fatalf("PutBatch without ratings: %v", err)
+		}
+	})
+	t.Run("ForEach", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+		var want []model.Rating
+		for i := range 1200 {
+			rating := rating(fmt.Sprint("user", i), model.RatingValue(i%5+1), "")
+			rating.RecordID = fmt.Sprint(i % 7)
+			want = append(want, rating)
+		}
+		other := rating("alice", 3, "")
+		other.RecordType = "other"
+		if err := r.PutBatch(ctx, append(slices.Clone(want), other)); err != nil {
+			t.Fatalf("PutBatch: %v", err)
+		}
+		var got []model.Rating
+		if err := r.ForEach(ctx, recordType, func(rating model.Rating) error {
+			got = append(got, rating)
+			return nil
+		}); err != nil {
+			t.Fatalf("ForEach: %v", err)
+		}
+		assertRatings(t, got, want)
+		stop := errors.New("stop")
+		calls := 0
+		err := r.ForEach(ctx, recordType, func(model.Rating) error {
+			calls++
+			return stop
+		})
+		if !errors.Is(err, stop) || calls != 1 {
+			t.Errorf("ForEach with a failing function: got error %v after %d calls, want %v after 1", err, calls, stop)
+		}
+		if err := r.ForEach(ctx, "none", func(model.Rating) error { return stop }); err != nil {
+			t.Errorf("ForEach of a record type without ratings: %v", err)
+		}
+	})
+	t.Run("NoAliasing", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+		put := rating("alice", 4, "")
+		if err := r.Put(ctx, recordID, recordType, &put); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		put.Value = 1
+		got := mustGet(t, r, recordID, recordType)
+		got[0].Value = 1
+		assertRatings(t, mustGet(t, r, recordID, recordType), []model.Rating{rating("alice", 4, "")})
+	})
+	t.Run("Concurrency", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+		const n = 20
+		var wg sync.WaitGroup
+		errs := make(chan error, 2*n)
+		var want []model.Rating
+		for i := range n {
+			want = append(want, rating(fmt.Sprint("user", i), 3, fmt.Sprint("e", i)))
+		}
+		for i := range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var err error
+				if i%2 == 0 {
+					put := want[i]
+					err = r.Put(ctx, recordID, recordType, &put)
+				} else {
+					err = r.PutBatch(ctx, []model.Rating{want[i]})
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+				if _, err := r.Get(ctx, recordID, recordType); err != nil {
+					errs <- err
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("concurrent Put/Get: %v", err)
+		}
+		assertRatings(t, mustGet(t, r, recordID, recordType), want)
+	})
+	t.Run("ContextCancellation", func(t *testing.T) {
+		r := newRepository(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		put := rating("alice", 4, "")
+		if err := r.Put(ctx, recordID, recordType, &put); !errors.Is(err, context.Canceled) {
+			t.Errorf("Put with a cancelled context: got error %v, want %v", err, context.Canceled)
+		}
+		if err := r.PutBatch(ctx, []model.Rating{put}); !errors.Is(err, context.Canceled) {
+			t.Errorf("PutBatch with a cancelled context: got error %v, want %v", err, context.Canceled)
+		}
+		if _, err := r.Get(ctx, recordID, recordType); !errors.Is(err, context.Canceled) {
+			t.Errorf("Get with a cancelled context: got error %v, want %v", err, context.Canceled)
+		}
+		if _, err := r.Get(context.Background(), recordID, recordType); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("writes with a cancelled context stored ratings: got error %v, want %v", err, repository.ErrNotFound)
+		}
+	})
+}
+
+func rating(userID string, value model.RatingValue, eventID string) model.Rating {
+	return model.Rating{
+		RecordID:   string(recordID),
+		RecordType: string(recordType),
+		UserID:     model.UserID(userID),
+		Value:      value,
+		EventID:    eventID,
+	}
+}
+
+func mustGet(t *testing.T, r Repository, recordID model.RecordID, recordType model.RecordType) []model.Rating {
+	t.Helper()
+	ratings, err := r.Get(context.Background(), recordID, recordType)
+	if err != nil {
+		t.Fatalf("Get %s/%s: %v", recordType, recordID, err)
+	}
+	return ratings
+}
+
+// assertRatings compares ratings regardless of their order, which repositories do not define.
+func assertRatings(t *testing.T, got, want []model.Rating) {
+	t.Helper()
+	compare := func(a, b model.Rating) int {
+		return strings.Compare(fmt.Sprintf("%+v", a), fmt.Sprintf("%+v", b))
+	}
+	got, want = slices.Clone(got), slices.Clone(want)
+	slices.SortFunc(got, compare)
+	slices.SortFunc(want, compare)
+	if !slices.Equal(got, want) {
+		if len(got) > 5 || len(want) > 5 {
+			t.Fatalf("got %d ratings, want %d, or they differ", len(got), len(want))
+		}
+		t.Fatalf("got ratings %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,39 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+
+	"github.com/meirongdev/movie-microservice/pkg/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationsTable records the applied migrations. It is owned by the rating service, so
+// services sharing a database do not see each other's versions.
+const migrationsTable = "rating_schema_migrations"
+
+// NewMigrator creates a migrator applying the rating schema migrations to a SQLite database.
+func NewMigrator(dsn string) (*migrate.Migrator, error) {
+	ms, err := migrate.Load(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(db, ms, migrate.WithTable(migrationsTable), migrate.WithLocker(migrate.SQLiteLocker{})), nil
+}
+
+// Migrate applies pending schema migrations to the database of the repository.
+func (r *Repository) Migrate(ctx context.Context) error {
+	ms, err := migrate.Load(migrations, "migrations")
+	if err != nil {
+		return err
+	}
+	_, err = migrate.New(r.db, ms, migrate.WithTable(migrationsTable), migrate.WithLocker(migrate.SQLiteLocker{})).Up(ctx)
+	return err
+}
@@ -0,0 +1,10 @@
+CREATE TABLE ratings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    record_id TEXT NOT NULL,
+    record_type TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    value INTEGER NOT NULL,
+    provider_id TEXT NOT NULL DEFAULT '',
+    event_id TEXT UNIQUE
+);
+CREATE INDEX ratings_record ON ratings (record_type, record_id, provider_id);
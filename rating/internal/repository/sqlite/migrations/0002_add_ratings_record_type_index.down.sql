@@ -0,0 +1 @@
+DROP INDEX ratings_record_type;
@@ -0,0 +1 @@
+CREATE INDEX ratings_record_type ON ratings (record_type);
@@ -0,0 +1,146 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/meirongdev/movie-microservice/rating/internal/repository"
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+)
+
+// Repository defines a SQLite-based rating repository.
+type Repository struct {
+	db *sql.DB
+}
+
+// New creates a new SQLite-based rating repository.
+func New(dsn string) (*Repository, error) {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer, so a single connection avoids busy errors.
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+	return &Repository{db}, nil
+}
+
+// Get retrieves all ratings for a given record.
+func (r *Repository) Get(ctx context.Context, recordID model.RecordID, recordType model.RecordType) ([]model.Rating, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT user_id, value, provider_id, event_id FROM ratings WHERE record_id = ? AND record_type = ?", recordID, recordType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []model.Rating
+	for rows.Next() {
+		var userID, providerID string
+		var value int32
+		var eventID sql.NullString
+		if err := rows.Scan(&userID, &value, &providerID, &eventID); err != nil {
+			return nil, err
+		}
+		res = append(res, model.Rating{
+			RecordID:   string(recordID),
+			RecordType: string(recordType),
+			UserID:     model.UserID(userID),
+			Value:      model.RatingValue(value),
+			ProviderID: providerID,
+			EventID:    eventID.String,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return res, nil
+}
+
+// Put adds a rating for a given record. A rating with an event id that was already stored is ignored.
+func (r *Repository) Put(ctx context.Context, recordID model.RecordID, recordType model.RecordType, rating *model.Rating) error {
+	eventID := sql.NullString{String: rating.EventID, Valid: rating.EventID != ""}
+	_, err := r.db.ExecContext(ctx, "INSERT INTO ratings (record_id, record_type, user_id, value, provider_id, event_id) VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (event_id) DO NOTHING",
+		recordID, recordType, rating.UserID, rating.Value, rating.ProviderID, eventID)
+	return err
+}
+
+// maxBatchRows limits the number of rows written by a single multi-row insert.
+const maxBatchRows = 500
+
+// PutBatch adds multiple ratings, each for the record given by its RecordID and RecordType, in a
+// single transaction using multi-row inserts. Ratings with an event id that was already stored are ignored.
+func (r *Repository) PutBatch(ctx context.Context, ratings []model.Rating) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for start := 0; start < len(ratings); start += maxBatchRows {
+		chunk := ratings[start:min(start+maxBatchRows, len(ratings))]
+		placeholders := make([]string, len(chunk))
+		args := make([]any, 0, len(chunk)*6)
+		for i, rating := range chunk {
+			placeholders[i] = "(?, ?, ?, ?, ?, ?)"
+			eventID := sql.NullString{String: rating.EventID, Valid: rating.EventID != ""}
+			args = append(args, rating.RecordID, rating.RecordType, rating.UserID, rating.Value, rating.ProviderID, eventID)
+		}
+		query := "INSERT INTO ratings (record_id, record_type, user_id, value, provider_id, event_id) VALUES " +
+			strings.Join(placeholders, ", ") + " ON CONFLICT (event_id) DO NOTHING"
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// exportPageSize is the number of ratings read per query by ForEach.
+const exportPageSize = 500
+
+// ForEach calls fn for every rating of a record type, in the order they were stored, and stops at
+// the first error of fn. Ratings are read page by page, so no query is open while fn runs.
+func (r *Repository) ForEach(ctx context.Context, recordType model.RecordType, fn func(rating model.Rating) error) error {
+	var after int64
+	for {
+		page, last, err := r.ratingsPage(ctx, recordType, after)
+		if err != nil {
+			return err
+		}
+		for _, rating := range page {
+			if err := fn(rating); err != nil {
+				return err
+			}
+		}
+		if len(page) < exportPageSize {
+			return nil
+		}
+		after = last
+	}
+}
+
+// ratingsPage returns the ratings of a record type stored after the rating with the given id, and
+// the id of the last one.
+func (r *Repository) ratingsPage(ctx context.Context, recordType model.RecordType, after int64) ([]model.Rating, int64, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, record_id, user_id, value, provider_id, event_id FROM ratings WHERE record_type = ? AND id > ? ORDER BY id LIMIT ?", recordType, after, exportPageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var res []model.Rating
+	last := after
+	for rows.Next() {
+		rating := model.Rating{RecordType: string(recordType)}
+		var eventID sql.NullString
+		if err := rows.Scan(&last, &rating.RecordID, &rating.UserID, &rating.Value, &rating.ProviderID, &eventID); err != nil {
+			return nil, 0, err
+		}
+		rating.EventID = eventID.String
+		res = append(res, rating)
+	}
+	return res, last, rows.Err()
+}
@@ -0,0 +1,25 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/meirongdev/movie-microservice/pkg/config"
+	"github.com/meirongdev/movie-microservice/rating/internal/repository/repositorytest"
+)
+
+func TestRepository(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repository {
+		cfg := config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "rating.db")}
+		r, err := New(cfg.FormatDSN())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { r.db.Close() })
+		if err := r.Migrate(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	})
+}
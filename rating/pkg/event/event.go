@@ -0,0 +1,144 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/meirongdev/movie-microservice/gen"
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// SchemaVersion is the current schema version of rating event envelopes.
+const SchemaVersion = "2.0"
+
+// currentMajor is the major version of SchemaVersion. Version 1 is the bare JSON RatingEvent
+// published before envelopes were introduced, which is upcast on decoding.
+const currentMajor = 2
+
+// ContentTypeHeader is the Kafka message header carrying the content type of an encoded envelope.
+const ContentTypeHeader = "content-type"
+
+// Content types of encoded envelopes.
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// ErrUnsupportedVersion is returned when decoding an event of an unknown major schema version.
+var ErrUnsupportedVersion = errors.New("unsupported rating event schema version")
+
+// NewEnvelope wraps a rating event in an envelope of the current schema version.
+// A zero timestamp is left unset.
+func NewEnvelope(e model.RatingEvent, producer string, timestamp time.Time) *gen.RatingEventEnvelope {
+	env := &gen.RatingEventEnvelope{
+		Id:            e.ID,
+		Type:          string(e.EventType),
+		SchemaVersion: SchemaVersion,
+		Producer:      producer,
+		Payload: &gen.RatingEventPayload{
+			UserId:     string(e.UserID),
+			RecordId:   string(e.RecordID),
+			RecordType: string(e.RecordType),
+			Value:      int32(e.Value),
+			ProviderId: e.ProviderID,
+		},
+	}
+	if !timestamp.IsZero() {
+		env.Timestamp = timestamppb.New(timestamp)
+	}
+	return env
+}
+
+// RatingEvent returns the rating event wrapped in an envelope.
+func RatingEvent(env *gen.RatingEventEnvelope) model.RatingEvent {
+	p := env.GetPayload()
+	return model.RatingEvent{
+		ID:         env.GetId(),
+		UserID:     model.UserID(p.GetUserId()),
+		RecordID:   model.RecordID(p.GetRecordId()),
+		RecordType: model.RecordType(p.GetRecordType()),
+		Value:      model.RatingValue(p.GetValue()),
+		ProviderID: p.GetProviderId(),
+		EventType:  model.RatingEventType(env.GetType()),
+	}
+}
+
+// Encode encodes an envelope with the given content type.
+func Encode(env *gen.RatingEventEnvelope, contentType string) ([]byte, error) {
+	switch contentType {
+	case ContentTypeJSON:
+		return protojson.Marshal(env)
+	case ContentTypeProtobuf:
+		return proto.Marshal(env)
+	}
+	return nil, fmt.Errorf("unsupported content type %q", contentType)
+}
+
+// Decode decodes an envelope of the given content type, defaulting to JSON if it is empty.
+// Older schema versions are upcast to the current one, and unknown major versions are
+// rejected with ErrUnsupportedVersion.
+func Decode(payload []byte, contentType string) (*gen.RatingEventEnvelope, error) {
+	env := &gen.RatingEventEnvelope{}
+	switch contentType {
+	case "", ContentTypeJSON:
+		var probe struct {
+			Payload json.RawMessage `json:"payload"`
+		}
+		if err := json.Unmarshal(payload, &probe); err != nil {
+			return nil, err
+		}
+		if probe.Payload == nil {
+			return upcastV1(payload)
+		}
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(payload, env); err != nil {
+			return nil, err
+		}
+	case ContentTypeProtobuf:
+		if err := proto.Unmarshal(payload, env); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content type %q", contentType)
+	}
+	major, err := majorVersion(env.SchemaVersion)
+	if err != nil {
+		return nil, err
+	}
+	if major != currentMajor {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedVersion, env.SchemaVersion)
+	}
+	return env, nil
+}
+
+// v1Event defines the bare JSON rating event of schema version 1.
+type v1Event struct {
+	model.RatingEvent
+	SchemaVersion int `json:"schemaVersion"`
+}
+
+func upcastV1(payload []byte) (*gen.RatingEventEnvelope, error) {
+	var e v1Event
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, err
+	}
+	if e.SchemaVersion > 1 {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, e.SchemaVersion)
+	}
+	return NewEnvelope(e.RatingEvent, "", time.Time{}), nil
+}
+
+func majorVersion(version string) (int, error) {
+	major, _, _ := strings.Cut(version, ".")
+	v, err := strconv.Atoi(major)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrUnsupportedVersion, version)
+	}
+	return v, nil
+}
@@ -0,0 +1,111 @@
+package event
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/meirongdev/movie-microservice/gen"
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+	"google.golang.org/protobuf/proto"
+)
+
+var testEvent = model.RatingEvent{
+	ID:         "e1",
+	UserID:     "u1",
+	RecordID:   "m1",
+	RecordType: "movie",
+	Value:      4,
+	ProviderID: "imdb",
+	EventType:  model.RatingEventTypePut,
+}
+
+func encode(t *testing.T, env *gen.RatingEventEnvelope, contentType string) []byte {
+	t.Helper()
+	b, err := Encode(env, contentType)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	return b
+}
+
+func TestDecode(t *testing.T) {
+	env := NewEnvelope(testEvent, "test", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	minor := proto.Clone(env).(*gen.RatingEventEnvelope)
+	minor.SchemaVersion = "2.7"
+	v3 := proto.Clone(env).(*gen.RatingEventEnvelope)
+	v3.SchemaVersion = "3.0"
+	tests := []struct {
+		name        string
+		payload     []byte
+		contentType string
+		want        model.RatingEvent
+		wantErr     error
+	}{
+		{"json", encode(t, env, ContentTypeJSON), ContentTypeJSON, testEvent, nil},
+		{"json by default", encode(t, env, ContentTypeJSON), "", testEvent, nil},
+		{"protobuf", encode(t, env, ContentTypeProtobuf), ContentTypeProtobuf, testEvent, nil},
+		{"newer minor version", encode(t, minor, ContentTypeProtobuf), ContentTypeProtobuf, testEvent, nil},
+		{"unknown json fields", []byte(`{"id":"e1","schemaVersion":"2.1","type":"put","future":true,
+			"payload":{"userId":"u1","recordId":"m1","recordType":"movie","value":4,"providerId":"imdb"}}`), ContentTypeJSON, testEvent, nil},
+		{"v1", []byte(`{"id":"e1","userId":"u1","recordId":"m1","recordType":"movie","value":4,"providerId":"imdb","eventType":"put"}`), "", testEvent, nil},
+		{"explicit v1", []byte(`{"schemaVersion":1,"id":"e1","userId":"u1","recordId":"m1","recordType":"movie","value":4,"providerId":"imdb","eventType":"put"}`), ContentTypeJSON, testEvent, nil},
+		{"v2 without envelope", []byte(`{"schemaVersion":2,"id":"e1"}`), "", model.RatingEvent{}, ErrUnsupportedVersion},
+		{"json v3", encode(t, v3, ContentTypeJSON), ContentTypeJSON, model.RatingEvent{}, ErrUnsupportedVersion},
+		{"protobuf v3", encode(t, v3, ContentTypeProtobuf), ContentTypeProtobuf, model.RatingEvent{}, ErrUnsupportedVersion},
+		{"missing version", []byte(`{"id":"e1","payload":{"userId":"u1"}}`), ContentTypeJSON, model.RatingEvent{}, ErrUnsupportedVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.payload, tt.contentType)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Decode: got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if e := RatingEvent(got); e != tt.want {
+				t.Fatalf("Decode: got %+v, want %+v", e, tt.want)
+			}
+			if got.SchemaVersion == "" {
+				t.Fatal("Decode: got no schema version")
+			}
+		})
+	}
+}
+
+func TestDecodeUpcastsV1(t *testing.T) {
+	env, err := Decode([]byte(`{"id":"e1","userId":"u1","recordId":"m1","recordType":"movie","value":4}`), ContentTypeJSON)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if env.SchemaVersion != SchemaVersion || env.Timestamp != nil {
+		t.Fatalf("upcast envelope: got version %q and timestamp %v, want %q without timestamp", env.SchemaVersion, env.Timestamp, SchemaVersion)
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	tests := []struct {
+		name        string
+		payload     []byte
+		contentType string
+	}{
+		{"json", []byte(`not json`), ContentTypeJSON},
+		{"json envelope", []byte(`{"schemaVersion":"2.0","payload":{"value":"high"}}`), ContentTypeJSON},
+		{"protobuf", []byte{0xff, 0xff, 0xff}, ContentTypeProtobuf},
+		{"content type", encode(t, NewEnvelope(testEvent, "", time.Time{}), ContentTypeJSON), "text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(tt.payload, tt.contentType); err == nil || errors.Is(err, ErrUnsupportedVersion) {
+				t.Fatalf("Decode: got error %v, want a decoding error", err)
+			}
+		})
+	}
+	if _, err := Encode(NewEnvelope(testEvent, "", time.Time{}), "text/plain"); err == nil {
+		t.Fatal("Encode with an unknown content type: got no error")
+	}
+}
@@ -1,5 +1,10 @@
 package model
 
+import (
+	"errors"
+	"fmt"
+)
+
 // RecordID defines a record id. Together with RecordType identifies unique records across all types.
 type RecordID string
 
@@ -17,16 +22,32 @@ type UserID string
 // RatingValue defines a value of a rating record.
 type RatingValue int
 
+// Bounds of the canonical rating scale that provider-specific scales are mapped to.
+const (
+	MinRatingValue = RatingValue(1)
+	MaxRatingValue = RatingValue(5)
+)
+
 // Rating defines an individual rating created by a user for some record.
 type Rating struct {
 	RecordID   string      `json:"recordId"`
 	RecordType string      `json:"recordType"`
 	UserID     UserID      `json:"userId"`
 	Value      RatingValue `json:"value"`
+	// ProviderID identifies the provider the rating was received from, empty for ratings made directly by users.
+	ProviderID string `json:"providerId,omitempty"`
+	// EventID is the id of the rating event the rating was ingested from, if any.
+	// Repositories store a rating at most once per event id.
+	EventID string `json:"eventId,omitempty"`
 }
 
+// ErrInvalidEvent is returned when a rating event is missing required fields.
+var ErrInvalidEvent = errors.New("invalid rating event")
+
 // RatingEvent defines an event containing rating information.
 type RatingEvent struct {
+	// ID uniquely identifies the event, so that redelivered events are only applied once.
+	ID         string          `json:"id"`
 	UserID     UserID          `json:"userId"`
 	RecordID   RecordID        `json:"recordId"`
 	RecordType RecordType      `json:"recordType"`
@@ -35,6 +56,19 @@ type RatingEvent struct {
 	EventType  RatingEventType `json:"eventType"`
 }
 
+// Validate checks that the event contains the fields required to ingest it.
+func (e RatingEvent) Validate() error {
+	switch {
+	case e.UserID == "":
+		return fmt.Errorf("%w: empty user id", ErrInvalidEvent)
+	case e.RecordID == "":
+		return fmt.Errorf("%w: empty record id", ErrInvalidEvent)
+	case e.RecordType == "":
+		return fmt.Errorf("%w: empty record type", ErrInvalidEvent)
+	}
+	return nil
+}
+
 // RatingEventType defines the type of a rating event.
 type RatingEventType string
 
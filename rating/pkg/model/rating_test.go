@@ -0,0 +1,30 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRatingEventValidate(t *testing.T) {
+	valid := RatingEvent{ID: "e1", UserID: "u1", RecordID: "m1", RecordType: "movie", Value: 4}
+	tests := []struct {
+		name   string
+		modify func(e *RatingEvent)
+		want   error
+	}{
+		{"valid", func(*RatingEvent) {}, nil},
+		{"without id", func(e *RatingEvent) { e.ID = "" }, nil},
+		{"empty user id", func(e *RatingEvent) { e.UserID = "" }, ErrInvalidEvent},
+		{"empty record id", func(e *RatingEvent) { e.RecordID = "" }, ErrInvalidEvent},
+		{"empty record type", func(e *RatingEvent) { e.RecordType = "" }, ErrInvalidEvent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := valid
+			tt.modify(&e)
+			if err := e.Validate(); !errors.Is(err, tt.want) || (err == nil) != (tt.want == nil) {
+				t.Fatalf("Validate: got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package producer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/meirongdev/movie-microservice/rating/pkg/event"
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+)
+
+// Producer defines a Kafka producer of rating events, sharing the event contract of the rating service ingester.
+type Producer struct {
+	producer *kafka.Producer
+	topic    string
+	config
+}
+
+type config struct {
+	name        string
+	contentType string
+}
+
+// Option configures a producer.
+type Option func(*config)
+
+// WithName sets the producer name recorded in the envelope of published events.
+func WithName(name string) Option {
+	return func(c *config) {
+		c.name = name
+	}
+}
+
+// WithContentType sets the envelope encoding, event.ContentTypeJSON (the default) or event.ContentTypeProtobuf.
+func WithContentType(contentType string) Option {
+	return func(c *config) {
+		c.contentType = contentType
+	}
+}
+
+// New creates a new rating event producer publishing to the given topic.
+func New(addr string, topic string, options ...Option) (*Producer, error) {
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{
+		"bootstrap.servers":  addr,
+		"enable.idempotence": true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	p := &Producer{producer, topic, config{contentType: event.ContentTypeJSON}}
+	for _, o := range options {
+		o(&p.config)
+	}
+	return p, nil
+}
+
+// Publish validates a rating event and publishes it in an envelope of the current schema version,
+// waiting for its delivery. Events without an id get a random one. Events are keyed by record, so
+// all events of a record land in the same partition and are ingested in order.
+func (p *Producer) Publish(ctx context.Context, e model.RatingEvent) error {
+	if e.ID == "" {
+		id, err := newEventID()
+		if err != nil {
+			return err
+		}
+		e.ID = id
+	}
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	value, err := event.Encode(event.NewEnvelope(e, p.name, time.Now()), p.contentType)
+	if err != nil {
+		return err
+	}
+	delivery := make(chan kafka.Event, 1)
+	if err := p.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &p.topic, Partition: kafka.PartitionAny},
+		Key:            []byte(Key(e)),
+		Value:          value,
+		Headers:        []kafka.Header{{Key: event.ContentTypeHeader, Value: []byte(p.contentType)}},
+	}, delivery); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case ev := <-delivery:
+		if m, ok := ev.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+			return m.TopicPartition.Error
+		}
+		return nil
+	}
+}
+
+// Close flushes pending events and closes the producer.
+func (p *Producer) Close() {
+	p.producer.Flush(10000)
+	p.producer.Close()
+}
+
+// Key returns the partitioning key of a rating event.
+func Key(e model.RatingEvent) string {
+	return string(e.RecordType) + "/" + string(e.RecordID)
+}
+
+func newEventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,37 @@
+package producer
+
+import (
+	"testing"
+
+	"github.com/meirongdev/movie-microservice/rating/pkg/model"
+)
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		event model.RatingEvent
+		want  string
+	}{
+		{model.RatingEvent{RecordType: "movie", RecordID: "1"}, "movie/1"},
+		{model.RatingEvent{RecordType: "movie", RecordID: "1", UserID: "u2", ID: "e2"}, "movie/1"},
+		{model.RatingEvent{RecordType: "series", RecordID: "1"}, "series/1"},
+	}
+	for _, tt := range tests {
+		if got := Key(tt.event); got != tt.want {
+			t.Errorf("Key(%+v): got %q, want %q", tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestNewEventID(t *testing.T) {
+	a, err := newEventID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newEventID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 32 || a == b {
+		t.Fatalf("newEventID: got %q and %q, want distinct 32-character ids", a, b)
+	}
+}